@@ -28,18 +28,22 @@ const (
 	RateLimitResponse_UNKNOWN    RateLimitResponse_Code = 0
 	RateLimitResponse_OK         RateLimitResponse_Code = 1
 	RateLimitResponse_OVER_LIMIT RateLimitResponse_Code = 2
+	// The owner or the resource is in the deny list, whatever its limits.
+	RateLimitResponse_DENIED RateLimitResponse_Code = 3
 )
 
 var RateLimitResponse_Code_name = map[int32]string{
 	0: "UNKNOWN",
 	1: "OK",
 	2: "OVER_LIMIT",
+	3: "DENIED",
 }
 
 var RateLimitResponse_Code_value = map[string]int32{
 	"UNKNOWN":    0,
 	"OK":         1,
 	"OVER_LIMIT": 2,
+	"DENIED":     3,
 }
 
 func (x RateLimitResponse_Code) String() string {
@@ -47,7 +51,68 @@ func (x RateLimitResponse_Code) String() string {
 }
 
 func (RateLimitResponse_Code) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_022a6ac14e109943, []int{1, 0}
+	return fileDescriptor_022a6ac14e109943, []int{2, 0}
+}
+
+// The level of the hierarchy of limits the limit belongs to.
+type RateLimitResponse_Level int32
+
+const (
+	// The limits of each owner and resource.
+	RateLimitResponse_KEY RateLimitResponse_Level = 0
+	// The limits of all the hits.
+	RateLimitResponse_GLOBAL RateLimitResponse_Level = 1
+	// The limits of each owner.
+	RateLimitResponse_OWNER RateLimitResponse_Level = 2
+	// The limits of each owner and resource, without its sub-part after "#".
+	RateLimitResponse_RESOURCE RateLimitResponse_Level = 3
+)
+
+var RateLimitResponse_Level_name = map[int32]string{
+	0: "KEY",
+	1: "GLOBAL",
+	2: "OWNER",
+	3: "RESOURCE",
+}
+
+var RateLimitResponse_Level_value = map[string]int32{
+	"KEY":      0,
+	"GLOBAL":   1,
+	"OWNER":    2,
+	"RESOURCE": 3,
+}
+
+func (x RateLimitResponse_Level) String() string {
+	return proto.EnumName(RateLimitResponse_Level_name, int32(x))
+}
+
+func (RateLimitResponse_Level) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_022a6ac14e109943, []int{2, 1}
+}
+
+type AccessEntry_List int32
+
+const (
+	AccessEntry_ALLOW AccessEntry_List = 0
+	AccessEntry_DENY  AccessEntry_List = 1
+)
+
+var AccessEntry_List_name = map[int32]string{
+	0: "ALLOW",
+	1: "DENY",
+}
+
+var AccessEntry_List_value = map[string]int32{
+	"ALLOW": 0,
+	"DENY":  1,
+}
+
+func (x AccessEntry_List) String() string {
+	return proto.EnumName(AccessEntry_List_name, int32(x))
+}
+
+func (AccessEntry_List) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_022a6ac14e109943, []int{16, 0}
 }
 
 // The main request message made to the RateLimitService.
@@ -65,10 +130,17 @@ type RateLimitRequest struct {
 	//   1. /v1/order/pay
 	//   2. /v1/order/pay#customer123
 	//   3. graphql_resolver_root
-	Resource             string   `protobuf:"bytes,2,opt,name=resource,proto3" json:"resource,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Resource string `protobuf:"bytes,2,opt,name=resource,proto3" json:"resource,omitempty"`
+	// Dimensions of the hit, in order. Hits with different descriptors are
+	// counted apart, and rules can match on them. Keys starting with "ratio."
+	// are reserved.
+	//
+	// Examples:
+	//   1. [{key: "customer", value: "customer123"}, {key: "plan", value: "free"}]
+	Descriptors          []*Descriptor `protobuf:"bytes,3,rep,name=descriptors,proto3" json:"descriptors,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
 }
 
 func (m *RateLimitRequest) Reset()         { *m = RateLimitRequest{} }
@@ -110,20 +182,88 @@ func (m *RateLimitRequest) GetResource() string {
 	return ""
 }
 
+func (m *RateLimitRequest) GetDescriptors() []*Descriptor {
+	if m != nil {
+		return m.Descriptors
+	}
+	return nil
+}
+
+// A dimension of a hit.
+type Descriptor struct {
+	Key                  string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value                string   `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Descriptor) Reset()         { *m = Descriptor{} }
+func (m *Descriptor) String() string { return proto.CompactTextString(m) }
+func (*Descriptor) ProtoMessage()    {}
+func (*Descriptor) Descriptor() ([]byte, []int) {
+	return fileDescriptor_022a6ac14e109943, []int{1}
+}
+
+func (m *Descriptor) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Descriptor.Unmarshal(m, b)
+}
+func (m *Descriptor) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Descriptor.Marshal(b, m, deterministic)
+}
+func (m *Descriptor) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Descriptor.Merge(m, src)
+}
+func (m *Descriptor) XXX_Size() int {
+	return xxx_messageInfo_Descriptor.Size(m)
+}
+func (m *Descriptor) XXX_DiscardUnknown() {
+	xxx_messageInfo_Descriptor.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Descriptor proto.InternalMessageInfo
+
+func (m *Descriptor) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *Descriptor) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
 // The response of RateLimit. Strongly based on Envoy.
 // See https://github.com/envoyproxy/envoy/blob/master/api/envoy/service/ratelimit/v2/rls.proto
 type RateLimitResponse struct {
-	Code                 RateLimitResponse_Code `protobuf:"varint,1,opt,name=code,proto3,enum=RateLimitResponse_Code" json:"code,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}               `json:"-"`
-	XXX_unrecognized     []byte                 `json:"-"`
-	XXX_sizecache        int32                  `json:"-"`
+	Code RateLimitResponse_Code `protobuf:"varint,1,opt,name=code,proto3,enum=RateLimitResponse_Code" json:"code,omitempty"`
+	// The limit applied to the request. When several windows are configured, the
+	// first one exceeded on OVER_LIMIT, or the one with less remaining hits on OK.
+	Limit *Limit `protobuf:"bytes,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	// The hits still allowed in the window of limit, after the current one.
+	Remaining uint32                  `protobuf:"varint,3,opt,name=remaining,proto3" json:"remaining,omitempty"`
+	Level     RateLimitResponse_Level `protobuf:"varint,4,opt,name=level,proto3,enum=RateLimitResponse_Level" json:"level,omitempty"`
+	// The calendar period limit with less remaining hits, or the one exceeded
+	// on OVER_LIMIT. Only set when period limits apply to the request.
+	Period *PeriodLimit `protobuf:"bytes,5,opt,name=period,proto3" json:"period,omitempty"`
+	// When the ban of the key ends, in unix milliseconds, if it was banned
+	// for exceeding its limits too often. Banned requests are OVER_LIMIT
+	// without checking any limit, so limit is not set.
+	BannedUntilMs        int64    `protobuf:"varint,6,opt,name=banned_until_ms,json=bannedUntilMs,proto3" json:"banned_until_ms,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *RateLimitResponse) Reset()         { *m = RateLimitResponse{} }
 func (m *RateLimitResponse) String() string { return proto.CompactTextString(m) }
 func (*RateLimitResponse) ProtoMessage()    {}
 func (*RateLimitResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_022a6ac14e109943, []int{1}
+	return fileDescriptor_022a6ac14e109943, []int{2}
 }
 
 func (m *RateLimitResponse) XXX_Unmarshal(b []byte) error {
@@ -151,99 +291,2086 @@ func (m *RateLimitResponse) GetCode() RateLimitResponse_Code {
 	return RateLimitResponse_UNKNOWN
 }
 
-func init() {
-	proto.RegisterEnum("RateLimitResponse_Code", RateLimitResponse_Code_name, RateLimitResponse_Code_value)
-	proto.RegisterType((*RateLimitRequest)(nil), "RateLimitRequest")
-	proto.RegisterType((*RateLimitResponse)(nil), "RateLimitResponse")
+func (m *RateLimitResponse) GetLimit() *Limit {
+	if m != nil {
+		return m.Limit
+	}
+	return nil
 }
 
-func init() { proto.RegisterFile("ratio.proto", fileDescriptor_022a6ac14e109943) }
+func (m *RateLimitResponse) GetRemaining() uint32 {
+	if m != nil {
+		return m.Remaining
+	}
+	return 0
+}
 
-var fileDescriptor_022a6ac14e109943 = []byte{
-	// 204 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0x2e, 0x4a, 0x2c, 0xc9,
-	0xcc, 0xd7, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x57, 0x72, 0xe1, 0x12, 0x08, 0x4a, 0x2c, 0x49, 0xf5,
-	0xc9, 0xcc, 0xcd, 0x2c, 0x09, 0x4a, 0x2d, 0x2c, 0x4d, 0x2d, 0x2e, 0x11, 0x12, 0xe1, 0x62, 0xcd,
-	0x2f, 0xcf, 0x4b, 0x2d, 0x92, 0x60, 0x54, 0x60, 0xd4, 0xe0, 0x0c, 0x82, 0x70, 0x84, 0xa4, 0xb8,
-	0x38, 0x8a, 0x52, 0x8b, 0xf3, 0x4b, 0x8b, 0x92, 0x53, 0x25, 0x98, 0xc0, 0x12, 0x70, 0xbe, 0x52,
-	0x2e, 0x97, 0x20, 0x92, 0x29, 0xc5, 0x05, 0xf9, 0x79, 0xc5, 0xa9, 0x42, 0xda, 0x5c, 0x2c, 0xc9,
-	0xf9, 0x29, 0xa9, 0x60, 0x53, 0xf8, 0x8c, 0xc4, 0xf5, 0x30, 0x54, 0xe8, 0x39, 0xe7, 0xa7, 0xa4,
-	0x06, 0x81, 0x15, 0x29, 0x69, 0x73, 0xb1, 0x80, 0x78, 0x42, 0xdc, 0x5c, 0xec, 0xa1, 0x7e, 0xde,
-	0x7e, 0xfe, 0xe1, 0x7e, 0x02, 0x0c, 0x42, 0x6c, 0x5c, 0x4c, 0xfe, 0xde, 0x02, 0x8c, 0x42, 0x7c,
-	0x5c, 0x5c, 0xfe, 0x61, 0xae, 0x41, 0xf1, 0x3e, 0x9e, 0xbe, 0x9e, 0x21, 0x02, 0x4c, 0x46, 0x6e,
-	0x48, 0x8e, 0x0e, 0x4e, 0x2d, 0x2a, 0xcb, 0x4c, 0x4e, 0x15, 0x32, 0xe2, 0xe2, 0x84, 0x8b, 0x09,
-	0x09, 0xea, 0xa1, 0x7b, 0x4a, 0x4a, 0x08, 0xd3, 0xfe, 0x24, 0x36, 0x70, 0x18, 0x18, 0x03, 0x02,
-	0x00, 0x00, 0xff, 0xff, 0xfc, 0x97, 0xae, 0xf6, 0x12, 0x01, 0x00, 0x00,
+func (m *RateLimitResponse) GetLevel() RateLimitResponse_Level {
+	if m != nil {
+		return m.Level
+	}
+	return RateLimitResponse_KEY
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
+func (m *RateLimitResponse) GetPeriod() *PeriodLimit {
+	if m != nil {
+		return m.Period
+	}
+	return nil
+}
 
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
+func (m *RateLimitResponse) GetBannedUntilMs() int64 {
+	if m != nil {
+		return m.BannedUntilMs
+	}
+	return 0
+}
 
-// RateLimitServiceClient is the client API for RateLimitService service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type RateLimitServiceClient interface {
-	// Provides info about whether the rate limit should apply or not.
-	RateLimit(ctx context.Context, in *RateLimitRequest, opts ...grpc.CallOption) (*RateLimitResponse, error)
+// A limit of hits during a calendar period, like a month, instead of a
+// sliding window.
+type PeriodLimit struct {
+	Quantity uint32 `protobuf:"varint,1,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	// The period, e.g. "day", "month" or "month@15" for monthly periods
+	// starting on the 15th.
+	Period string `protobuf:"bytes,2,opt,name=period,proto3" json:"period,omitempty"`
+	// The hits still allowed in the current period, after the current one.
+	Remaining uint32 `protobuf:"varint,3,opt,name=remaining,proto3" json:"remaining,omitempty"`
+	// The bounds of the current period, in unix milliseconds.
+	StartMs              int64    `protobuf:"varint,4,opt,name=start_ms,json=startMs,proto3" json:"start_ms,omitempty"`
+	ResetAtMs            int64    `protobuf:"varint,5,opt,name=reset_at_ms,json=resetAtMs,proto3" json:"reset_at_ms,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-type rateLimitServiceClient struct {
-	cc *grpc.ClientConn
+func (m *PeriodLimit) Reset()         { *m = PeriodLimit{} }
+func (m *PeriodLimit) String() string { return proto.CompactTextString(m) }
+func (*PeriodLimit) ProtoMessage()    {}
+func (*PeriodLimit) Descriptor() ([]byte, []int) {
+	return fileDescriptor_022a6ac14e109943, []int{3}
 }
 
-func NewRateLimitServiceClient(cc *grpc.ClientConn) RateLimitServiceClient {
-	return &rateLimitServiceClient{cc}
+func (m *PeriodLimit) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PeriodLimit.Unmarshal(m, b)
+}
+func (m *PeriodLimit) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PeriodLimit.Marshal(b, m, deterministic)
+}
+func (m *PeriodLimit) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PeriodLimit.Merge(m, src)
+}
+func (m *PeriodLimit) XXX_Size() int {
+	return xxx_messageInfo_PeriodLimit.Size(m)
+}
+func (m *PeriodLimit) XXX_DiscardUnknown() {
+	xxx_messageInfo_PeriodLimit.DiscardUnknown(m)
 }
 
-func (c *rateLimitServiceClient) RateLimit(ctx context.Context, in *RateLimitRequest, opts ...grpc.CallOption) (*RateLimitResponse, error) {
-	out := new(RateLimitResponse)
-	err := c.cc.Invoke(ctx, "/RateLimitService/RateLimit", in, out, opts...)
-	if err != nil {
-		return nil, err
+var xxx_messageInfo_PeriodLimit proto.InternalMessageInfo
+
+func (m *PeriodLimit) GetQuantity() uint32 {
+	if m != nil {
+		return m.Quantity
 	}
-	return out, nil
+	return 0
 }
 
-// RateLimitServiceServer is the server API for RateLimitService service.
-type RateLimitServiceServer interface {
-	// Provides info about whether the rate limit should apply or not.
-	RateLimit(context.Context, *RateLimitRequest) (*RateLimitResponse, error)
+func (m *PeriodLimit) GetPeriod() string {
+	if m != nil {
+		return m.Period
+	}
+	return ""
 }
 
-func RegisterRateLimitServiceServer(s *grpc.Server, srv RateLimitServiceServer) {
-	s.RegisterService(&_RateLimitService_serviceDesc, srv)
+func (m *PeriodLimit) GetRemaining() uint32 {
+	if m != nil {
+		return m.Remaining
+	}
+	return 0
 }
 
-func _RateLimitService_RateLimit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(RateLimitRequest)
-	if err := dec(in); err != nil {
-		return nil, err
+func (m *PeriodLimit) GetStartMs() int64 {
+	if m != nil {
+		return m.StartMs
 	}
-	if interceptor == nil {
-		return srv.(RateLimitServiceServer).RateLimit(ctx, in)
+	return 0
+}
+
+func (m *PeriodLimit) GetResetAtMs() int64 {
+	if m != nil {
+		return m.ResetAtMs
 	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/RateLimitService/RateLimit",
+	return 0
+}
+
+// A rate limit: a quantity of hits per window of time.
+type Limit struct {
+	Quantity uint32 `protobuf:"varint,1,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	// The size of the window, in milliseconds.
+	WindowMs             int64    `protobuf:"varint,2,opt,name=window_ms,json=windowMs,proto3" json:"window_ms,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Limit) Reset()         { *m = Limit{} }
+func (m *Limit) String() string { return proto.CompactTextString(m) }
+func (*Limit) ProtoMessage()    {}
+func (*Limit) Descriptor() ([]byte, []int) {
+	return fileDescriptor_022a6ac14e109943, []int{4}
+}
+
+func (m *Limit) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Limit.Unmarshal(m, b)
+}
+func (m *Limit) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Limit.Marshal(b, m, deterministic)
+}
+func (m *Limit) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Limit.Merge(m, src)
+}
+func (m *Limit) XXX_Size() int {
+	return xxx_messageInfo_Limit.Size(m)
+}
+func (m *Limit) XXX_DiscardUnknown() {
+	xxx_messageInfo_Limit.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Limit proto.InternalMessageInfo
+
+func (m *Limit) GetQuantity() uint32 {
+	if m != nil {
+		return m.Quantity
 	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(RateLimitServiceServer).RateLimit(ctx, req.(*RateLimitRequest))
+	return 0
+}
+
+func (m *Limit) GetWindowMs() int64 {
+	if m != nil {
+		return m.WindowMs
 	}
-	return interceptor(ctx, in, info, handler)
+	return 0
 }
 
-var _RateLimitService_serviceDesc = grpc.ServiceDesc{
-	ServiceName: "RateLimitService",
-	HandlerType: (*RateLimitServiceServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "RateLimit",
-			Handler:    _RateLimitService_RateLimit_Handler,
+type AcquireRequest struct {
+	// See RateLimitRequest.
+	Owner       string        `protobuf:"bytes,1,opt,name=owner,proto3" json:"owner,omitempty"`
+	Resource    string        `protobuf:"bytes,2,opt,name=resource,proto3" json:"resource,omitempty"`
+	Descriptors []*Descriptor `protobuf:"bytes,3,rep,name=descriptors,proto3" json:"descriptors,omitempty"`
+	// For how long the lease is held if not released, so crashed callers do
+	// not keep their slots. 0 means the server default.
+	TtlMs                int64    `protobuf:"varint,4,opt,name=ttl_ms,json=ttlMs,proto3" json:"ttl_ms,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AcquireRequest) Reset()         { *m = AcquireRequest{} }
+func (m *AcquireRequest) String() string { return proto.CompactTextString(m) }
+func (*AcquireRequest) ProtoMessage()    {}
+func (*AcquireRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_022a6ac14e109943, []int{5}
+}
+
+func (m *AcquireRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AcquireRequest.Unmarshal(m, b)
+}
+func (m *AcquireRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AcquireRequest.Marshal(b, m, deterministic)
+}
+func (m *AcquireRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AcquireRequest.Merge(m, src)
+}
+func (m *AcquireRequest) XXX_Size() int {
+	return xxx_messageInfo_AcquireRequest.Size(m)
+}
+func (m *AcquireRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_AcquireRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AcquireRequest proto.InternalMessageInfo
+
+func (m *AcquireRequest) GetOwner() string {
+	if m != nil {
+		return m.Owner
+	}
+	return ""
+}
+
+func (m *AcquireRequest) GetResource() string {
+	if m != nil {
+		return m.Resource
+	}
+	return ""
+}
+
+func (m *AcquireRequest) GetDescriptors() []*Descriptor {
+	if m != nil {
+		return m.Descriptors
+	}
+	return nil
+}
+
+func (m *AcquireRequest) GetTtlMs() int64 {
+	if m != nil {
+		return m.TtlMs
+	}
+	return 0
+}
+
+type AcquireResponse struct {
+	// OK when the slot was acquired, OVER_LIMIT when there is none available.
+	Code RateLimitResponse_Code `protobuf:"varint,1,opt,name=code,proto3,enum=RateLimitResponse_Code" json:"code,omitempty"`
+	// The lease holding the slot, used for releasing it.
+	LeaseId string `protobuf:"bytes,2,opt,name=lease_id,json=leaseId,proto3" json:"lease_id,omitempty"`
+	// Max slots held at once.
+	Limit uint32 `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	// The slots still available, after the acquired one.
+	Remaining uint32 `protobuf:"varint,4,opt,name=remaining,proto3" json:"remaining,omitempty"`
+	// When the lease expires, in unix milliseconds.
+	ExpireAtMs           int64    `protobuf:"varint,5,opt,name=expire_at_ms,json=expireAtMs,proto3" json:"expire_at_ms,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AcquireResponse) Reset()         { *m = AcquireResponse{} }
+func (m *AcquireResponse) String() string { return proto.CompactTextString(m) }
+func (*AcquireResponse) ProtoMessage()    {}
+func (*AcquireResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_022a6ac14e109943, []int{6}
+}
+
+func (m *AcquireResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AcquireResponse.Unmarshal(m, b)
+}
+func (m *AcquireResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AcquireResponse.Marshal(b, m, deterministic)
+}
+func (m *AcquireResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AcquireResponse.Merge(m, src)
+}
+func (m *AcquireResponse) XXX_Size() int {
+	return xxx_messageInfo_AcquireResponse.Size(m)
+}
+func (m *AcquireResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_AcquireResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AcquireResponse proto.InternalMessageInfo
+
+func (m *AcquireResponse) GetCode() RateLimitResponse_Code {
+	if m != nil {
+		return m.Code
+	}
+	return RateLimitResponse_UNKNOWN
+}
+
+func (m *AcquireResponse) GetLeaseId() string {
+	if m != nil {
+		return m.LeaseId
+	}
+	return ""
+}
+
+func (m *AcquireResponse) GetLimit() uint32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *AcquireResponse) GetRemaining() uint32 {
+	if m != nil {
+		return m.Remaining
+	}
+	return 0
+}
+
+func (m *AcquireResponse) GetExpireAtMs() int64 {
+	if m != nil {
+		return m.ExpireAtMs
+	}
+	return 0
+}
+
+type ReleaseRequest struct {
+	// See RateLimitRequest. They should be the same used when acquiring.
+	Owner                string        `protobuf:"bytes,1,opt,name=owner,proto3" json:"owner,omitempty"`
+	Resource             string        `protobuf:"bytes,2,opt,name=resource,proto3" json:"resource,omitempty"`
+	Descriptors          []*Descriptor `protobuf:"bytes,3,rep,name=descriptors,proto3" json:"descriptors,omitempty"`
+	LeaseId              string        `protobuf:"bytes,4,opt,name=lease_id,json=leaseId,proto3" json:"lease_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *ReleaseRequest) Reset()         { *m = ReleaseRequest{} }
+func (m *ReleaseRequest) String() string { return proto.CompactTextString(m) }
+func (*ReleaseRequest) ProtoMessage()    {}
+func (*ReleaseRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_022a6ac14e109943, []int{7}
+}
+
+func (m *ReleaseRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReleaseRequest.Unmarshal(m, b)
+}
+func (m *ReleaseRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReleaseRequest.Marshal(b, m, deterministic)
+}
+func (m *ReleaseRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReleaseRequest.Merge(m, src)
+}
+func (m *ReleaseRequest) XXX_Size() int {
+	return xxx_messageInfo_ReleaseRequest.Size(m)
+}
+func (m *ReleaseRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReleaseRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReleaseRequest proto.InternalMessageInfo
+
+func (m *ReleaseRequest) GetOwner() string {
+	if m != nil {
+		return m.Owner
+	}
+	return ""
+}
+
+func (m *ReleaseRequest) GetResource() string {
+	if m != nil {
+		return m.Resource
+	}
+	return ""
+}
+
+func (m *ReleaseRequest) GetDescriptors() []*Descriptor {
+	if m != nil {
+		return m.Descriptors
+	}
+	return nil
+}
+
+func (m *ReleaseRequest) GetLeaseId() string {
+	if m != nil {
+		return m.LeaseId
+	}
+	return ""
+}
+
+type ReleaseResponse struct {
+	// False when the lease was not found, e.g. because it expired.
+	Released             bool     `protobuf:"varint,1,opt,name=released,proto3" json:"released,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReleaseResponse) Reset()         { *m = ReleaseResponse{} }
+func (m *ReleaseResponse) String() string { return proto.CompactTextString(m) }
+func (*ReleaseResponse) ProtoMessage()    {}
+func (*ReleaseResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_022a6ac14e109943, []int{8}
+}
+
+func (m *ReleaseResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReleaseResponse.Unmarshal(m, b)
+}
+func (m *ReleaseResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReleaseResponse.Marshal(b, m, deterministic)
+}
+func (m *ReleaseResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReleaseResponse.Merge(m, src)
+}
+func (m *ReleaseResponse) XXX_Size() int {
+	return xxx_messageInfo_ReleaseResponse.Size(m)
+}
+func (m *ReleaseResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReleaseResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReleaseResponse proto.InternalMessageInfo
+
+func (m *ReleaseResponse) GetReleased() bool {
+	if m != nil {
+		return m.Released
+	}
+	return false
+}
+
+type ReserveRequest struct {
+	// See RateLimitRequest.
+	Owner       string        `protobuf:"bytes,1,opt,name=owner,proto3" json:"owner,omitempty"`
+	Resource    string        `protobuf:"bytes,2,opt,name=resource,proto3" json:"resource,omitempty"`
+	Descriptors []*Descriptor `protobuf:"bytes,3,rep,name=descriptors,proto3" json:"descriptors,omitempty"`
+	// The permits wanted.
+	Permits uint32 `protobuf:"varint,4,opt,name=permits,proto3" json:"permits,omitempty"`
+	// For how long the caller wants to consume the permits. 0 means the
+	// server default.
+	TtlMs                int64    `protobuf:"varint,5,opt,name=ttl_ms,json=ttlMs,proto3" json:"ttl_ms,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReserveRequest) Reset()         { *m = ReserveRequest{} }
+func (m *ReserveRequest) String() string { return proto.CompactTextString(m) }
+func (*ReserveRequest) ProtoMessage()    {}
+func (*ReserveRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_022a6ac14e109943, []int{9}
+}
+
+func (m *ReserveRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReserveRequest.Unmarshal(m, b)
+}
+func (m *ReserveRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReserveRequest.Marshal(b, m, deterministic)
+}
+func (m *ReserveRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReserveRequest.Merge(m, src)
+}
+func (m *ReserveRequest) XXX_Size() int {
+	return xxx_messageInfo_ReserveRequest.Size(m)
+}
+func (m *ReserveRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReserveRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReserveRequest proto.InternalMessageInfo
+
+func (m *ReserveRequest) GetOwner() string {
+	if m != nil {
+		return m.Owner
+	}
+	return ""
+}
+
+func (m *ReserveRequest) GetResource() string {
+	if m != nil {
+		return m.Resource
+	}
+	return ""
+}
+
+func (m *ReserveRequest) GetDescriptors() []*Descriptor {
+	if m != nil {
+		return m.Descriptors
+	}
+	return nil
+}
+
+func (m *ReserveRequest) GetPermits() uint32 {
+	if m != nil {
+		return m.Permits
+	}
+	return 0
+}
+
+func (m *ReserveRequest) GetTtlMs() int64 {
+	if m != nil {
+		return m.TtlMs
+	}
+	return 0
+}
+
+type ReserveResponse struct {
+	// OK when some permits were reserved, OVER_LIMIT when none or the key
+	// is banned, and DENIED when the owner or resource is in the deny list.
+	// Owners and resources in the allow list get all the permits, without a
+	// lease.
+	Code RateLimitResponse_Code `protobuf:"varint,1,opt,name=code,proto3,enum=RateLimitResponse_Code" json:"code,omitempty"`
+	// The lease, used for returning the unused permits.
+	LeaseId string `protobuf:"bytes,2,opt,name=lease_id,json=leaseId,proto3" json:"lease_id,omitempty"`
+	// The permits reserved. May be less than the wanted ones.
+	Permits uint32 `protobuf:"varint,3,opt,name=permits,proto3" json:"permits,omitempty"`
+	// Until when the permits can be consumed, in unix milliseconds.
+	ExpireAtMs int64 `protobuf:"varint,4,opt,name=expire_at_ms,json=expireAtMs,proto3" json:"expire_at_ms,omitempty"`
+	// See RateLimitResponse. Remaining excludes the reserved permits.
+	Limit                *Limit   `protobuf:"bytes,5,opt,name=limit,proto3" json:"limit,omitempty"`
+	Remaining            uint32   `protobuf:"varint,6,opt,name=remaining,proto3" json:"remaining,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReserveResponse) Reset()         { *m = ReserveResponse{} }
+func (m *ReserveResponse) String() string { return proto.CompactTextString(m) }
+func (*ReserveResponse) ProtoMessage()    {}
+func (*ReserveResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_022a6ac14e109943, []int{10}
+}
+
+func (m *ReserveResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReserveResponse.Unmarshal(m, b)
+}
+func (m *ReserveResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReserveResponse.Marshal(b, m, deterministic)
+}
+func (m *ReserveResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReserveResponse.Merge(m, src)
+}
+func (m *ReserveResponse) XXX_Size() int {
+	return xxx_messageInfo_ReserveResponse.Size(m)
+}
+func (m *ReserveResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReserveResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReserveResponse proto.InternalMessageInfo
+
+func (m *ReserveResponse) GetCode() RateLimitResponse_Code {
+	if m != nil {
+		return m.Code
+	}
+	return RateLimitResponse_UNKNOWN
+}
+
+func (m *ReserveResponse) GetLeaseId() string {
+	if m != nil {
+		return m.LeaseId
+	}
+	return ""
+}
+
+func (m *ReserveResponse) GetPermits() uint32 {
+	if m != nil {
+		return m.Permits
+	}
+	return 0
+}
+
+func (m *ReserveResponse) GetExpireAtMs() int64 {
+	if m != nil {
+		return m.ExpireAtMs
+	}
+	return 0
+}
+
+func (m *ReserveResponse) GetLimit() *Limit {
+	if m != nil {
+		return m.Limit
+	}
+	return nil
+}
+
+func (m *ReserveResponse) GetRemaining() uint32 {
+	if m != nil {
+		return m.Remaining
+	}
+	return 0
+}
+
+type ReturnRequest struct {
+	// See RateLimitRequest. They should be the same used when reserving.
+	Owner       string        `protobuf:"bytes,1,opt,name=owner,proto3" json:"owner,omitempty"`
+	Resource    string        `protobuf:"bytes,2,opt,name=resource,proto3" json:"resource,omitempty"`
+	Descriptors []*Descriptor `protobuf:"bytes,3,rep,name=descriptors,proto3" json:"descriptors,omitempty"`
+	LeaseId     string        `protobuf:"bytes,4,opt,name=lease_id,json=leaseId,proto3" json:"lease_id,omitempty"`
+	// The permits not consumed.
+	Unused               uint32   `protobuf:"varint,5,opt,name=unused,proto3" json:"unused,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReturnRequest) Reset()         { *m = ReturnRequest{} }
+func (m *ReturnRequest) String() string { return proto.CompactTextString(m) }
+func (*ReturnRequest) ProtoMessage()    {}
+func (*ReturnRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_022a6ac14e109943, []int{11}
+}
+
+func (m *ReturnRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReturnRequest.Unmarshal(m, b)
+}
+func (m *ReturnRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReturnRequest.Marshal(b, m, deterministic)
+}
+func (m *ReturnRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReturnRequest.Merge(m, src)
+}
+func (m *ReturnRequest) XXX_Size() int {
+	return xxx_messageInfo_ReturnRequest.Size(m)
+}
+func (m *ReturnRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReturnRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReturnRequest proto.InternalMessageInfo
+
+func (m *ReturnRequest) GetOwner() string {
+	if m != nil {
+		return m.Owner
+	}
+	return ""
+}
+
+func (m *ReturnRequest) GetResource() string {
+	if m != nil {
+		return m.Resource
+	}
+	return ""
+}
+
+func (m *ReturnRequest) GetDescriptors() []*Descriptor {
+	if m != nil {
+		return m.Descriptors
+	}
+	return nil
+}
+
+func (m *ReturnRequest) GetLeaseId() string {
+	if m != nil {
+		return m.LeaseId
+	}
+	return ""
+}
+
+func (m *ReturnRequest) GetUnused() uint32 {
+	if m != nil {
+		return m.Unused
+	}
+	return 0
+}
+
+type ReturnResponse struct {
+	// The permits given back. Less than the unused ones if the lease is
+	// already out of the window.
+	Returned             uint32   `protobuf:"varint,1,opt,name=returned,proto3" json:"returned,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReturnResponse) Reset()         { *m = ReturnResponse{} }
+func (m *ReturnResponse) String() string { return proto.CompactTextString(m) }
+func (*ReturnResponse) ProtoMessage()    {}
+func (*ReturnResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_022a6ac14e109943, []int{12}
+}
+
+func (m *ReturnResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReturnResponse.Unmarshal(m, b)
+}
+func (m *ReturnResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReturnResponse.Marshal(b, m, deterministic)
+}
+func (m *ReturnResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReturnResponse.Merge(m, src)
+}
+func (m *ReturnResponse) XXX_Size() int {
+	return xxx_messageInfo_ReturnResponse.Size(m)
+}
+func (m *ReturnResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReturnResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReturnResponse proto.InternalMessageInfo
+
+func (m *ReturnResponse) GetReturned() uint32 {
+	if m != nil {
+		return m.Returned
+	}
+	return 0
+}
+
+type ExportUsageRequest struct {
+	// The time range, in unix milliseconds. The buckets starting before to_ms
+	// and ending after from_ms are exported.
+	FromMs int64 `protobuf:"varint,1,opt,name=from_ms,json=fromMs,proto3" json:"from_ms,omitempty"`
+	ToMs   int64 `protobuf:"varint,2,opt,name=to_ms,json=toMs,proto3" json:"to_ms,omitempty"`
+	// Exports the usage of a single owner. Empty for every owner.
+	Owner                string   `protobuf:"bytes,3,opt,name=owner,proto3" json:"owner,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ExportUsageRequest) Reset()         { *m = ExportUsageRequest{} }
+func (m *ExportUsageRequest) String() string { return proto.CompactTextString(m) }
+func (*ExportUsageRequest) ProtoMessage()    {}
+func (*ExportUsageRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_022a6ac14e109943, []int{13}
+}
+
+func (m *ExportUsageRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ExportUsageRequest.Unmarshal(m, b)
+}
+func (m *ExportUsageRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ExportUsageRequest.Marshal(b, m, deterministic)
+}
+func (m *ExportUsageRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExportUsageRequest.Merge(m, src)
+}
+func (m *ExportUsageRequest) XXX_Size() int {
+	return xxx_messageInfo_ExportUsageRequest.Size(m)
+}
+func (m *ExportUsageRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ExportUsageRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ExportUsageRequest proto.InternalMessageInfo
+
+func (m *ExportUsageRequest) GetFromMs() int64 {
+	if m != nil {
+		return m.FromMs
+	}
+	return 0
+}
+
+func (m *ExportUsageRequest) GetToMs() int64 {
+	if m != nil {
+		return m.ToMs
+	}
+	return 0
+}
+
+func (m *ExportUsageRequest) GetOwner() string {
+	if m != nil {
+		return m.Owner
+	}
+	return ""
+}
+
+// The hits of an owner on a resource during a bucket of time.
+type Usage struct {
+	Owner    string `protobuf:"bytes,1,opt,name=owner,proto3" json:"owner,omitempty"`
+	Resource string `protobuf:"bytes,2,opt,name=resource,proto3" json:"resource,omitempty"`
+	// The start of the bucket, in unix milliseconds.
+	BucketMs             int64    `protobuf:"varint,3,opt,name=bucket_ms,json=bucketMs,proto3" json:"bucket_ms,omitempty"`
+	Allowed              uint64   `protobuf:"varint,4,opt,name=allowed,proto3" json:"allowed,omitempty"`
+	Rejected             uint64   `protobuf:"varint,5,opt,name=rejected,proto3" json:"rejected,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Usage) Reset()         { *m = Usage{} }
+func (m *Usage) String() string { return proto.CompactTextString(m) }
+func (*Usage) ProtoMessage()    {}
+func (*Usage) Descriptor() ([]byte, []int) {
+	return fileDescriptor_022a6ac14e109943, []int{14}
+}
+
+func (m *Usage) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Usage.Unmarshal(m, b)
+}
+func (m *Usage) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Usage.Marshal(b, m, deterministic)
+}
+func (m *Usage) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Usage.Merge(m, src)
+}
+func (m *Usage) XXX_Size() int {
+	return xxx_messageInfo_Usage.Size(m)
+}
+func (m *Usage) XXX_DiscardUnknown() {
+	xxx_messageInfo_Usage.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Usage proto.InternalMessageInfo
+
+func (m *Usage) GetOwner() string {
+	if m != nil {
+		return m.Owner
+	}
+	return ""
+}
+
+func (m *Usage) GetResource() string {
+	if m != nil {
+		return m.Resource
+	}
+	return ""
+}
+
+func (m *Usage) GetBucketMs() int64 {
+	if m != nil {
+		return m.BucketMs
+	}
+	return 0
+}
+
+func (m *Usage) GetAllowed() uint64 {
+	if m != nil {
+		return m.Allowed
+	}
+	return 0
+}
+
+func (m *Usage) GetRejected() uint64 {
+	if m != nil {
+		return m.Rejected
+	}
+	return 0
+}
+
+type ExportUsageResponse struct {
+	// Sorted by bucket, owner and resource.
+	Usage []*Usage `protobuf:"bytes,1,rep,name=usage,proto3" json:"usage,omitempty"`
+	// The size of the buckets, in milliseconds.
+	BucketSizeMs         int64    `protobuf:"varint,2,opt,name=bucket_size_ms,json=bucketSizeMs,proto3" json:"bucket_size_ms,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ExportUsageResponse) Reset()         { *m = ExportUsageResponse{} }
+func (m *ExportUsageResponse) String() string { return proto.CompactTextString(m) }
+func (*ExportUsageResponse) ProtoMessage()    {}
+func (*ExportUsageResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_022a6ac14e109943, []int{15}
+}
+
+func (m *ExportUsageResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ExportUsageResponse.Unmarshal(m, b)
+}
+func (m *ExportUsageResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ExportUsageResponse.Marshal(b, m, deterministic)
+}
+func (m *ExportUsageResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExportUsageResponse.Merge(m, src)
+}
+func (m *ExportUsageResponse) XXX_Size() int {
+	return xxx_messageInfo_ExportUsageResponse.Size(m)
+}
+func (m *ExportUsageResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ExportUsageResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ExportUsageResponse proto.InternalMessageInfo
+
+func (m *ExportUsageResponse) GetUsage() []*Usage {
+	if m != nil {
+		return m.Usage
+	}
+	return nil
+}
+
+func (m *ExportUsageResponse) GetBucketSizeMs() int64 {
+	if m != nil {
+		return m.BucketSizeMs
+	}
+	return 0
+}
+
+// An entry of the allow or deny list. Hits of the allowed entries bypass
+// their limits, and hits of the denied ones are answered with DENIED. Deny
+// entries win over allow ones.
+type AccessEntry struct {
+	List AccessEntry_List `protobuf:"varint,1,opt,name=list,proto3,enum=AccessEntry_List" json:"list,omitempty"`
+	// Patterns with the syntax of Go's path.Match, empty matching anything.
+	// The owner may be a CIDR as well, matching the owners that are IPs in
+	// its range. At least one of them is required.
+	//
+	// Examples:
+	//   1. owner: "health-checker"
+	//   2. owner: "203.0.113.0/24", resource: "/v1/*"
+	Owner                string   `protobuf:"bytes,2,opt,name=owner,proto3" json:"owner,omitempty"`
+	Resource             string   `protobuf:"bytes,3,opt,name=resource,proto3" json:"resource,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AccessEntry) Reset()         { *m = AccessEntry{} }
+func (m *AccessEntry) String() string { return proto.CompactTextString(m) }
+func (*AccessEntry) ProtoMessage()    {}
+func (*AccessEntry) Descriptor() ([]byte, []int) {
+	return fileDescriptor_022a6ac14e109943, []int{16}
+}
+
+func (m *AccessEntry) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AccessEntry.Unmarshal(m, b)
+}
+func (m *AccessEntry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AccessEntry.Marshal(b, m, deterministic)
+}
+func (m *AccessEntry) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AccessEntry.Merge(m, src)
+}
+func (m *AccessEntry) XXX_Size() int {
+	return xxx_messageInfo_AccessEntry.Size(m)
+}
+func (m *AccessEntry) XXX_DiscardUnknown() {
+	xxx_messageInfo_AccessEntry.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AccessEntry proto.InternalMessageInfo
+
+func (m *AccessEntry) GetList() AccessEntry_List {
+	if m != nil {
+		return m.List
+	}
+	return AccessEntry_ALLOW
+}
+
+func (m *AccessEntry) GetOwner() string {
+	if m != nil {
+		return m.Owner
+	}
+	return ""
+}
+
+func (m *AccessEntry) GetResource() string {
+	if m != nil {
+		return m.Resource
+	}
+	return ""
+}
+
+type ListAccessEntriesRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListAccessEntriesRequest) Reset()         { *m = ListAccessEntriesRequest{} }
+func (m *ListAccessEntriesRequest) String() string { return proto.CompactTextString(m) }
+func (*ListAccessEntriesRequest) ProtoMessage()    {}
+func (*ListAccessEntriesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_022a6ac14e109943, []int{17}
+}
+
+func (m *ListAccessEntriesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListAccessEntriesRequest.Unmarshal(m, b)
+}
+func (m *ListAccessEntriesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListAccessEntriesRequest.Marshal(b, m, deterministic)
+}
+func (m *ListAccessEntriesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListAccessEntriesRequest.Merge(m, src)
+}
+func (m *ListAccessEntriesRequest) XXX_Size() int {
+	return xxx_messageInfo_ListAccessEntriesRequest.Size(m)
+}
+func (m *ListAccessEntriesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListAccessEntriesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListAccessEntriesRequest proto.InternalMessageInfo
+
+type ListAccessEntriesResponse struct {
+	// The allow entries first, then the deny ones.
+	Entries              []*AccessEntry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *ListAccessEntriesResponse) Reset()         { *m = ListAccessEntriesResponse{} }
+func (m *ListAccessEntriesResponse) String() string { return proto.CompactTextString(m) }
+func (*ListAccessEntriesResponse) ProtoMessage()    {}
+func (*ListAccessEntriesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_022a6ac14e109943, []int{18}
+}
+
+func (m *ListAccessEntriesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListAccessEntriesResponse.Unmarshal(m, b)
+}
+func (m *ListAccessEntriesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListAccessEntriesResponse.Marshal(b, m, deterministic)
+}
+func (m *ListAccessEntriesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListAccessEntriesResponse.Merge(m, src)
+}
+func (m *ListAccessEntriesResponse) XXX_Size() int {
+	return xxx_messageInfo_ListAccessEntriesResponse.Size(m)
+}
+func (m *ListAccessEntriesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListAccessEntriesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListAccessEntriesResponse proto.InternalMessageInfo
+
+func (m *ListAccessEntriesResponse) GetEntries() []*AccessEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+type AddAccessEntryResponse struct {
+	// False when the entry was already in the list.
+	Added                bool     `protobuf:"varint,1,opt,name=added,proto3" json:"added,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AddAccessEntryResponse) Reset()         { *m = AddAccessEntryResponse{} }
+func (m *AddAccessEntryResponse) String() string { return proto.CompactTextString(m) }
+func (*AddAccessEntryResponse) ProtoMessage()    {}
+func (*AddAccessEntryResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_022a6ac14e109943, []int{19}
+}
+
+func (m *AddAccessEntryResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AddAccessEntryResponse.Unmarshal(m, b)
+}
+func (m *AddAccessEntryResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AddAccessEntryResponse.Marshal(b, m, deterministic)
+}
+func (m *AddAccessEntryResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AddAccessEntryResponse.Merge(m, src)
+}
+func (m *AddAccessEntryResponse) XXX_Size() int {
+	return xxx_messageInfo_AddAccessEntryResponse.Size(m)
+}
+func (m *AddAccessEntryResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_AddAccessEntryResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AddAccessEntryResponse proto.InternalMessageInfo
+
+func (m *AddAccessEntryResponse) GetAdded() bool {
+	if m != nil {
+		return m.Added
+	}
+	return false
+}
+
+type RemoveAccessEntryResponse struct {
+	// False when the entry was not in the list.
+	Removed              bool     `protobuf:"varint,1,opt,name=removed,proto3" json:"removed,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RemoveAccessEntryResponse) Reset()         { *m = RemoveAccessEntryResponse{} }
+func (m *RemoveAccessEntryResponse) String() string { return proto.CompactTextString(m) }
+func (*RemoveAccessEntryResponse) ProtoMessage()    {}
+func (*RemoveAccessEntryResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_022a6ac14e109943, []int{20}
+}
+
+func (m *RemoveAccessEntryResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RemoveAccessEntryResponse.Unmarshal(m, b)
+}
+func (m *RemoveAccessEntryResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RemoveAccessEntryResponse.Marshal(b, m, deterministic)
+}
+func (m *RemoveAccessEntryResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RemoveAccessEntryResponse.Merge(m, src)
+}
+func (m *RemoveAccessEntryResponse) XXX_Size() int {
+	return xxx_messageInfo_RemoveAccessEntryResponse.Size(m)
+}
+func (m *RemoveAccessEntryResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_RemoveAccessEntryResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RemoveAccessEntryResponse proto.InternalMessageInfo
+
+func (m *RemoveAccessEntryResponse) GetRemoved() bool {
+	if m != nil {
+		return m.Removed
+	}
+	return false
+}
+
+type UnbanRequest struct {
+	// See RateLimitRequest. They should be the same of the banned requests.
+	Owner                string        `protobuf:"bytes,1,opt,name=owner,proto3" json:"owner,omitempty"`
+	Resource             string        `protobuf:"bytes,2,opt,name=resource,proto3" json:"resource,omitempty"`
+	Descriptors          []*Descriptor `protobuf:"bytes,3,rep,name=descriptors,proto3" json:"descriptors,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *UnbanRequest) Reset()         { *m = UnbanRequest{} }
+func (m *UnbanRequest) String() string { return proto.CompactTextString(m) }
+func (*UnbanRequest) ProtoMessage()    {}
+func (*UnbanRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_022a6ac14e109943, []int{21}
+}
+
+func (m *UnbanRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UnbanRequest.Unmarshal(m, b)
+}
+func (m *UnbanRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UnbanRequest.Marshal(b, m, deterministic)
+}
+func (m *UnbanRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UnbanRequest.Merge(m, src)
+}
+func (m *UnbanRequest) XXX_Size() int {
+	return xxx_messageInfo_UnbanRequest.Size(m)
+}
+func (m *UnbanRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_UnbanRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UnbanRequest proto.InternalMessageInfo
+
+func (m *UnbanRequest) GetOwner() string {
+	if m != nil {
+		return m.Owner
+	}
+	return ""
+}
+
+func (m *UnbanRequest) GetResource() string {
+	if m != nil {
+		return m.Resource
+	}
+	return ""
+}
+
+func (m *UnbanRequest) GetDescriptors() []*Descriptor {
+	if m != nil {
+		return m.Descriptors
+	}
+	return nil
+}
+
+type UnbanResponse struct {
+	// False when the key was not banned.
+	Unbanned             bool     `protobuf:"varint,1,opt,name=unbanned,proto3" json:"unbanned,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UnbanResponse) Reset()         { *m = UnbanResponse{} }
+func (m *UnbanResponse) String() string { return proto.CompactTextString(m) }
+func (*UnbanResponse) ProtoMessage()    {}
+func (*UnbanResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_022a6ac14e109943, []int{22}
+}
+
+func (m *UnbanResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UnbanResponse.Unmarshal(m, b)
+}
+func (m *UnbanResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UnbanResponse.Marshal(b, m, deterministic)
+}
+func (m *UnbanResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UnbanResponse.Merge(m, src)
+}
+func (m *UnbanResponse) XXX_Size() int {
+	return xxx_messageInfo_UnbanResponse.Size(m)
+}
+func (m *UnbanResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_UnbanResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UnbanResponse proto.InternalMessageInfo
+
+func (m *UnbanResponse) GetUnbanned() bool {
+	if m != nil {
+		return m.Unbanned
+	}
+	return false
+}
+
+// A grow-only counter of the hits of a key during a bucket of time, with one entry per ratio instance (node).
+type GCounter struct {
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// Start of the bucket of time, in unix milliseconds.
+	Bucket int64 `protobuf:"varint,2,opt,name=bucket,proto3" json:"bucket,omitempty"`
+	// Unix milliseconds after which the counter can be discarded.
+	ExpireAt int64 `protobuf:"varint,3,opt,name=expire_at,json=expireAt,proto3" json:"expire_at,omitempty"`
+	// Hits counted by each node.
+	Counts               map[string]int64 `protobuf:"bytes,4,rep,name=counts,proto3" json:"counts,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *GCounter) Reset()         { *m = GCounter{} }
+func (m *GCounter) String() string { return proto.CompactTextString(m) }
+func (*GCounter) ProtoMessage()    {}
+func (*GCounter) Descriptor() ([]byte, []int) {
+	return fileDescriptor_022a6ac14e109943, []int{23}
+}
+
+func (m *GCounter) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GCounter.Unmarshal(m, b)
+}
+func (m *GCounter) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GCounter.Marshal(b, m, deterministic)
+}
+func (m *GCounter) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GCounter.Merge(m, src)
+}
+func (m *GCounter) XXX_Size() int {
+	return xxx_messageInfo_GCounter.Size(m)
+}
+func (m *GCounter) XXX_DiscardUnknown() {
+	xxx_messageInfo_GCounter.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GCounter proto.InternalMessageInfo
+
+func (m *GCounter) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *GCounter) GetBucket() int64 {
+	if m != nil {
+		return m.Bucket
+	}
+	return 0
+}
+
+func (m *GCounter) GetExpireAt() int64 {
+	if m != nil {
+		return m.ExpireAt
+	}
+	return 0
+}
+
+func (m *GCounter) GetCounts() map[string]int64 {
+	if m != nil {
+		return m.Counts
+	}
+	return nil
+}
+
+type GossipRequest struct {
+	// The node sending the counters.
+	Node                 string      `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	Counters             []*GCounter `protobuf:"bytes,2,rep,name=counters,proto3" json:"counters,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *GossipRequest) Reset()         { *m = GossipRequest{} }
+func (m *GossipRequest) String() string { return proto.CompactTextString(m) }
+func (*GossipRequest) ProtoMessage()    {}
+func (*GossipRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_022a6ac14e109943, []int{24}
+}
+
+func (m *GossipRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GossipRequest.Unmarshal(m, b)
+}
+func (m *GossipRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GossipRequest.Marshal(b, m, deterministic)
+}
+func (m *GossipRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GossipRequest.Merge(m, src)
+}
+func (m *GossipRequest) XXX_Size() int {
+	return xxx_messageInfo_GossipRequest.Size(m)
+}
+func (m *GossipRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GossipRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GossipRequest proto.InternalMessageInfo
+
+func (m *GossipRequest) GetNode() string {
+	if m != nil {
+		return m.Node
+	}
+	return ""
+}
+
+func (m *GossipRequest) GetCounters() []*GCounter {
+	if m != nil {
+		return m.Counters
+	}
+	return nil
+}
+
+type GossipResponse struct {
+	// The node answering with its counters.
+	Node                 string      `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	Counters             []*GCounter `protobuf:"bytes,2,rep,name=counters,proto3" json:"counters,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *GossipResponse) Reset()         { *m = GossipResponse{} }
+func (m *GossipResponse) String() string { return proto.CompactTextString(m) }
+func (*GossipResponse) ProtoMessage()    {}
+func (*GossipResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_022a6ac14e109943, []int{25}
+}
+
+func (m *GossipResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GossipResponse.Unmarshal(m, b)
+}
+func (m *GossipResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GossipResponse.Marshal(b, m, deterministic)
+}
+func (m *GossipResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GossipResponse.Merge(m, src)
+}
+func (m *GossipResponse) XXX_Size() int {
+	return xxx_messageInfo_GossipResponse.Size(m)
+}
+func (m *GossipResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GossipResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GossipResponse proto.InternalMessageInfo
+
+func (m *GossipResponse) GetNode() string {
+	if m != nil {
+		return m.Node
+	}
+	return ""
+}
+
+func (m *GossipResponse) GetCounters() []*GCounter {
+	if m != nil {
+		return m.Counters
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterEnum("RateLimitResponse_Code", RateLimitResponse_Code_name, RateLimitResponse_Code_value)
+	proto.RegisterEnum("RateLimitResponse_Level", RateLimitResponse_Level_name, RateLimitResponse_Level_value)
+	proto.RegisterEnum("AccessEntry_List", AccessEntry_List_name, AccessEntry_List_value)
+	proto.RegisterType((*RateLimitRequest)(nil), "RateLimitRequest")
+	proto.RegisterType((*Descriptor)(nil), "Descriptor")
+	proto.RegisterType((*RateLimitResponse)(nil), "RateLimitResponse")
+	proto.RegisterType((*PeriodLimit)(nil), "PeriodLimit")
+	proto.RegisterType((*Limit)(nil), "Limit")
+	proto.RegisterType((*AcquireRequest)(nil), "AcquireRequest")
+	proto.RegisterType((*AcquireResponse)(nil), "AcquireResponse")
+	proto.RegisterType((*ReleaseRequest)(nil), "ReleaseRequest")
+	proto.RegisterType((*ReleaseResponse)(nil), "ReleaseResponse")
+	proto.RegisterType((*ReserveRequest)(nil), "ReserveRequest")
+	proto.RegisterType((*ReserveResponse)(nil), "ReserveResponse")
+	proto.RegisterType((*ReturnRequest)(nil), "ReturnRequest")
+	proto.RegisterType((*ReturnResponse)(nil), "ReturnResponse")
+	proto.RegisterType((*ExportUsageRequest)(nil), "ExportUsageRequest")
+	proto.RegisterType((*Usage)(nil), "Usage")
+	proto.RegisterType((*ExportUsageResponse)(nil), "ExportUsageResponse")
+	proto.RegisterType((*AccessEntry)(nil), "AccessEntry")
+	proto.RegisterType((*ListAccessEntriesRequest)(nil), "ListAccessEntriesRequest")
+	proto.RegisterType((*ListAccessEntriesResponse)(nil), "ListAccessEntriesResponse")
+	proto.RegisterType((*AddAccessEntryResponse)(nil), "AddAccessEntryResponse")
+	proto.RegisterType((*RemoveAccessEntryResponse)(nil), "RemoveAccessEntryResponse")
+	proto.RegisterType((*UnbanRequest)(nil), "UnbanRequest")
+	proto.RegisterType((*UnbanResponse)(nil), "UnbanResponse")
+	proto.RegisterType((*GCounter)(nil), "GCounter")
+	proto.RegisterMapType((map[string]int64)(nil), "GCounter.CountsEntry")
+	proto.RegisterType((*GossipRequest)(nil), "GossipRequest")
+	proto.RegisterType((*GossipResponse)(nil), "GossipResponse")
+}
+
+func init() { proto.RegisterFile("ratio.proto", fileDescriptor_022a6ac14e109943) }
+
+var fileDescriptor_022a6ac14e109943 = []byte{
+	// 1296 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xcc, 0x57, 0xcd, 0x6e, 0xdb, 0x46,
+	0x10, 0x0e, 0xc5, 0x1f, 0x49, 0xa3, 0x3f, 0x7a, 0xf3, 0x27, 0x33, 0x41, 0x61, 0x2c, 0x92, 0xc0,
+	0x41, 0x92, 0x3d, 0xa8, 0x0d, 0x92, 0x06, 0x28, 0x50, 0xd5, 0x56, 0x03, 0xc7, 0x92, 0x95, 0x32,
+	0x75, 0xd3, 0x9c, 0x0c, 0x9a, 0xdc, 0x06, 0x6c, 0x24, 0x52, 0xe1, 0x2e, 0xed, 0x24, 0xd7, 0x5e,
+	0x7a, 0xe8, 0x13, 0xf4, 0xd8, 0x3e, 0x40, 0x2f, 0xbd, 0xf5, 0x29, 0xfa, 0x1a, 0x7d, 0x8a, 0x62,
+	0x77, 0x49, 0x8a, 0x94, 0xed, 0xa6, 0x08, 0x0a, 0xa3, 0x27, 0x6b, 0x66, 0xb8, 0x3b, 0x33, 0xdf,
+	0x7c, 0xfb, 0xed, 0x1a, 0x5a, 0x89, 0xc7, 0xc3, 0x98, 0x2c, 0x92, 0x98, 0xc7, 0x98, 0x81, 0xed,
+	0x7a, 0x9c, 0x8e, 0xc3, 0x79, 0xc8, 0x5d, 0xfa, 0x3a, 0xa5, 0x8c, 0xa3, 0x4b, 0x60, 0xc6, 0xc7,
+	0x11, 0x4d, 0xfa, 0xda, 0x86, 0xb6, 0xd9, 0x74, 0x95, 0x81, 0x1c, 0x68, 0x24, 0x94, 0xc5, 0x69,
+	0xe2, 0xd3, 0x7e, 0x4d, 0x06, 0x0a, 0x1b, 0xdd, 0x83, 0x56, 0x40, 0x99, 0x9f, 0x84, 0x0b, 0x1e,
+	0x27, 0xac, 0xaf, 0x6f, 0xe8, 0x9b, 0xad, 0x41, 0x8b, 0x6c, 0x17, 0x3e, 0xb7, 0x1c, 0xc7, 0x9f,
+	0x00, 0x2c, 0x43, 0xc8, 0x06, 0xfd, 0x15, 0x7d, 0x9b, 0x25, 0x13, 0x3f, 0x45, 0x01, 0x47, 0xde,
+	0x2c, 0xcd, 0xf3, 0x28, 0x03, 0xff, 0x55, 0x83, 0xb5, 0x52, 0xad, 0x6c, 0x11, 0x47, 0x8c, 0xa2,
+	0x3b, 0x60, 0xf8, 0x71, 0x40, 0xe5, 0xf2, 0xee, 0xe0, 0x2a, 0x39, 0xf1, 0x05, 0xd9, 0x8a, 0x03,
+	0xea, 0xca, 0x8f, 0xd0, 0x75, 0x30, 0x67, 0x22, 0x26, 0x37, 0x6e, 0x0d, 0x2c, 0xa2, 0xbe, 0x54,
+	0x4e, 0x74, 0x1d, 0x9a, 0x09, 0x9d, 0x7b, 0x61, 0x14, 0x46, 0x2f, 0xfb, 0xfa, 0x86, 0xb6, 0xd9,
+	0x71, 0x97, 0x0e, 0x44, 0xc0, 0x9c, 0xd1, 0x23, 0x3a, 0xeb, 0x1b, 0x32, 0x53, 0xff, 0x94, 0x4c,
+	0x63, 0x11, 0x77, 0xd5, 0x67, 0xe8, 0x06, 0x58, 0x0b, 0x9a, 0x84, 0x71, 0xd0, 0x37, 0x65, 0xb2,
+	0x36, 0x79, 0x2a, 0x4d, 0xb5, 0x24, 0x8b, 0xa1, 0x5b, 0xd0, 0x3b, 0xf4, 0xa2, 0x88, 0x06, 0x07,
+	0x69, 0xc4, 0xc3, 0xd9, 0xc1, 0x9c, 0xf5, 0xad, 0x0d, 0x6d, 0x53, 0x77, 0x3b, 0xca, 0xbd, 0x2f,
+	0xbc, 0x13, 0x86, 0x1f, 0x80, 0x21, 0xfa, 0x40, 0x2d, 0xa8, 0xef, 0xef, 0xed, 0xee, 0x4d, 0x9f,
+	0xef, 0xd9, 0x17, 0x90, 0x05, 0xb5, 0xe9, 0xae, 0xad, 0xa1, 0x2e, 0xc0, 0xf4, 0x9b, 0x91, 0x7b,
+	0x30, 0xde, 0x99, 0xec, 0x7c, 0x6d, 0xd7, 0x10, 0x80, 0xb5, 0x3d, 0xda, 0xdb, 0x19, 0x6d, 0xdb,
+	0x3a, 0xbe, 0x0f, 0xa6, 0x2c, 0x0b, 0xd5, 0x41, 0xdf, 0x1d, 0xbd, 0xb0, 0x2f, 0x88, 0xe8, 0xe3,
+	0xf1, 0xf4, 0x8b, 0xe1, 0xd8, 0xd6, 0x50, 0x13, 0xcc, 0xe9, 0xf3, 0xbd, 0x91, 0x6b, 0xd7, 0x50,
+	0x1b, 0x1a, 0xee, 0xe8, 0xd9, 0x74, 0xdf, 0xdd, 0x1a, 0xd9, 0x3a, 0xfe, 0x59, 0x83, 0x56, 0xa9,
+	0x5e, 0x31, 0xfd, 0xd7, 0xa9, 0x17, 0xf1, 0x90, 0xab, 0x49, 0x75, 0xdc, 0xc2, 0x46, 0x57, 0x8a,
+	0x4e, 0xd5, 0xbc, 0xf2, 0xde, 0xfe, 0x19, 0xcf, 0x75, 0x68, 0x30, 0xee, 0x25, 0x5c, 0xb4, 0x6c,
+	0xc8, 0x96, 0xeb, 0xd2, 0x9e, 0x30, 0xf4, 0x11, 0xb4, 0x12, 0xca, 0x28, 0x3f, 0xf0, 0x64, 0xd4,
+	0x94, 0xd1, 0xa6, 0x74, 0x0d, 0xf9, 0x84, 0xe1, 0xcf, 0xc1, 0x7c, 0x7f, 0x55, 0xd7, 0xa0, 0x79,
+	0x1c, 0x46, 0x41, 0x7c, 0x2c, 0xb6, 0xa8, 0xc9, 0x2d, 0x1a, 0xca, 0x31, 0x61, 0xf8, 0x47, 0x0d,
+	0xba, 0x43, 0xff, 0x75, 0x1a, 0x26, 0xf4, 0xbc, 0x58, 0x8f, 0x2e, 0x83, 0xc5, 0xf9, 0x6c, 0xd9,
+	0xae, 0xc9, 0xb9, 0x98, 0xec, 0x6f, 0x1a, 0xf4, 0x8a, 0x52, 0x3e, 0x84, 0xd4, 0xeb, 0xd0, 0x98,
+	0x51, 0x8f, 0xd1, 0x83, 0x30, 0x1f, 0x40, 0x5d, 0xda, 0x3b, 0x81, 0xe8, 0x49, 0xf1, 0x5d, 0xa1,
+	0x7f, 0x1a, 0xcf, 0x8d, 0xd5, 0xb9, 0x6c, 0x40, 0x9b, 0xbe, 0x59, 0x84, 0x09, 0xad, 0xa0, 0x0f,
+	0xca, 0x27, 0xe1, 0xff, 0x49, 0x83, 0xae, 0x4b, 0x65, 0x8e, 0x73, 0x03, 0xaf, 0xdc, 0xa4, 0x51,
+	0x69, 0x12, 0xdf, 0x83, 0x5e, 0x51, 0x4d, 0x86, 0x9f, 0x4c, 0x2c, 0x5d, 0x81, 0xac, 0xa8, 0xe1,
+	0x16, 0x36, 0xfe, 0x45, 0x56, 0xcf, 0x68, 0x72, 0x74, 0x7e, 0xd5, 0xf7, 0xa1, 0xbe, 0xa0, 0xc9,
+	0x3c, 0xe4, 0x2c, 0xc3, 0x3b, 0x37, 0x4b, 0xa4, 0x30, 0xcb, 0xa4, 0xf8, 0x53, 0x83, 0x5e, 0x51,
+	0xe4, 0x7f, 0x4c, 0x8a, 0x52, 0x31, 0x7a, 0xb5, 0x98, 0xd5, 0xd1, 0x1b, 0xab, 0xa3, 0x5f, 0x0a,
+	0xa8, 0xf9, 0x5e, 0x01, 0xb5, 0x56, 0x88, 0x85, 0x7f, 0xd5, 0xa0, 0xe3, 0x52, 0x9e, 0x26, 0xd1,
+	0xff, 0x80, 0x35, 0x42, 0xb4, 0xd2, 0x28, 0x15, 0x04, 0x31, 0x65, 0xa1, 0x99, 0x85, 0xef, 0x42,
+	0x37, 0x2f, 0xb2, 0x4c, 0x26, 0xe1, 0xc9, 0xc8, 0xd4, 0x71, 0x0b, 0x1b, 0x7f, 0x0b, 0x68, 0xf4,
+	0x66, 0x11, 0x27, 0x7c, 0x9f, 0x79, 0x2f, 0x0b, 0x3e, 0x5d, 0x85, 0xfa, 0x77, 0x49, 0x3c, 0x17,
+	0x10, 0x6a, 0x12, 0x42, 0x4b, 0x98, 0x13, 0x86, 0x2e, 0x82, 0xc9, 0xe3, 0xa5, 0x1e, 0x19, 0x3c,
+	0x9e, 0xb0, 0x25, 0x0a, 0x7a, 0x09, 0x05, 0x71, 0xc8, 0x4c, 0xb9, 0xe9, 0x07, 0xa0, 0x74, 0x0d,
+	0x9a, 0x87, 0xa9, 0xff, 0x8a, 0xca, 0x21, 0xea, 0x4a, 0xfa, 0x94, 0x63, 0x22, 0xb9, 0xe8, 0xcd,
+	0x66, 0xf1, 0x31, 0x55, 0x90, 0x18, 0x6e, 0x6e, 0xaa, 0x2d, 0xbf, 0xa7, 0x3e, 0xcf, 0x40, 0x31,
+	0xdc, 0xc2, 0xc6, 0x2f, 0xe0, 0x62, 0xa5, 0xd1, 0x0c, 0x9b, 0xeb, 0x60, 0xa6, 0xc2, 0xd1, 0xd7,
+	0xe4, 0x24, 0x2c, 0xa2, 0xc2, 0xca, 0x89, 0x6e, 0x40, 0x37, 0xab, 0x83, 0x85, 0xef, 0xe8, 0xb2,
+	0xef, 0xb6, 0xf2, 0x3e, 0x0b, 0xdf, 0xd1, 0x09, 0xc3, 0x3f, 0x68, 0xd0, 0x1a, 0xfa, 0x3e, 0x65,
+	0x6c, 0x14, 0xf1, 0xe4, 0x2d, 0xba, 0x09, 0xc6, 0x2c, 0x64, 0x3c, 0xe3, 0xf9, 0x1a, 0x29, 0xc5,
+	0xc8, 0x38, 0x64, 0xdc, 0x95, 0xe1, 0x25, 0x2c, 0xb5, 0xb3, 0x60, 0xd1, 0xab, 0xb0, 0xe0, 0x6b,
+	0x60, 0x88, 0xf5, 0xe2, 0xd2, 0x1b, 0x8e, 0xc7, 0xd3, 0xe7, 0xf6, 0x05, 0xd4, 0x00, 0x63, 0x7b,
+	0xb4, 0xf7, 0xc2, 0xd6, 0xb0, 0x03, 0x7d, 0x11, 0x5c, 0x26, 0x0b, 0x29, 0xcb, 0xe6, 0x89, 0xb7,
+	0x60, 0xfd, 0x94, 0x58, 0x06, 0xc1, 0x2d, 0xa8, 0x53, 0xe5, 0xca, 0x40, 0x68, 0x97, 0x2b, 0x76,
+	0xf3, 0x20, 0x26, 0x70, 0x65, 0x18, 0x04, 0xe5, 0x50, 0xbe, 0xc3, 0x25, 0x30, 0xbd, 0x20, 0x28,
+	0xa4, 0x4a, 0x19, 0xf8, 0x3e, 0xac, 0xbb, 0x74, 0x1e, 0x1f, 0xd1, 0xd3, 0x96, 0xf4, 0xa1, 0x9e,
+	0xc8, 0x60, 0xbe, 0x28, 0x37, 0x71, 0x0c, 0xed, 0xfd, 0xe8, 0xd0, 0x3b, 0xb7, 0x33, 0x86, 0xef,
+	0x40, 0x27, 0x4b, 0xb8, 0x3c, 0x2f, 0x69, 0xa4, 0x5e, 0x2f, 0xb9, 0xf8, 0xe6, 0x36, 0xfe, 0x43,
+	0x83, 0xc6, 0xe3, 0xad, 0x38, 0x8d, 0x38, 0x3d, 0xed, 0xe1, 0x77, 0x05, 0x2c, 0x45, 0x8d, 0x8c,
+	0x28, 0x99, 0x25, 0x08, 0x5d, 0x08, 0x53, 0x4e, 0xe8, 0x5c, 0x95, 0xd0, 0x3d, 0xb0, 0x7c, 0xb1,
+	0xa3, 0xd0, 0x2b, 0x51, 0xea, 0x65, 0x92, 0x67, 0x20, 0xf2, 0x6f, 0x06, 0x5d, 0xf6, 0x91, 0xf3,
+	0x29, 0xb4, 0x4a, 0xee, 0xf7, 0xbd, 0x3e, 0xf5, 0xec, 0xf5, 0xf9, 0xa8, 0xf6, 0x50, 0xc3, 0x4f,
+	0xa0, 0xf3, 0x38, 0x66, 0x2c, 0x5c, 0xe4, 0xe0, 0x22, 0x30, 0xa2, 0x5c, 0x92, 0x9b, 0xae, 0xfc,
+	0x8d, 0x6e, 0x42, 0xc3, 0x57, 0xe9, 0x05, 0xdd, 0x45, 0x41, 0xcd, 0xa2, 0x20, 0xb7, 0x08, 0xe1,
+	0x5d, 0xe8, 0xe6, 0x7b, 0x65, 0xb8, 0x7d, 0xf8, 0x66, 0x83, 0x2f, 0x4b, 0xaf, 0xf8, 0x67, 0x34,
+	0x39, 0x0a, 0x7d, 0x8a, 0x06, 0xd0, 0x2c, 0x7c, 0x68, 0x8d, 0xac, 0xbe, 0xf2, 0x1d, 0x74, 0xf2,
+	0x02, 0x19, 0x2c, 0x00, 0x6d, 0xc5, 0x91, 0x9f, 0x26, 0x09, 0x8d, 0xfc, 0xb7, 0xf9, 0x4e, 0x77,
+	0xa1, 0x9e, 0x3d, 0x50, 0x50, 0x8f, 0x54, 0x5f, 0x4d, 0x8e, 0x4d, 0x56, 0xdf, 0x2e, 0x77, 0xa1,
+	0x9e, 0x5d, 0xc7, 0xa8, 0x47, 0xaa, 0xcf, 0x04, 0xc7, 0x26, 0x2b, 0x37, 0xf5, 0xe0, 0x25, 0xb4,
+	0xbf, 0x4a, 0x63, 0xee, 0x95, 0x72, 0x65, 0xf7, 0x9e, 0x5c, 0x5d, 0xbe, 0xa6, 0x1d, 0x7b, 0xe9,
+	0xc8, 0x72, 0xdd, 0x06, 0x4b, 0x89, 0x35, 0xea, 0x92, 0xca, 0xd5, 0xe2, 0xf4, 0x48, 0x55, 0xc5,
+	0x07, 0xbf, 0xd7, 0xa0, 0x3d, 0x0c, 0xe6, 0x61, 0x94, 0x67, 0x7a, 0x08, 0xad, 0x92, 0xa2, 0xa1,
+	0x8b, 0xe4, 0xa4, 0x90, 0x3b, 0x97, 0xc8, 0x69, 0xa2, 0xf7, 0x04, 0xd6, 0x4e, 0xc8, 0x01, 0x5a,
+	0x27, 0x67, 0xc9, 0x87, 0xe3, 0x90, 0xb3, 0xd5, 0xe3, 0x01, 0x74, 0xab, 0xaa, 0x80, 0x2a, 0xf2,
+	0xe1, 0x5c, 0x25, 0x67, 0x88, 0xc6, 0x67, 0xb0, 0x76, 0x42, 0x1e, 0x56, 0xd6, 0x3a, 0xe4, 0x6c,
+	0x01, 0xb9, 0x05, 0xa6, 0x3c, 0xb5, 0xa8, 0x43, 0xca, 0x72, 0xe1, 0x74, 0x49, 0xe5, 0x30, 0x0f,
+	0x1e, 0x42, 0xf7, 0x29, 0x8d, 0xbc, 0x19, 0x2f, 0xd8, 0xf0, 0x6f, 0x57, 0x3e, 0xca, 0x0f, 0x4b,
+	0xbe, 0xf0, 0x36, 0x58, 0xca, 0x81, 0xba, 0xa4, 0x72, 0x8c, 0x9c, 0x1e, 0xa9, 0x1e, 0x85, 0x43,
+	0x4b, 0xfe, 0x73, 0xfa, 0xf1, 0xdf, 0x03, 0x00, 0x81, 0x54, 0x63, 0xcc, 0xab, 0x0e, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// RateLimitServiceClient is the client API for RateLimitService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type RateLimitServiceClient interface {
+	// Provides info about whether the rate limit should apply or not.
+	RateLimit(ctx context.Context, in *RateLimitRequest, opts ...grpc.CallOption) (*RateLimitResponse, error)
+}
+
+type rateLimitServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewRateLimitServiceClient(cc *grpc.ClientConn) RateLimitServiceClient {
+	return &rateLimitServiceClient{cc}
+}
+
+func (c *rateLimitServiceClient) RateLimit(ctx context.Context, in *RateLimitRequest, opts ...grpc.CallOption) (*RateLimitResponse, error) {
+	out := new(RateLimitResponse)
+	err := c.cc.Invoke(ctx, "/RateLimitService/RateLimit", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RateLimitServiceServer is the server API for RateLimitService service.
+type RateLimitServiceServer interface {
+	// Provides info about whether the rate limit should apply or not.
+	RateLimit(context.Context, *RateLimitRequest) (*RateLimitResponse, error)
+}
+
+func RegisterRateLimitServiceServer(s *grpc.Server, srv RateLimitServiceServer) {
+	s.RegisterService(&_RateLimitService_serviceDesc, srv)
+}
+
+func _RateLimitService_RateLimit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RateLimitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimitServiceServer).RateLimit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/RateLimitService/RateLimit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimitServiceServer).RateLimit(ctx, req.(*RateLimitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _RateLimitService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "RateLimitService",
+	HandlerType: (*RateLimitServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RateLimit",
+			Handler:    _RateLimitService_RateLimit_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "ratio.proto",
+}
+
+// ConcurrencyServiceClient is the client API for ConcurrencyService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type ConcurrencyServiceClient interface {
+	// Acquires a slot of the resource, so at most a number of hits of the
+	// owner run at once. The slot is held by a lease until released or expired.
+	Acquire(ctx context.Context, in *AcquireRequest, opts ...grpc.CallOption) (*AcquireResponse, error)
+	// Releases the slot held by a lease.
+	Release(ctx context.Context, in *ReleaseRequest, opts ...grpc.CallOption) (*ReleaseResponse, error)
+}
+
+type concurrencyServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewConcurrencyServiceClient(cc *grpc.ClientConn) ConcurrencyServiceClient {
+	return &concurrencyServiceClient{cc}
+}
+
+func (c *concurrencyServiceClient) Acquire(ctx context.Context, in *AcquireRequest, opts ...grpc.CallOption) (*AcquireResponse, error) {
+	out := new(AcquireResponse)
+	err := c.cc.Invoke(ctx, "/ConcurrencyService/Acquire", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *concurrencyServiceClient) Release(ctx context.Context, in *ReleaseRequest, opts ...grpc.CallOption) (*ReleaseResponse, error) {
+	out := new(ReleaseResponse)
+	err := c.cc.Invoke(ctx, "/ConcurrencyService/Release", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ConcurrencyServiceServer is the server API for ConcurrencyService service.
+type ConcurrencyServiceServer interface {
+	// Acquires a slot of the resource, so at most a number of hits of the
+	// owner run at once. The slot is held by a lease until released or expired.
+	Acquire(context.Context, *AcquireRequest) (*AcquireResponse, error)
+	// Releases the slot held by a lease.
+	Release(context.Context, *ReleaseRequest) (*ReleaseResponse, error)
+}
+
+func RegisterConcurrencyServiceServer(s *grpc.Server, srv ConcurrencyServiceServer) {
+	s.RegisterService(&_ConcurrencyService_serviceDesc, srv)
+}
+
+func _ConcurrencyService_Acquire_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AcquireRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConcurrencyServiceServer).Acquire(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ConcurrencyService/Acquire",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConcurrencyServiceServer).Acquire(ctx, req.(*AcquireRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConcurrencyService_Release_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReleaseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConcurrencyServiceServer).Release(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ConcurrencyService/Release",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConcurrencyServiceServer).Release(ctx, req.(*ReleaseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _ConcurrencyService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "ConcurrencyService",
+	HandlerType: (*ConcurrencyServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Acquire",
+			Handler:    _ConcurrencyService_Acquire_Handler,
+		},
+		{
+			MethodName: "Release",
+			Handler:    _ConcurrencyService_Release_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "ratio.proto",
+}
+
+// QuotaServiceClient is the client API for QuotaService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type QuotaServiceClient interface {
+	// Reserves a batch of permits at once (a quota lease), so the caller can
+	// consume them on its own instead of calling RateLimit on every hit.
+	// Reserved permits count as hits of the owner, resource and descriptors.
+	Reserve(ctx context.Context, in *ReserveRequest, opts ...grpc.CallOption) (*ReserveResponse, error)
+	// Gives the unused permits of a lease back.
+	Return(ctx context.Context, in *ReturnRequest, opts ...grpc.CallOption) (*ReturnResponse, error)
+}
+
+type quotaServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewQuotaServiceClient(cc *grpc.ClientConn) QuotaServiceClient {
+	return &quotaServiceClient{cc}
+}
+
+func (c *quotaServiceClient) Reserve(ctx context.Context, in *ReserveRequest, opts ...grpc.CallOption) (*ReserveResponse, error) {
+	out := new(ReserveResponse)
+	err := c.cc.Invoke(ctx, "/QuotaService/Reserve", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *quotaServiceClient) Return(ctx context.Context, in *ReturnRequest, opts ...grpc.CallOption) (*ReturnResponse, error) {
+	out := new(ReturnResponse)
+	err := c.cc.Invoke(ctx, "/QuotaService/Return", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// QuotaServiceServer is the server API for QuotaService service.
+type QuotaServiceServer interface {
+	// Reserves a batch of permits at once (a quota lease), so the caller can
+	// consume them on its own instead of calling RateLimit on every hit.
+	// Reserved permits count as hits of the owner, resource and descriptors.
+	Reserve(context.Context, *ReserveRequest) (*ReserveResponse, error)
+	// Gives the unused permits of a lease back.
+	Return(context.Context, *ReturnRequest) (*ReturnResponse, error)
+}
+
+func RegisterQuotaServiceServer(s *grpc.Server, srv QuotaServiceServer) {
+	s.RegisterService(&_QuotaService_serviceDesc, srv)
+}
+
+func _QuotaService_Reserve_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReserveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QuotaServiceServer).Reserve(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/QuotaService/Reserve",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QuotaServiceServer).Reserve(ctx, req.(*ReserveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _QuotaService_Return_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReturnRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QuotaServiceServer).Return(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/QuotaService/Return",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QuotaServiceServer).Return(ctx, req.(*ReturnRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _QuotaService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "QuotaService",
+	HandlerType: (*QuotaServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Reserve",
+			Handler:    _QuotaService_Reserve_Handler,
+		},
+		{
+			MethodName: "Return",
+			Handler:    _QuotaService_Return_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "ratio.proto",
+}
+
+// AdminServiceClient is the client API for AdminService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type AdminServiceClient interface {
+	// Exports the allowed and rejected hits of every owner and resource,
+	// aggregated by bucket of time, for reporting and billing.
+	ExportUsage(ctx context.Context, in *ExportUsageRequest, opts ...grpc.CallOption) (*ExportUsageResponse, error)
+	// Returns the entries of the allow and deny lists.
+	ListAccessEntries(ctx context.Context, in *ListAccessEntriesRequest, opts ...grpc.CallOption) (*ListAccessEntriesResponse, error)
+	// Adds an entry to the allow or deny list, saving it to the file of the
+	// lists. Fails with FAILED_PRECONDITION if the lists have no file.
+	AddAccessEntry(ctx context.Context, in *AccessEntry, opts ...grpc.CallOption) (*AddAccessEntryResponse, error)
+	// Removes an entry from the allow or deny list, saving it to the file of
+	// the lists. Fails with FAILED_PRECONDITION if the lists have no file.
+	RemoveAccessEntry(ctx context.Context, in *AccessEntry, opts ...grpc.CallOption) (*RemoveAccessEntryResponse, error)
+	// Lifts the ban of a key banned for exceeding its limits too often, and
+	// forgets its violations. In cluster mode, it is fanned out to every
+	// instance.
+	Unban(ctx context.Context, in *UnbanRequest, opts ...grpc.CallOption) (*UnbanResponse, error)
+}
+
+type adminServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewAdminServiceClient(cc *grpc.ClientConn) AdminServiceClient {
+	return &adminServiceClient{cc}
+}
+
+func (c *adminServiceClient) ExportUsage(ctx context.Context, in *ExportUsageRequest, opts ...grpc.CallOption) (*ExportUsageResponse, error) {
+	out := new(ExportUsageResponse)
+	err := c.cc.Invoke(ctx, "/AdminService/ExportUsage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) ListAccessEntries(ctx context.Context, in *ListAccessEntriesRequest, opts ...grpc.CallOption) (*ListAccessEntriesResponse, error) {
+	out := new(ListAccessEntriesResponse)
+	err := c.cc.Invoke(ctx, "/AdminService/ListAccessEntries", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) AddAccessEntry(ctx context.Context, in *AccessEntry, opts ...grpc.CallOption) (*AddAccessEntryResponse, error) {
+	out := new(AddAccessEntryResponse)
+	err := c.cc.Invoke(ctx, "/AdminService/AddAccessEntry", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) RemoveAccessEntry(ctx context.Context, in *AccessEntry, opts ...grpc.CallOption) (*RemoveAccessEntryResponse, error) {
+	out := new(RemoveAccessEntryResponse)
+	err := c.cc.Invoke(ctx, "/AdminService/RemoveAccessEntry", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) Unban(ctx context.Context, in *UnbanRequest, opts ...grpc.CallOption) (*UnbanResponse, error) {
+	out := new(UnbanResponse)
+	err := c.cc.Invoke(ctx, "/AdminService/Unban", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminServiceServer is the server API for AdminService service.
+type AdminServiceServer interface {
+	// Exports the allowed and rejected hits of every owner and resource,
+	// aggregated by bucket of time, for reporting and billing.
+	ExportUsage(context.Context, *ExportUsageRequest) (*ExportUsageResponse, error)
+	// Returns the entries of the allow and deny lists.
+	ListAccessEntries(context.Context, *ListAccessEntriesRequest) (*ListAccessEntriesResponse, error)
+	// Adds an entry to the allow or deny list, saving it to the file of the
+	// lists. Fails with FAILED_PRECONDITION if the lists have no file.
+	AddAccessEntry(context.Context, *AccessEntry) (*AddAccessEntryResponse, error)
+	// Removes an entry from the allow or deny list, saving it to the file of
+	// the lists. Fails with FAILED_PRECONDITION if the lists have no file.
+	RemoveAccessEntry(context.Context, *AccessEntry) (*RemoveAccessEntryResponse, error)
+	// Lifts the ban of a key banned for exceeding its limits too often, and
+	// forgets its violations. In cluster mode, it is fanned out to every
+	// instance.
+	Unban(context.Context, *UnbanRequest) (*UnbanResponse, error)
+}
+
+func RegisterAdminServiceServer(s *grpc.Server, srv AdminServiceServer) {
+	s.RegisterService(&_AdminService_serviceDesc, srv)
+}
+
+func _AdminService_ExportUsage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExportUsageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).ExportUsage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/AdminService/ExportUsage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).ExportUsage(ctx, req.(*ExportUsageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_ListAccessEntries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAccessEntriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).ListAccessEntries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/AdminService/ListAccessEntries",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).ListAccessEntries(ctx, req.(*ListAccessEntriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_AddAccessEntry_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AccessEntry)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).AddAccessEntry(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/AdminService/AddAccessEntry",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).AddAccessEntry(ctx, req.(*AccessEntry))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_RemoveAccessEntry_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AccessEntry)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).RemoveAccessEntry(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/AdminService/RemoveAccessEntry",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).RemoveAccessEntry(ctx, req.(*AccessEntry))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_Unban_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnbanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).Unban(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/AdminService/Unban",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).Unban(ctx, req.(*UnbanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _AdminService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "AdminService",
+	HandlerType: (*AdminServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ExportUsage",
+			Handler:    _AdminService_ExportUsage_Handler,
+		},
+		{
+			MethodName: "ListAccessEntries",
+			Handler:    _AdminService_ListAccessEntries_Handler,
+		},
+		{
+			MethodName: "AddAccessEntry",
+			Handler:    _AdminService_AddAccessEntry_Handler,
+		},
+		{
+			MethodName: "RemoveAccessEntry",
+			Handler:    _AdminService_RemoveAccessEntry_Handler,
+		},
+		{
+			MethodName: "Unban",
+			Handler:    _AdminService_Unban_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "ratio.proto",
+}
+
+// PenaltyServiceClient is the client API for PenaltyService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type PenaltyServiceClient interface {
+	Unban(ctx context.Context, in *UnbanRequest, opts ...grpc.CallOption) (*UnbanResponse, error)
+}
+
+type penaltyServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewPenaltyServiceClient(cc *grpc.ClientConn) PenaltyServiceClient {
+	return &penaltyServiceClient{cc}
+}
+
+func (c *penaltyServiceClient) Unban(ctx context.Context, in *UnbanRequest, opts ...grpc.CallOption) (*UnbanResponse, error) {
+	out := new(UnbanResponse)
+	err := c.cc.Invoke(ctx, "/PenaltyService/Unban", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PenaltyServiceServer is the server API for PenaltyService service.
+type PenaltyServiceServer interface {
+	Unban(context.Context, *UnbanRequest) (*UnbanResponse, error)
+}
+
+func RegisterPenaltyServiceServer(s *grpc.Server, srv PenaltyServiceServer) {
+	s.RegisterService(&_PenaltyService_serviceDesc, srv)
+}
+
+func _PenaltyService_Unban_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnbanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PenaltyServiceServer).Unban(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/PenaltyService/Unban",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PenaltyServiceServer).Unban(ctx, req.(*UnbanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _PenaltyService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "PenaltyService",
+	HandlerType: (*PenaltyServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Unban",
+			Handler:    _PenaltyService_Unban_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "ratio.proto",
+}
+
+// GossipServiceClient is the client API for GossipService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type GossipServiceClient interface {
+	// Exchanges the G-Counters of the caller with the ones of the callee (push-pull). Used between ratio instances.
+	Gossip(ctx context.Context, in *GossipRequest, opts ...grpc.CallOption) (*GossipResponse, error)
+}
+
+type gossipServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewGossipServiceClient(cc *grpc.ClientConn) GossipServiceClient {
+	return &gossipServiceClient{cc}
+}
+
+func (c *gossipServiceClient) Gossip(ctx context.Context, in *GossipRequest, opts ...grpc.CallOption) (*GossipResponse, error) {
+	out := new(GossipResponse)
+	err := c.cc.Invoke(ctx, "/GossipService/Gossip", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GossipServiceServer is the server API for GossipService service.
+type GossipServiceServer interface {
+	// Exchanges the G-Counters of the caller with the ones of the callee (push-pull). Used between ratio instances.
+	Gossip(context.Context, *GossipRequest) (*GossipResponse, error)
+}
+
+func RegisterGossipServiceServer(s *grpc.Server, srv GossipServiceServer) {
+	s.RegisterService(&_GossipService_serviceDesc, srv)
+}
+
+func _GossipService_Gossip_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GossipRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GossipServiceServer).Gossip(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/GossipService/Gossip",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GossipServiceServer).Gossip(ctx, req.(*GossipRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _GossipService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "GossipService",
+	HandlerType: (*GossipServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Gossip",
+			Handler:    _GossipService_Gossip_Handler,
 		},
 	},
 	Streams:  []grpc.StreamDesc{},
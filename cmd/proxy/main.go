@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+
+	"github.com/smoya/ratio/internal/proxy"
+	"github.com/smoya/ratio/pkg/rate"
+)
+
+type config struct {
+	Port            int           `default:"8080" help:"HTTP Port"`
+	Routes          string        `required:"true" help:"Path to the routes config (JSON)"`
+	ShutdownTimeout time.Duration `default:"10s" help:"Max time to wait for in-flight requests on shutdown" split_words:"true"`
+	Storage         string        `default:"redis://redis:6379/0" help:"DSN Storage. Example: inmemory://"`
+	Limit           string        `default:"100/m" help:"Limits, separated by \";\", for the routes not defining their own"`
+}
+
+func main() {
+	var c config
+	err := envconfig.Process("ratio", &c)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	routes, err := proxy.LoadConfig(c.Routes)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	limits, err := rate.ParseLimits(c.Limit)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	storage, err := rate.NewSlideWindowStorageFromDSN(c.Storage)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	handler, err := proxy.New(routes, limits, rate.SlideWindowRateLimiter(storage))
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	srv := &http.Server{
+		Addr:    fmt.Sprintf(":%d", c.Port),
+		Handler: handler,
+	}
+
+	done := make(chan struct{})
+	go func() {
+		sig := make(chan os.Signal, 2)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
+		<-sig
+		log.Println("Shutting down ratio proxy...")
+
+		ctx, cancel := context.WithTimeout(context.Background(), c.ShutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Printf("in-flight requests did not finish in %s: %s\n", c.ShutdownTimeout, err.Error())
+		}
+
+		if err := storage.Close(); err != nil {
+			log.Printf("error closing: %s\n", err.Error())
+		}
+		close(done)
+	}()
+
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatalf("failed to serve: %v", err)
+	}
+
+	<-done
+}
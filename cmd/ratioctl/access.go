@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	ratio "github.com/smoya/ratio/api/proto"
+)
+
+const accessHelp = `Usage: ratioctl access <action> [flags]
+
+Actions:
+  list      List the entries of the allow and deny lists as CSV or JSON
+  allow     Add an entry to the allow list
+  deny      Add an entry to the deny list
+  remove    Remove an entry from the allow or deny list
+
+Run ratioctl access <action> -h for the flags of each action.
+`
+
+// accessRecord is a listed entry of the access lists.
+type accessRecord struct {
+	List     string `json:"list"`
+	Owner    string `json:"owner"`
+	Resource string `json:"resource"`
+}
+
+func runAccess(args []string, w io.Writer) error {
+	if len(args) < 1 {
+		return fmt.Errorf("missing action\n\n%s", accessHelp)
+	}
+
+	var (
+		conn                    connFlags
+		owner, resource, format string
+		list                    string
+		timeout                 time.Duration
+	)
+	action := args[0]
+	fs := flag.NewFlagSet("access "+action, flag.ContinueOnError)
+	conn.register(fs)
+	fs.DurationVar(&timeout, "timeout", 10*time.Second, "Timeout of the call")
+	switch action {
+	case "list":
+		fs.StringVar(&format, "format", "csv", "Output format: csv or json")
+	case "allow", "deny", "remove":
+		fs.StringVar(&owner, "owner", "", "Owner pattern or CIDR. Any owner if empty")
+		fs.StringVar(&resource, "resource", "", "Resource pattern. Any resource if empty")
+		if action == "remove" {
+			fs.StringVar(&list, "list", "", "List to remove the entry from: allow or deny")
+		}
+	default:
+		return fmt.Errorf("unknown action %s\n\n%s", action, accessHelp)
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if action == "list" && format != "csv" && format != "json" {
+		return fmt.Errorf("%s is not a valid format", format)
+	}
+
+	entry := &ratio.AccessEntry{Owner: owner, Resource: resource}
+	if action != "list" {
+		if action != "remove" {
+			list = action
+		}
+
+		l, ok := ratio.AccessEntry_List_value[strings.ToUpper(list)]
+		if !ok {
+			return fmt.Errorf("%s is not a valid list: use allow or deny", list)
+		}
+		entry.List = ratio.AccessEntry_List(l)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cc, ctx, err := conn.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer cc.Close()
+
+	admin := ratio.NewAdminServiceClient(cc)
+	switch action {
+	case "list":
+		resp, err := admin.ListAccessEntries(ctx, &ratio.ListAccessEntriesRequest{})
+		if err != nil {
+			return err
+		}
+
+		return writeAccessEntries(w, format, resp.Entries)
+	case "remove":
+		resp, err := admin.RemoveAccessEntry(ctx, entry)
+		if err != nil {
+			return err
+		}
+
+		if !resp.Removed {
+			return fmt.Errorf("entry not found in the %s list", list)
+		}
+	default:
+		resp, err := admin.AddAccessEntry(ctx, entry)
+		if err != nil {
+			return err
+		}
+
+		if !resp.Added {
+			fmt.Fprintf(w, "entry already in the %s list\n", list)
+		}
+	}
+
+	return nil
+}
+
+func writeAccessEntries(w io.Writer, format string, entries []*ratio.AccessEntry) error {
+	records := make([]accessRecord, 0, len(entries))
+	for _, e := range entries {
+		records = append(records, accessRecord{
+			List:     strings.ToLower(e.List.String()),
+			Owner:    e.Owner,
+			Resource: e.Resource,
+		})
+	}
+
+	if format == "json" {
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(records)
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"list", "owner", "resource"}); err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		if err := cw.Write([]string{r.List, r.Owner, r.Resource}); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/smoya/ratio/internal/access"
+	"github.com/smoya/ratio/internal/server"
+
+	ratio "github.com/smoya/ratio/api/proto"
+)
+
+func TestRunAccess(t *testing.T) {
+	dir, err := ioutil.TempDir("", "access")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := access.Config{Allow: []access.Entry{{Owner: "health-checker"}}}
+	file := filepath.Join(dir, "access.json")
+	require.NoError(t, access.SaveConfig(file, c))
+	lists, err := access.NewLists(c)
+	require.NoError(t, err)
+	lists.Persist(file)
+
+	srv := grpc.NewServer()
+	ratio.RegisterAdminServiceServer(srv, server.NewAdminGRPC(nil, lists, nil))
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = srv.Serve(l) }()
+	defer srv.Stop()
+
+	addr := l.Addr().String()
+	var out bytes.Buffer
+	require.NoError(t, runAccess([]string{"deny", "-addr", addr, "-owner", "203.0.113.0/24", "-resource", "/v1/*"}, &out))
+	assert.Equal(t, access.Denied, lists.Check("203.0.113.7", "/v1/search"))
+
+	require.NoError(t, runAccess([]string{"deny", "-addr", addr, "-owner", "203.0.113.0/24", "-resource", "/v1/*"}, &out))
+	assert.Equal(t, "entry already in the deny list\n", out.String())
+
+	out.Reset()
+	require.NoError(t, runAccess([]string{"list", "-addr", addr}, &out))
+	assert.Equal(t, `list,owner,resource
+allow,health-checker,
+deny,203.0.113.0/24,/v1/*
+`, out.String())
+
+	out.Reset()
+	require.NoError(t, runAccess([]string{"list", "-addr", addr, "-format", "json"}, &out))
+	assert.JSONEq(t, `[
+		{"list": "allow", "owner": "health-checker", "resource": ""},
+		{"list": "deny", "owner": "203.0.113.0/24", "resource": "/v1/*"}
+	]`, out.String())
+
+	require.NoError(t, runAccess([]string{"remove", "-addr", addr, "-list", "allow", "-owner", "health-checker"}, &out))
+	assert.Equal(t, access.None, lists.Check("health-checker", "/"))
+
+	assert.Error(t, runAccess([]string{"remove", "-addr", addr, "-list", "allow", "-owner", "health-checker"}, &out))
+	assert.Error(t, runAccess([]string{"remove", "-addr", addr, "-list", "block", "-owner", "scraper"}, &out))
+	assert.Error(t, runAccess([]string{"allow", "-addr", addr}, &out), "entries without owner nor resource are rejected")
+	assert.Error(t, runAccess([]string{"ban", "-addr", addr}, &out))
+	assert.Error(t, runAccess(nil, &out))
+}
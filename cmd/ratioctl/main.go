@@ -0,0 +1,88 @@
+// Command ratioctl administers a ratio server through its AdminService.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+const help = `Usage: ratioctl <command> [flags]
+
+Commands:
+  usage    Export the usage of the owners and resources as CSV or JSON
+  access   Manage the lists of owners and resources always allowed or denied
+  unban    Lift the ban of a key banned for exceeding its limits too often
+
+Run ratioctl <command> -h for the flags of each command.
+`
+
+func main() {
+	log.SetFlags(0)
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, help)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "usage":
+		err = runUsage(os.Args[2:], os.Stdout)
+	case "access":
+		err = runAccess(os.Args[2:], os.Stdout)
+	case "unban":
+		err = runUnban(os.Args[2:], os.Stdout)
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(os.Stdout, help)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %s\n\n%s", os.Args[1], help)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+}
+
+// connFlags are the flags for connecting to ratio, common to every command.
+type connFlags struct {
+	addr   string
+	apiKey string
+	caFile string
+}
+
+func (c *connFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.addr, "addr", "localhost:50051", "Address of the ratio server")
+	fs.StringVar(&c.apiKey, "api-key", "", "API key sent in the x-api-key metadata")
+	fs.StringVar(&c.caFile, "tls-ca", "", "CA certificate for connecting with TLS. Insecure if empty")
+}
+
+// dial connects to ratio, returning a context carrying the credentials.
+func (c *connFlags) dial(ctx context.Context) (*grpc.ClientConn, context.Context, error) {
+	creds := grpc.WithInsecure()
+	if c.caFile != "" {
+		tls, err := credentials.NewClientTLSFromFile(c.caFile, "")
+		if err != nil {
+			return nil, ctx, err
+		}
+		creds = grpc.WithTransportCredentials(tls)
+	}
+
+	conn, err := grpc.Dial(c.addr, creds)
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	if c.apiKey != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-api-key", c.apiKey)
+	}
+
+	return conn, ctx, nil
+}
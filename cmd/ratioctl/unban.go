@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	ratio "github.com/smoya/ratio/api/proto"
+)
+
+// descriptorsFlag collects the descriptors given as repeated key=value flags.
+type descriptorsFlag []*ratio.Descriptor
+
+func (d *descriptorsFlag) String() string {
+	parts := make([]string, 0, len(*d))
+	for _, desc := range *d {
+		parts = append(parts, desc.Key+"="+desc.Value)
+	}
+
+	return strings.Join(parts, ",")
+}
+
+func (d *descriptorsFlag) Set(s string) error {
+	i := strings.Index(s, "=")
+	if i < 1 {
+		return fmt.Errorf("%s is not a valid descriptor: use key=value", s)
+	}
+
+	*d = append(*d, &ratio.Descriptor{Key: s[:i], Value: s[i+1:]})
+	return nil
+}
+
+func runUnban(args []string, w io.Writer) error {
+	var (
+		conn            connFlags
+		owner, resource string
+		descriptors     descriptorsFlag
+		timeout         time.Duration
+	)
+	fs := flag.NewFlagSet("unban", flag.ContinueOnError)
+	conn.register(fs)
+	fs.StringVar(&owner, "owner", "", "Owner of the banned key")
+	fs.StringVar(&resource, "resource", "", "Resource of the banned key")
+	fs.Var(&descriptors, "descriptor", "Descriptor of the banned key, as key=value. Repeat it for several, in order")
+	fs.DurationVar(&timeout, "timeout", 10*time.Second, "Timeout of the call")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if owner == "" && resource == "" {
+		return errors.New("-owner or -resource is required")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cc, ctx, err := conn.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer cc.Close()
+
+	resp, err := ratio.NewAdminServiceClient(cc).Unban(ctx, &ratio.UnbanRequest{
+		Owner:       owner,
+		Resource:    resource,
+		Descriptors: descriptors,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !resp.Unbanned {
+		fmt.Fprintln(w, "key not banned")
+	}
+
+	return nil
+}
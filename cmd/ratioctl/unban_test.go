@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/smoya/ratio/internal/server"
+	"github.com/smoya/ratio/pkg/rate"
+
+	ratio "github.com/smoya/ratio/api/proto"
+)
+
+func TestRunUnban(t *testing.T) {
+	storage := rate.NewInMemorySlideWindowStorage(make(map[string][]time.Time))
+	penalty, err := rate.NewPenaltyBox(storage.(rate.PenaltyStorage), rate.PenaltyPolicy{Violations: 1, Window: time.Minute, Ban: time.Hour})
+	require.NoError(t, err)
+
+	limiter := rate.PenaltyRateLimiter(rate.SlideWindowRateLimiter(storage), penalty)
+	descriptors := []rate.Descriptor{{Key: "customer", Value: "c=1"}, {Key: "plan", Value: "free"}}
+	for i := 0; i < 2; i++ {
+		_, err := limiter(rate.Limits{rate.NewLimit(rate.PerMinute, 1)}, "checkout", "/pay", descriptors...)
+		require.NoError(t, err)
+	}
+
+	srv := grpc.NewServer()
+	ratio.RegisterAdminServiceServer(srv, server.NewAdminGRPC(nil, nil, penalty))
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = srv.Serve(l) }()
+	defer srv.Stop()
+
+	addr := l.Addr().String()
+	var out bytes.Buffer
+	require.NoError(t, runUnban([]string{"-addr", addr, "-owner", "checkout", "-resource", "/pay", "-descriptor", "customer=c=1", "-descriptor", "plan=free"}, &out))
+	assert.Empty(t, out.String())
+
+	until, err := penalty.BannedUntil("checkout", "/pay", descriptors...)
+	require.NoError(t, err)
+	assert.True(t, until.IsZero())
+
+	require.NoError(t, runUnban([]string{"-addr", addr, "-owner", "checkout", "-resource", "/pay"}, &out))
+	assert.Equal(t, "key not banned\n", out.String())
+
+	assert.Error(t, runUnban([]string{"-addr", addr, "-descriptor", "plan"}, &out))
+	assert.Error(t, runUnban([]string{"-addr", addr}, &out))
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	ratio "github.com/smoya/ratio/api/proto"
+)
+
+// usageRecord is an exported row of usage.
+type usageRecord struct {
+	BucketStart time.Time `json:"bucket_start"`
+	BucketEnd   time.Time `json:"bucket_end"`
+	Owner       string    `json:"owner"`
+	Resource    string    `json:"resource"`
+	Allowed     uint64    `json:"allowed"`
+	Rejected    uint64    `json:"rejected"`
+}
+
+func runUsage(args []string, w io.Writer) error {
+	var (
+		conn                    connFlags
+		from, to, owner, format string
+		timeout                 time.Duration
+	)
+	fs := flag.NewFlagSet("usage", flag.ContinueOnError)
+	conn.register(fs)
+	fs.StringVar(&from, "from", time.Now().UTC().AddDate(0, 0, -1).Format(time.RFC3339), "Start of the time range (RFC 3339 or YYYY-MM-DD)")
+	fs.StringVar(&to, "to", "now", "End of the time range (RFC 3339, YYYY-MM-DD or now)")
+	fs.StringVar(&owner, "owner", "", "Export the usage of a single owner. Every owner if empty")
+	fs.StringVar(&format, "format", "csv", "Output format: csv or json")
+	fs.DurationVar(&timeout, "timeout", 30*time.Second, "Timeout of the export")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if format != "csv" && format != "json" {
+		return fmt.Errorf("%s is not a valid format", format)
+	}
+
+	fromTime, err := parseTime(from)
+	if err != nil {
+		return err
+	}
+	toTime, err := parseTime(to)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cc, ctx, err := conn.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer cc.Close()
+
+	resp, err := ratio.NewAdminServiceClient(cc).ExportUsage(ctx, &ratio.ExportUsageRequest{
+		FromMs: toMilliseconds(fromTime),
+		ToMs:   toMilliseconds(toTime),
+		Owner:  owner,
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeUsage(w, format, resp)
+}
+
+// parseTime parses a time in RFC 3339 or YYYY-MM-DD (UTC) formats, or "now".
+func parseTime(s string) (time.Time, error) {
+	if s == "now" {
+		return time.Now(), nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s is not a valid time: use RFC 3339, YYYY-MM-DD or now", s)
+	}
+
+	return t, nil
+}
+
+func writeUsage(w io.Writer, format string, resp *ratio.ExportUsageResponse) error {
+	records := make([]usageRecord, 0, len(resp.Usage))
+	for _, u := range resp.Usage {
+		records = append(records, usageRecord{
+			BucketStart: fromMilliseconds(u.BucketMs),
+			BucketEnd:   fromMilliseconds(u.BucketMs + resp.BucketSizeMs),
+			Owner:       u.Owner,
+			Resource:    u.Resource,
+			Allowed:     u.Allowed,
+			Rejected:    u.Rejected,
+		})
+	}
+
+	if format == "json" {
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(records)
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"bucket_start", "bucket_end", "owner", "resource", "allowed", "rejected"}); err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		if err := cw.Write([]string{
+			r.BucketStart.Format(time.RFC3339),
+			r.BucketEnd.Format(time.RFC3339),
+			r.Owner,
+			r.Resource,
+			strconv.FormatUint(r.Allowed, 10),
+			strconv.FormatUint(r.Rejected, 10),
+		}); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func toMilliseconds(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func fromMilliseconds(ms int64) time.Time {
+	return time.Unix(0, ms*int64(time.Millisecond)).UTC()
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/smoya/ratio/internal/server"
+	"github.com/smoya/ratio/pkg/rate"
+
+	ratio "github.com/smoya/ratio/api/proto"
+)
+
+func TestRunUsage(t *testing.T) {
+	storage := rate.NewInMemorySlideWindowStorage(make(map[string][]time.Time))
+	usage := rate.NewUsageAccountant(storage.(rate.UsageStorage), rate.UsageOptions{Bucket: time.Hour})
+	defer usage.Close()
+
+	bucket := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	usage.Record("checkout", "/v1/order,pay", true, bucket.Add(time.Minute))
+	usage.Record("checkout", "/v1/order,pay", false, bucket.Add(time.Minute))
+	usage.Record("search", "/q", true, bucket.Add(time.Hour))
+
+	srv := grpc.NewServer()
+	ratio.RegisterAdminServiceServer(srv, server.NewAdminGRPC(usage, nil, nil))
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = srv.Serve(l) }()
+	defer srv.Stop()
+
+	var out bytes.Buffer
+	err = runUsage([]string{"-addr", l.Addr().String(), "-from", "2026-10-19", "-to", "2026-10-20"}, &out)
+	require.NoError(t, err)
+	assert.Equal(t, `bucket_start,bucket_end,owner,resource,allowed,rejected
+2026-10-19T10:00:00Z,2026-10-19T11:00:00Z,checkout,"/v1/order,pay",1,1
+2026-10-19T11:00:00Z,2026-10-19T12:00:00Z,search,/q,1,0
+`, out.String())
+
+	out.Reset()
+	err = runUsage([]string{"-addr", l.Addr().String(), "-from", "2026-10-19T11:00:00Z", "-to", "2026-10-20", "-owner", "search", "-format", "json"}, &out)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{
+		"bucket_start": "2026-10-19T11:00:00Z",
+		"bucket_end": "2026-10-19T12:00:00Z",
+		"owner": "search",
+		"resource": "/q",
+		"allowed": 1,
+		"rejected": 0
+	}]`, out.String())
+
+	assert.Error(t, runUsage([]string{"-addr", l.Addr().String(), "-format", "xml"}, &out))
+	assert.Error(t, runUsage([]string{"-addr", l.Addr().String(), "-from", "yesterday"}, &out))
+}
+
+func TestParseTime(t *testing.T) {
+	ts, err := parseTime("2026-10-19")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), ts)
+
+	ts, err = parseTime("2026-10-19T10:00:00+02:00")
+	assert.NoError(t, err)
+	assert.True(t, time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC).Equal(ts))
+
+	ts, err = parseTime("now")
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), ts, time.Second)
+
+	_, err = parseTime("19/10/2026")
+	assert.Error(t, err)
+}
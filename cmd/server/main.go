@@ -4,6 +4,7 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"os"
@@ -12,12 +13,20 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/smoya/ratio/pkg/cluster"
 	"github.com/smoya/ratio/pkg/rate"
 
 	"github.com/kelseyhightower/envconfig"
 
+	"github.com/smoya/ratio/internal/access"
+	"github.com/smoya/ratio/internal/alert"
+	"github.com/smoya/ratio/internal/audit"
+	"github.com/smoya/ratio/internal/auth"
 	"github.com/smoya/ratio/internal/server"
 
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 
 	ratio "github.com/smoya/ratio/api/proto"
@@ -27,8 +36,108 @@ import (
 type config struct {
 	Port              int           `default:"50051" help:"GRPC Port"`
 	ConnectionTimeout time.Duration `default:"1s" help:"Timeout for all incoming connections" split_words:"true"`
+	ShutdownTimeout   time.Duration `default:"10s" help:"Max time to wait for in-flight requests on shutdown" split_words:"true"`
+	HealthInterval    time.Duration `default:"5s" help:"Interval for checking the storage health" split_words:"true"`
+	AuthConfig        string        `help:"Path to the authentication and authorization config (JSON)" split_words:"true"`
 	Storage           string        `default:"redis://redis:6379/0" help:"DSN Storage. Example: inmemory://"`
-	Limit             string        `default:"100/m"`
+	Limit             string        `default:"100/m" help:"Limits separated by \";\". Example: 10/s;1000/h"`
+	Rules             string        `help:"Path to the rules assigning limits to owners, resources and descriptors (JSON)"`
+	AccessLists       string        `help:"Path to the lists of owners and resources always allowed or denied (JSON). Reloaded on SIGHUP and when changed. Changes of the AdminService are saved to it" split_words:"true"`
+	AccessListsReload time.Duration `default:"10s" help:"Interval for checking whether the access lists file changed, e.g. by the AdminService of other instances sharing it. 0 disables it" split_words:"true"`
+	Hierarchy         hierarchyConfig
+	Period            periodConfig
+	Penalty           penaltyConfig
+	Usage             usageConfig
+	Audit             auditConfig
+	Alert             alertConfig
+	Concurrency       concurrencyConfig
+	Quota             quotaConfig
+	Cluster           clusterConfig
+	Replication       replicationConfig
+	Async             asyncConfig
+	TLS               tlsConfig
+}
+
+type periodConfig struct {
+	Limit    string `help:"Calendar period limits separated by \";\". Example: 50000/day;1000000/month@15"`
+	Timezone string `default:"UTC" help:"Time zone the calendar periods are aligned to"`
+}
+
+type penaltyConfig struct {
+	Violations int           `help:"OVER_LIMIT decisions of a key in a window that ban it. Enables the penalty box"`
+	Window     time.Duration `default:"1m" help:"Fixed window of time the violations are counted in"`
+	Ban        time.Duration `default:"10m" help:"How long a key stays banned"`
+}
+
+type alertConfig struct {
+	Webhook               string        `help:"URL notified of the alerts of the thresholds without their own webhook"`
+	Percent               int           `help:"Percent of the limit reached that is notified. Applies to the hits not matching any rule with alerts"`
+	ConsecutiveRejections int           `help:"Hits rejected in a row that are notified. Applies to the hits not matching any rule with alerts" split_words:"true"`
+	Debounce              time.Duration `default:"1h" help:"Min time between two notifications of the same threshold and owner"`
+}
+
+type auditConfig struct {
+	Outputs      []string `help:"Outputs the decisions are emitted to: stdout, file:///path or http(s)://webhook"`
+	OKSampleRate float64  `default:"0" help:"Ratio of OK decisions emitted, between 0 and 1" envconfig:"OK_SAMPLE_RATE"`
+	QueueSize    int      `default:"10000" help:"Max decisions pending to be emitted per output" split_words:"true"`
+}
+
+type usageConfig struct {
+	Enabled       bool          `help:"Account the allowed and rejected hits of every owner and resource, for exporting them"`
+	Bucket        time.Duration `default:"1h" help:"Size of the buckets of time the hits are accounted into"`
+	Retention     time.Duration `default:"2160h" help:"Time the accounted hits are kept for"`
+	FlushInterval time.Duration `default:"10s" help:"Interval the accounted hits are written to the storage at" split_words:"true"`
+}
+
+type quotaConfig struct {
+	LeaseTTL time.Duration `default:"10s" help:"Default time reserved permits can be consumed" envconfig:"LEASE_TTL"`
+}
+
+type concurrencyConfig struct {
+	Limit       int           `help:"Max hits of an owner on a resource running at once. Enables the ConcurrencyService"`
+	LeaseTTL    time.Duration `default:"30s" help:"Default time a slot is held if not released" envconfig:"LEASE_TTL"`
+	MaxLeaseTTL time.Duration `default:"10m" help:"Max time a caller can ask a slot to be held" envconfig:"MAX_LEASE_TTL"`
+}
+
+type hierarchyConfig struct {
+	Global   string `help:"Limits of all the hits"`
+	Owner    string `help:"Limits of each owner"`
+	Resource string `help:"Limits of each owner and resource, without its sub-part after #"`
+}
+
+type tlsConfig struct {
+	Cert           string        `help:"Path to the server certificate (PEM). Enables TLS"`
+	Key            string        `help:"Path to the server private key (PEM)"`
+	ClientCA       string        `help:"Path to the CA (PEM) verifying client certificates. Enables mutual TLS" split_words:"true"`
+	ReloadInterval time.Duration `default:"1m" help:"Interval for reloading rotated certificates" split_words:"true"`
+	OwnerFromCert  bool          `help:"Use the client certificate identity as owner. Requires mutual TLS" split_words:"true"`
+}
+
+type asyncConfig struct {
+	Enabled   bool   `default:"true" help:"Add hits asynchronously, without making the caller wait"`
+	QueueSize int    `default:"10000" help:"Max hits pending to be added" split_words:"true"`
+	Workers   int    `default:"4" help:"Number of workers adding hits"`
+	BatchSize int    `default:"100" help:"Max hits added at once by a worker" split_words:"true"`
+	Overflow  string `default:"drop" help:"What to do with hits when the queue is full: block or drop"`
+}
+
+type replicationConfig struct {
+	Storages     []string      `help:"DSN Storages of the remote regions the hits are replicated to"`
+	QueueSize    int           `default:"10000" help:"Max hits pending to be replicated per remote region" split_words:"true"`
+	MaxStaleness time.Duration `default:"10s" help:"Max time a hit can wait to be replicated" split_words:"true"`
+}
+
+type clusterConfig struct {
+	Peers           []string      `help:"Static list of peers (host:port). Enables cluster mode"`
+	DNSSRV          string        `envconfig:"DNS_SRV" help:"DNS SRV record for discovering peers. Enables cluster mode"`
+	Advertise       string        `help:"Address (host:port) the peers know this instance by"`
+	Secret          string        `help:"Secret shared by the peers for authenticating each other. Required in cluster mode"`
+	RefreshInterval time.Duration `default:"10s" help:"Interval for refreshing the peers" split_words:"true"`
+	GossipInterval  time.Duration `default:"1s" help:"Interval for gossiping counters with the gcounter storage" split_words:"true"`
+}
+
+func (c clusterConfig) enabled() bool {
+	return len(c.Peers) > 0 || c.DNSSRV != ""
 }
 
 func main() {
@@ -42,43 +151,352 @@ func main() {
 	if err != nil {
 		log.Fatalf("failed to listen: %v", err)
 	}
-	s := grpc.NewServer(grpc.ConnectionTimeout(c.ConnectionTimeout))
+
+	// closers are closed in order on shutdown, once the server is stopped.
+	var closers []io.Closer
+
+	opts := []grpc.ServerOption{grpc.ConnectionTimeout(c.ConnectionTimeout)}
+	var interceptors []grpc.UnaryServerInterceptor
+	if c.Cluster.enabled() {
+		if c.Cluster.Secret == "" {
+			log.Fatal("RATIO_CLUSTER_SECRET is required in cluster mode")
+		}
+
+		// Goes first, so the requests of the peers are trusted by the following interceptors.
+		interceptors = append(interceptors, server.ClusterSecretInterceptor(c.Cluster.Secret, "/GossipService/", "/PenaltyService/"))
+	}
+
+	peerCreds := grpc.WithInsecure()
+	if c.TLS.Cert != "" {
+		certs, err := server.NewCertReloader(c.TLS.Cert, c.TLS.Key, c.TLS.ClientCA)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		certs.Start(c.TLS.ReloadInterval)
+		closers = append(closers, certs)
+
+		opts = append(opts, grpc.Creds(credentials.NewTLS(certs.ServerConfig())))
+		peerCreds = grpc.WithTransportCredentials(credentials.NewTLS(certs.ClientConfig()))
+
+		if c.TLS.OwnerFromCert {
+			if c.TLS.ClientCA == "" {
+				log.Fatal("RATIO_TLS_CLIENT_CA is required for deriving the owner from the client certificate")
+			}
+
+			// Goes before the authorization, so the owner authorized is the one of the certificate.
+			interceptors = append(interceptors, server.OwnerFromPeerIdentityInterceptor())
+		}
+	}
+
+	if c.AuthConfig != "" {
+		authConfig, err := auth.LoadConfig(c.AuthConfig)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		authenticator, err := authConfig.Authenticator()
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		// Health checks are not authenticated.
+		interceptors = append(interceptors, auth.UnaryServerInterceptor(
+			authenticator,
+			authConfig.Policy,
+			authConfig.Admins,
+			"/grpc.health.v1.Health/",
+		))
+	}
+
+	if len(interceptors) > 0 {
+		opts = append(opts, grpc.UnaryInterceptor(server.ChainUnaryInterceptors(interceptors...)))
+	}
+
+	s := grpc.NewServer(opts...)
 	reflection.Register(s)
 
-	storage, err := rate.NewSlideWindowStorageFromDSN(c.Storage)
+	local, err := rate.NewSlideWindowStorageFromDSN(c.Storage)
 	if err != nil {
 		log.Fatal(err.Error())
 	}
 
-	limit, err := rate.ParseLimit(c.Limit)
+	storage := local
+
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(s, healthServer)
+	healthChecker := server.NewHealthChecker(healthServer, local, c.HealthInterval)
+	healthChecker.Start()
+
+	if len(c.Replication.Storages) > 0 {
+		remotes := make([]rate.SlideWindowStorage, 0, len(c.Replication.Storages))
+		for _, dsn := range c.Replication.Storages {
+			remote, err := rate.NewSlideWindowStorageFromDSN(dsn)
+			if err != nil {
+				log.Fatal(err.Error())
+			}
+			remotes = append(remotes, remote)
+		}
+
+		storage = rate.NewMultiRegionSlideWindowStorage(storage, remotes, rate.MultiRegionOptions{
+			QueueSize:    c.Replication.QueueSize,
+			MaxStaleness: c.Replication.MaxStaleness,
+		})
+	}
+
+	if c.Async.Enabled {
+		overflow, err := rate.ParseOverflowPolicy(c.Async.Overflow)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		storage = rate.NewAsyncSlideWindowStorage(storage, rate.AsyncOptions{
+			QueueSize: c.Async.QueueSize,
+			Workers:   c.Async.Workers,
+			BatchSize: c.Async.BatchSize,
+			Overflow:  overflow,
+		})
+	}
+
+	limits, err := rate.ParseLimits(c.Limit)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	hierarchy, err := rate.ParseHierarchy(c.Hierarchy.Global, c.Hierarchy.Owner, c.Hierarchy.Resource)
 	if err != nil {
 		log.Fatal(err.Error())
 	}
-	grpcServer := server.NewGRPC(
-		limit,
-		rate.SlideWindowRateLimiter(storage, true),
-	)
+	var rules rate.Rules
+	if c.Rules != "" {
+		loaded, err := rate.LoadRules(c.Rules)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		rules, err = rate.CompileRules(loaded)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+	limiter := rate.RulesRateLimiter(rate.HierarchicalRateLimiter(rate.SlideWindowRateLimiter(storage), hierarchy), rules)
+
+	var periods rate.PeriodLimits
+	if c.Period.Limit != "" {
+		loc, err := time.LoadLocation(c.Period.Timezone)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		periods, err = rate.ParsePeriodLimits(c.Period.Limit, loc)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+	if len(periods) > 0 || rules.HasPeriodLimits() {
+		counters, ok := local.(rate.CounterStorage)
+		if !ok {
+			log.Fatalf("storage %s does not support period limits", c.Storage)
+		}
+
+		limiter = rate.PeriodRateLimiter(limiter, counters, periods, rules)
+	}
+
+	var penalty *rate.PenaltyBox
+	if c.Penalty.Violations > 0 {
+		penaltyStorage, ok := local.(rate.PenaltyStorage)
+		if !ok {
+			log.Fatalf("storage %s does not support penalties", c.Storage)
+		}
+
+		penalty, err = rate.NewPenaltyBox(penaltyStorage, rate.PenaltyPolicy{
+			Violations: c.Penalty.Violations,
+			Window:     c.Penalty.Window,
+			Ban:        c.Penalty.Ban,
+		})
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		limiter = rate.PenaltyRateLimiter(limiter, penalty)
+	}
+
+	var usage *rate.UsageAccountant
+	if c.Usage.Enabled {
+		usageStorage, ok := local.(rate.UsageStorage)
+		if !ok {
+			log.Fatalf("storage %s does not support usage accounting", c.Storage)
+		}
+
+		usage = rate.NewUsageAccountant(usageStorage, rate.UsageOptions{
+			Bucket:        c.Usage.Bucket,
+			Retention:     c.Usage.Retention,
+			FlushInterval: c.Usage.FlushInterval,
+		})
+		closers = append(closers, usage)
+		limiter = rate.UsageRateLimiter(limiter, usage)
+	}
+
+	lists := &access.Lists{}
+	if c.AccessLists != "" {
+		if err := reloadAccessLists(c.AccessLists, lists); err != nil {
+			log.Fatal(err.Error())
+		}
+
+		reloadAccessListsOnHangup(c.AccessLists, lists)
+		closers = append(closers, reloadAccessListsEvery(c.AccessLists, lists, c.AccessListsReload))
+		lists.Persist(c.AccessLists)
+	}
+	sinks := make([]audit.Sink, 0, len(c.Audit.Outputs))
+	for _, o := range c.Audit.Outputs {
+		out, err := audit.NewOutput(o)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		sink := audit.NewBufferedSink(out, audit.Options{OKSampleRate: c.Audit.OKSampleRate, QueueSize: c.Audit.QueueSize})
+		closers = append(closers, sink)
+		sinks = append(sinks, sink)
+	}
+
+	var thresholds []rate.AlertThreshold
+	if c.Alert.Percent > 0 {
+		thresholds = append(thresholds, rate.AlertThreshold{Percent: c.Alert.Percent})
+	}
+	if c.Alert.ConsecutiveRejections > 0 {
+		thresholds = append(thresholds, rate.AlertThreshold{ConsecutiveRejections: c.Alert.ConsecutiveRejections})
+	}
+	if len(thresholds) > 0 || rules.HasAlerts() {
+		o := alert.Options{
+			Thresholds: thresholds,
+			Webhook:    c.Alert.Webhook,
+			Debounce:   c.Alert.Debounce,
+		}
+		if err := o.Validate(rules); err != nil {
+			log.Fatalf("invalid alerts, set RATIO_ALERT_WEBHOOK or their webhook: %s", err.Error())
+		}
+
+		alerter := alert.NewAlerter(rules, o)
+		closers = append(closers, alerter)
+		sinks = append(sinks, alerter)
+	}
+
+	grpcServer := server.NewGRPC(limits, limiter, lists, usage, sinks...)
+
+	var members *cluster.Cluster
+	if c.Cluster.enabled() {
+		discoverer := cluster.StaticDiscoverer(c.Cluster.Peers...)
+		if c.Cluster.DNSSRV != "" {
+			discoverer = cluster.DNSSRVDiscoverer(c.Cluster.DNSSRV)
+		}
+
+		if c.Cluster.Advertise == "" {
+			log.Fatal("RATIO_CLUSTER_ADVERTISE is required in cluster mode")
+		}
+
+		members = cluster.New(
+			c.Cluster.Advertise,
+			discoverer,
+			peerCreds,
+			grpc.WithPerRPCCredentials(cluster.SecretCredentials(c.Cluster.Secret)),
+		)
+		members.Start(c.Cluster.RefreshInterval)
+
+		if gcounter, ok := local.(rate.GCounterSlideWindowStorage); ok {
+			// Every instance counts locally and converges by gossiping, so requests are not forwarded.
+			gossiper := cluster.NewGossiper(gcounter, members, c.Cluster.GossipInterval)
+			gossiper.Start()
+			closers = append(closers, gossiper)
+
+			ratio.RegisterGossipServiceServer(s, cluster.NewGossipServer(gcounter))
+		} else {
+			grpcServer = server.NewClusterGRPC(grpcServer, members)
+		}
+
+		closers = append(closers, members)
+	}
 
-	ensureInterruptionsGracefullyShutdown(storage)
+	// Anyone could lift their own bans and deny entries otherwise.
+	if c.AuthConfig != "" {
+		adminServer := server.NewAdminGRPC(usage, lists, penalty)
+		if members != nil {
+			adminServer = server.NewClusterAdminGRPC(adminServer, members)
+			ratio.RegisterPenaltyServiceServer(s, server.NewPenaltyGRPC(penalty))
+		}
+		ratio.RegisterAdminServiceServer(s, adminServer)
+	} else {
+		log.Println("the AdminService is disabled, as it requires authentication (RATIO_AUTH_CONFIG)")
+	}
+
+	if c.Concurrency.Limit > 0 {
+		leases, ok := local.(rate.ConcurrencyStorage)
+		if !ok {
+			log.Fatalf("storage %s does not support concurrency limits", c.Storage)
+		}
+
+		concurrencyServer := server.NewConcurrencyGRPC(
+			c.Concurrency.Limit,
+			c.Concurrency.LeaseTTL,
+			c.Concurrency.MaxLeaseTTL,
+			rate.NewConcurrencyLimiter(leases),
+		)
+		if members != nil {
+			// Slots are held by the owner of their key whatever the storage, as leases are not gossiped.
+			concurrencyServer = server.NewClusterConcurrencyGRPC(concurrencyServer, members)
+		}
+		ratio.RegisterConcurrencyServiceServer(s, concurrencyServer)
+	}
+
+	if weighted, ok := local.(rate.WeightedSlideWindowStorage); ok {
+		// Reservations would skip the hierarchy and period limits, letting callers go over them.
+		switch {
+		case !hierarchy.IsZero() || len(periods) > 0 || rules.HasPeriodLimits():
+			log.Println("the QuotaService is disabled, as it does not support hierarchy nor period limits")
+		case len(c.Replication.Storages) > 0:
+			// Reservations would only count in the local region.
+			log.Println("the QuotaService is disabled, as it does not support replication to other regions")
+		default:
+			quotaServer := server.NewQuotaGRPC(
+				limits,
+				rules,
+				c.Quota.LeaseTTL,
+				rate.NewSlideWindowQuotaLeaser(weighted),
+				lists,
+				penalty,
+				usage,
+				sinks...,
+			)
+			if members != nil {
+				quotaServer = server.NewClusterQuotaGRPC(quotaServer, members)
+			}
+			ratio.RegisterQuotaServiceServer(s, quotaServer)
+		}
+	}
+
+	// Closing the storage flushes the pending writes first.
+	closers = append(closers, storage)
+	done := ensureInterruptionsGracefullyShutdown(s, c.ShutdownTimeout, healthChecker, closers...)
 
 	ratio.RegisterRateLimitServiceServer(s, grpcServer)
 	if err := s.Serve(listener); err != nil {
 		log.Fatalf("failed to serve: %v", err)
 	}
+
+	<-done
 }
 
-func ensureInterruptionsGracefullyShutdown(s rate.SlideWindowStorage) {
+// ensureInterruptionsGracefullyShutdown shuts down the server on interruption. The returned channel is closed once
+// the shutdown finishes.
+func ensureInterruptionsGracefullyShutdown(srv gracefulStopper, timeout time.Duration, health drainer, closers ...io.Closer) <-chan struct{} {
+	done := make(chan struct{})
+
 	c := make(chan os.Signal, 2)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
 	go func() {
 		<-c
 		log.Println("Shutting down ratio...")
 
-		_ = s.Close()
-
-		// TODO notify on close and remove this sleep.
-		time.Sleep(time.Second)
-
-		os.Exit(0)
+		shutdown(srv, timeout, health, closers...)
+		close(done)
 	}()
+
+	return done
 }
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/smoya/ratio/internal/access"
+)
+
+// reloadAccessListsOnHangup loads the access lists from file again on every SIGHUP. The lists are kept as they are if
+// the file is not valid.
+func reloadAccessListsOnHangup(file string, lists *access.Lists) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+	go func() {
+		for range c {
+			if err := reloadAccessLists(file, lists); err != nil {
+				log.Printf("error reloading the access lists: %s\n", err.Error())
+				continue
+			}
+
+			log.Println("access lists reloaded")
+		}
+	}()
+}
+
+func reloadAccessLists(file string, lists *access.Lists) error {
+	c, err := access.LoadConfig(file)
+	if err != nil {
+		return err
+	}
+
+	return lists.Set(c)
+}
+
+// accessListsReloader reloads the access lists from file in the background until Close is called.
+type accessListsReloader struct {
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// reloadAccessListsEvery loads the access lists from file again every interval if the file changed, e.g. by the
+// AdminService of another instance sharing it, until the returned reloader is closed. The lists are kept as they are
+// if the file is not valid. A zero interval disables it.
+func reloadAccessListsEvery(file string, lists *access.Lists, interval time.Duration) *accessListsReloader {
+	r := &accessListsReloader{done: make(chan struct{})}
+	if interval <= 0 {
+		return r
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		log.Printf("error checking the access lists: %s\n", err.Error())
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-r.done:
+				return
+			case <-t.C:
+			}
+
+			current, err := os.Stat(file)
+			if err != nil {
+				log.Printf("error checking the access lists: %s\n", err.Error())
+				continue
+			}
+
+			if info != nil && current.ModTime().Equal(info.ModTime()) && current.Size() == info.Size() {
+				continue
+			}
+			info = current
+
+			if err := reloadAccessLists(file, lists); err != nil {
+				log.Printf("error reloading the access lists: %s\n", err.Error())
+				continue
+			}
+
+			log.Println("access lists reloaded")
+		}
+	}()
+
+	return r
+}
+
+// Close stops reloading the access lists.
+func (r *accessListsReloader) Close() error {
+	close(r.done)
+	r.wg.Wait()
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smoya/ratio/internal/access"
+)
+
+func TestReloadAccessListsEvery(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ratio")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "access.json")
+	require.NoError(t, access.SaveConfig(file, access.Config{}))
+
+	lists := &access.Lists{}
+	require.NoError(t, reloadAccessLists(file, lists))
+
+	r := reloadAccessListsEvery(file, lists, 10*time.Millisecond)
+	require.NoError(t, access.SaveConfig(file, access.Config{Deny: []access.Entry{{Owner: "scraper"}}}))
+	for deadline := time.Now().Add(time.Second); lists.Check("scraper", "/pay") != access.Denied; {
+		require.True(t, time.Now().Before(deadline), "the access lists should be reloaded")
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	require.NoError(t, r.Close())
+	require.NoError(t, access.SaveConfig(file, access.Config{}))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, access.Denied, lists.Check("scraper", "/pay"), "not reloaded once closed")
+
+	assert.NoError(t, reloadAccessListsEvery(file, lists, 0).Close(), "disabled")
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"io"
+	"log"
+	"time"
+)
+
+// gracefulStopper is a server that can be stopped gracefully, like *grpc.Server.
+type gracefulStopper interface {
+	GracefulStop()
+	Stop()
+}
+
+// drainer stops advertising the server as healthy, like *server.HealthChecker.
+type drainer interface {
+	Drain()
+}
+
+// shutdown marks the server as not serving through health, if any, so probes see it while it drains. Then, it stops
+// the server from accepting new connections and waits for the in-flight requests to finish. If they do not finish
+// before the timeout, the server is forcibly stopped. Finally, closers are closed in the given order, so pending
+// writes can be flushed before closing the storage.
+func shutdown(srv gracefulStopper, timeout time.Duration, health drainer, closers ...io.Closer) {
+	if health != nil {
+		health.Drain()
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(timeout):
+		log.Printf("in-flight requests did not finish in %s, forcing stop\n", timeout)
+		srv.Stop()
+		<-stopped
+	}
+
+	for _, c := range closers {
+		if err := c.Close(); err != nil {
+			log.Printf("error closing: %s\n", err.Error())
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/smoya/ratio/internal/server"
+	"github.com/smoya/ratio/pkg/rate"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+
+	ratio "github.com/smoya/ratio/api/proto"
+)
+
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recorder) record(call string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, call)
+}
+
+func (r *recorder) recorded() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string{}, r.calls...)
+}
+
+type fakeServer struct {
+	*recorder
+	inFlight chan struct{}
+}
+
+func (s fakeServer) GracefulStop() {
+	<-s.inFlight
+	s.record("graceful stop")
+}
+
+func (s fakeServer) Stop() {
+	s.record("stop")
+	close(s.inFlight)
+}
+
+type fakeCloser struct {
+	*recorder
+	name string
+	err  error
+}
+
+func (c fakeCloser) Close() error {
+	c.record(c.name)
+	return c.err
+}
+
+type fakeDrainer struct {
+	*recorder
+	drained chan struct{}
+}
+
+func (d fakeDrainer) Drain() {
+	d.record("not serving")
+	close(d.drained)
+}
+
+func TestShutdown(t *testing.T) {
+	r := &recorder{}
+	srv := fakeServer{recorder: r, inFlight: make(chan struct{})}
+
+	drainer := fakeDrainer{recorder: r, drained: make(chan struct{})}
+
+	go func() {
+		<-drainer.drained
+		r.record("in-flight finished")
+		close(srv.inFlight)
+	}()
+
+	shutdown(srv, time.Second, drainer, fakeCloser{r, "pending writes", errors.New("whatever error")}, fakeCloser{r, "storage", nil})
+
+	assert.Equal(t, []string{"not serving", "in-flight finished", "graceful stop", "pending writes", "storage"}, r.recorded())
+}
+
+func TestShutdown_Timeout(t *testing.T) {
+	r := &recorder{}
+	srv := fakeServer{recorder: r, inFlight: make(chan struct{})}
+
+	shutdown(srv, 10*time.Millisecond, nil, fakeCloser{r, "storage", nil})
+
+	assert.Equal(t, []string{"stop", "graceful stop", "storage"}, r.recorded())
+}
+
+func TestShutdown_GRPC(t *testing.T) {
+	inner := rate.NewInMemorySlideWindowStorage(make(map[string][]time.Time))
+	storage := rate.NewAsyncSlideWindowStorage(inner, rate.AsyncOptions{})
+	limiter := rate.SlideWindowRateLimiter(storage)
+
+	inFlight := make(chan struct{})
+	srv := grpc.NewServer()
+	ratio.RegisterRateLimitServiceServer(srv, server.NewGRPC(
+		rate.Limits{rate.NewLimit(rate.PerMinute, 5)},
+		func(l rate.Limits, owner, resource string, descriptors ...rate.Descriptor) (rate.Decision, error) {
+			close(inFlight)
+			time.Sleep(20 * time.Millisecond)
+			return limiter(l, owner, resource, descriptors...)
+		},
+		nil,
+		nil,
+	))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go func() { _ = srv.Serve(l) }()
+
+	conn, err := grpc.Dial(l.Addr().String(), grpc.WithInsecure())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	resp := make(chan *ratio.RateLimitResponse)
+	go func() {
+		r, err := ratio.NewRateLimitServiceClient(conn).RateLimit(context.Background(), &ratio.RateLimitRequest{Owner: "myservice"})
+		assert.NoError(t, err)
+		resp <- r
+	}()
+
+	<-inFlight
+	done := make(chan struct{})
+	go func() {
+		shutdown(srv, time.Second, nil, storage)
+		close(done)
+	}()
+
+	assert.Equal(t, ratio.RateLimitResponse_OK, (<-resp).Code, "in-flight requests should finish")
+	<-done
+
+	hits, err := inner.Count(rate.Key("myservice", ""), time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, hits, "pending writes should be flushed")
+}
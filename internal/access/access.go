@@ -0,0 +1,349 @@
+// Package access keeps the lists of owners and resources that are always allowed, bypassing their limits, or always
+// denied.
+package access
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Names of the lists.
+const (
+	Allow = "allow"
+	Deny  = "deny"
+)
+
+// Verdict is the result of checking a hit against the lists.
+type Verdict int
+
+// Verdicts.
+const (
+	// None means the hit is in no list, so its limits apply.
+	None Verdict = iota
+	Allowed
+	Denied
+)
+
+// Entry matches hits by owner and resource. Both are patterns with the syntax of path.Match, and empty ones match
+// anything. The owner may be a CIDR as well, matching the owners that are IPs in its range.
+type Entry struct {
+	Owner    string `json:"owner,omitempty"`
+	Resource string `json:"resource,omitempty"`
+}
+
+// Config are the entries of the lists.
+//
+// Example:
+//
+//	{
+//	  "allow": [{"owner": "health-checker"}],
+//	  "deny": [{"owner": "203.0.113.0/24"}, {"owner": "scraper-*", "resource": "/v1/search"}]
+//	}
+type Config struct {
+	Allow []Entry `json:"allow"`
+	Deny  []Entry `json:"deny"`
+}
+
+// LoadConfig loads a Config from a JSON file.
+func LoadConfig(file string) (Config, error) {
+	var c Config
+
+	raw, err := ioutil.ReadFile(file)
+	if err != nil {
+		return c, err
+	}
+
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return c, fmt.Errorf("invalid access lists %s: %s", file, err.Error())
+	}
+
+	return c, nil
+}
+
+// SaveConfig writes c to a JSON file. The file is replaced at once, so it is never read half written.
+func SaveConfig(file string, c Config) error {
+	raw, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(file); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(file), "."+filepath.Base(file)+"-*")
+	if err != nil {
+		return fmt.Errorf("saving the access lists %s: %s", file, err.Error())
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(append(raw, '\n'))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), mode)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), file)
+	}
+	if err != nil {
+		return fmt.Errorf("saving the access lists %s: %s", file, err.Error())
+	}
+
+	return nil
+}
+
+// Validate checks the entry is usable.
+func (e Entry) Validate() error {
+	_, err := compile(e)
+	return err
+}
+
+type matcher struct {
+	Entry
+	network *net.IPNet
+}
+
+func compile(e Entry) (matcher, error) {
+	if e.Owner == "" && e.Resource == "" {
+		return matcher{}, errors.New("entry without owner nor resource matches every hit")
+	}
+
+	m := matcher{Entry: e}
+	if strings.Contains(e.Owner, "/") {
+		if _, network, err := net.ParseCIDR(e.Owner); err == nil {
+			m.network = network
+		}
+	}
+
+	if _, err := path.Match(e.Owner, ""); m.network == nil && err != nil {
+		return matcher{}, fmt.Errorf("invalid owner pattern %s: %s", e.Owner, err.Error())
+	}
+
+	if _, err := path.Match(e.Resource, ""); err != nil {
+		return matcher{}, fmt.Errorf("invalid resource pattern %s: %s", e.Resource, err.Error())
+	}
+
+	return m, nil
+}
+
+func (m matcher) matches(owner, resource string) bool {
+	if m.network != nil {
+		ip := net.ParseIP(owner)
+		if ip == nil || !m.network.Contains(ip) {
+			return false
+		}
+	} else if !match(m.Owner, owner) {
+		return false
+	}
+
+	return match(m.Resource, resource)
+}
+
+func match(pattern, s string) bool {
+	if pattern == "" {
+		return true
+	}
+
+	ok, _ := path.Match(pattern, s)
+	return ok
+}
+
+// Lists are the allow and deny lists. Deny entries win over allow ones. They are safe for concurrent use, and their
+// entries can be changed while in use. The zero value has no entries.
+type Lists struct {
+	mu    sync.RWMutex
+	allow []matcher
+	deny  []matcher
+	file  string
+}
+
+// NewLists creates Lists with the entries of c.
+func NewLists(c Config) (*Lists, error) {
+	l := &Lists{}
+	if err := l.Set(c); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Check returns the verdict of the lists for a hit of the owner on the resource. Nil Lists have no entries.
+func (l *Lists) Check(owner, resource string) Verdict {
+	if l == nil {
+		return None
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for _, m := range l.deny {
+		if m.matches(owner, resource) {
+			return Denied
+		}
+	}
+
+	for _, m := range l.allow {
+		if m.matches(owner, resource) {
+			return Allowed
+		}
+	}
+
+	return None
+}
+
+// Set replaces all the entries by the ones of c. The entries are kept if c is not valid.
+func (l *Lists) Set(c Config) error {
+	allow, err := compileAll(Allow, c.Allow)
+	if err != nil {
+		return err
+	}
+
+	deny, err := compileAll(Deny, c.Deny)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.allow, l.deny = allow, deny
+	l.mu.Unlock()
+
+	return nil
+}
+
+func compileAll(list string, entries []Entry) ([]matcher, error) {
+	matchers := make([]matcher, 0, len(entries))
+	for i, e := range entries {
+		m, err := compile(e)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s entry %d: %s", list, i, err.Error())
+		}
+
+		matchers = append(matchers, m)
+	}
+
+	return matchers, nil
+}
+
+// Persist makes Add and Remove write the entries to file, with the format of LoadConfig, so their changes survive
+// the reloads of the file and reach the other instances loading it.
+func (l *Lists) Persist(file string) {
+	l.mu.Lock()
+	l.file = file
+	l.mu.Unlock()
+}
+
+// Persistent returns whether the changes of Add and Remove are written to a file. Otherwise they only apply to these
+// Lists, until replaced by Set.
+func (l *Lists) Persistent() bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.file != ""
+}
+
+// Config returns the current entries.
+func (l *Lists) Config() Config {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.config()
+}
+
+func (l *Lists) config() Config {
+	var c Config
+	for _, m := range l.allow {
+		c.Allow = append(c.Allow, m.Entry)
+	}
+
+	for _, m := range l.deny {
+		c.Deny = append(c.Deny, m.Entry)
+	}
+
+	return c
+}
+
+// Add adds the entry to the list, returning false if it was already there.
+func (l *Lists) Add(list string, e Entry) (bool, error) {
+	if err := e.Validate(); err != nil {
+		return false, err
+	}
+
+	return l.change(list, func(entries []Entry) ([]Entry, bool) {
+		for _, existing := range entries {
+			if existing == e {
+				return entries, false
+			}
+		}
+
+		return append(entries, e), true
+	})
+}
+
+// Remove removes the entry from the list, returning false if it was not there.
+func (l *Lists) Remove(list string, e Entry) (bool, error) {
+	return l.change(list, func(entries []Entry) ([]Entry, bool) {
+		for i, existing := range entries {
+			if existing == e {
+				return append(entries[:i:i], entries[i+1:]...), true
+			}
+		}
+
+		return entries, false
+	})
+}
+
+// change applies f to the entries of the list. The entries are read from the file first, if persisted, and written
+// back to it when changed, so the changes of other instances sharing the file are kept.
+func (l *Lists) change(list string, f func([]Entry) ([]Entry, bool)) (bool, error) {
+	if list != Allow && list != Deny {
+		return false, fmt.Errorf("unknown list %s: use %s or %s", list, Allow, Deny)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c := l.config()
+	if l.file != "" {
+		var err error
+		if c, err = LoadConfig(l.file); err != nil {
+			return false, err
+		}
+	}
+
+	var changed bool
+	if list == Allow {
+		c.Allow, changed = f(c.Allow)
+	} else {
+		c.Deny, changed = f(c.Deny)
+	}
+
+	allow, err := compileAll(Allow, c.Allow)
+	if err != nil {
+		return false, err
+	}
+
+	deny, err := compileAll(Deny, c.Deny)
+	if err != nil {
+		return false, err
+	}
+
+	if changed && l.file != "" {
+		if err := SaveConfig(l.file, c); err != nil {
+			return false, err
+		}
+	}
+	l.allow, l.deny = allow, deny
+
+	return changed, nil
+}
@@ -0,0 +1,149 @@
+package access
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLists_Check(t *testing.T) {
+	l, err := NewLists(Config{
+		Allow: []Entry{{Owner: "health-*"}, {Owner: "10.0.0.0/8"}, {Resource: "/healthz"}},
+		Deny:  []Entry{{Owner: "scraper", Resource: "/v1/*"}, {Owner: "10.6.6.0/24"}},
+	})
+	require.NoError(t, err)
+
+	cases := map[string]struct {
+		owner, resource string
+		verdict         Verdict
+	}{
+		"Allowed by pattern":       {owner: "health-checker", resource: "/v1/order", verdict: Allowed},
+		"Allowed by CIDR":          {owner: "10.1.2.3", resource: "/v1/order", verdict: Allowed},
+		"Allowed by resource":      {owner: "checkout", resource: "/healthz", verdict: Allowed},
+		"Denied":                   {owner: "scraper", resource: "/v1/search", verdict: Denied},
+		"Deny wins over allow":     {owner: "10.6.6.6", resource: "/healthz", verdict: Denied},
+		"Other resource":           {owner: "scraper", resource: "/v2/search", verdict: None},
+		"Owner not an IP":          {owner: "10.0.0.0/8", resource: "/v1/order", verdict: None},
+		"IPv6 owner out of ranges": {owner: "2001:db8::1", resource: "/v1/order", verdict: None},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, c.verdict, l.Check(c.owner, c.resource))
+		})
+	}
+
+	var nilLists *Lists
+	assert.Equal(t, None, nilLists.Check("scraper", "/v1/search"))
+}
+
+func TestLists_AddRemove(t *testing.T) {
+	l, err := NewLists(Config{})
+	require.NoError(t, err)
+
+	snapshot := l.Config()
+
+	added, err := l.Add(Deny, Entry{Owner: "scraper"})
+	require.NoError(t, err)
+	assert.True(t, added)
+	assert.Equal(t, Denied, l.Check("scraper", "/v1/search"))
+
+	added, err = l.Add(Deny, Entry{Owner: "scraper"})
+	require.NoError(t, err)
+	assert.False(t, added)
+
+	_, err = l.Add(Allow, Entry{})
+	assert.Error(t, err)
+	_, err = l.Add("block", Entry{Owner: "scraper"})
+	assert.Error(t, err)
+
+	assert.Equal(t, Config{Deny: []Entry{{Owner: "scraper"}}}, l.Config())
+	assert.Equal(t, Config{}, snapshot)
+
+	removed, err := l.Remove(Allow, Entry{Owner: "scraper"})
+	require.NoError(t, err)
+	assert.False(t, removed)
+
+	removed, err = l.Remove(Deny, Entry{Owner: "scraper"})
+	require.NoError(t, err)
+	assert.True(t, removed)
+	assert.Equal(t, None, l.Check("scraper", "/v1/search"))
+}
+
+func TestLists_Set(t *testing.T) {
+	l, err := NewLists(Config{Deny: []Entry{{Owner: "scraper"}}})
+	require.NoError(t, err)
+
+	assert.Error(t, l.Set(Config{Allow: []Entry{{Owner: "["}}}))
+	assert.Equal(t, Denied, l.Check("scraper", "/"), "entries are kept on invalid configs")
+
+	require.NoError(t, l.Set(Config{Allow: []Entry{{Owner: "scraper"}}}))
+	assert.Equal(t, Allowed, l.Check("scraper", "/"))
+}
+
+func TestLists_Persist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "access")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "access.json")
+	require.NoError(t, SaveConfig(file, Config{Allow: []Entry{{Owner: "health-checker"}}}))
+
+	l, err := NewLists(Config{})
+	require.NoError(t, err)
+	assert.False(t, l.Persistent())
+	l.Persist(file)
+	assert.True(t, l.Persistent())
+
+	// Another instance sharing the file.
+	other, err := NewLists(Config{})
+	require.NoError(t, err)
+	other.Persist(file)
+	added, err := other.Add(Deny, Entry{Owner: "10.6.6.0/24"})
+	require.NoError(t, err)
+	assert.True(t, added)
+
+	added, err = l.Add(Deny, Entry{Owner: "scraper"})
+	require.NoError(t, err)
+	assert.True(t, added)
+	assert.Equal(t, Allowed, l.Check("health-checker", "/"), "the entries should be read from the file")
+
+	removed, err := l.Remove(Allow, Entry{Owner: "health-checker"})
+	require.NoError(t, err)
+	assert.True(t, removed)
+
+	c, err := LoadConfig(file)
+	require.NoError(t, err)
+	assert.Empty(t, c.Allow)
+	assert.Equal(t, []Entry{{Owner: "10.6.6.0/24"}, {Owner: "scraper"}}, c.Deny)
+	assert.Equal(t, c.Deny, l.Config().Deny)
+
+	l.Persist(filepath.Join(dir, "missing", "access.json"))
+	_, err = l.Add(Deny, Entry{Owner: "crawler"})
+	assert.Error(t, err)
+	assert.Equal(t, None, l.Check("crawler", "/"), "entries are not changed when the file fails")
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "access")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "access.json")
+	require.NoError(t, ioutil.WriteFile(file, []byte(`{"allow": [{"owner": "health-checker"}], "deny": [{"owner": "203.0.113.0/24", "resource": "/v1/*"}]}`), 0600))
+
+	c, err := LoadConfig(file)
+	require.NoError(t, err)
+	assert.Equal(t, Config{
+		Allow: []Entry{{Owner: "health-checker"}},
+		Deny:  []Entry{{Owner: "203.0.113.0/24", Resource: "/v1/*"}},
+	}, c)
+
+	require.NoError(t, ioutil.WriteFile(file, []byte(`{"allow": `), 0600))
+	_, err = LoadConfig(file)
+	assert.Error(t, err)
+}
@@ -0,0 +1,328 @@
+// Package alert notifies webhooks when the hits of an owner approach or exceed its limits.
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/smoya/ratio/internal/audit"
+	"github.com/smoya/ratio/pkg/rate"
+)
+
+// Kinds of Alert.
+const (
+	KindPercent               = "percent"
+	KindConsecutiveRejections = "consecutive_rejections"
+)
+
+// Alert is the notification of a threshold reached, sent as JSON in the body of a POST to the webhook.
+type Alert struct {
+	Time        time.Time         `json:"time"`
+	Kind        string            `json:"kind"`
+	Owner       string            `json:"owner"`
+	Resource    string            `json:"resource"`
+	Descriptors []rate.Descriptor `json:"descriptors,omitempty"`
+	// Threshold is the percent or the consecutive rejections reached.
+	Threshold int         `json:"threshold"`
+	Limit     audit.Limit `json:"limit"`
+	Level     string      `json:"level"`
+	// Count is the number of hits in the window of the limit, the current one included if allowed.
+	Count int `json:"count"`
+	// Rejections is the number of hits rejected in a row.
+	Rejections int `json:"rejections"`
+	// Period is the usage of the period limit of the owner, if any, the current hit included if allowed.
+	Period *audit.Period `json:"period,omitempty"`
+
+	webhook string
+}
+
+// Default Options.
+const (
+	DefaultDebounce  = time.Hour
+	DefaultQueueSize = 1000
+)
+
+// Options configures an Alerter.
+type Options struct {
+	// Thresholds are the ones of the hits not matching any rule with alerts.
+	Thresholds []rate.AlertThreshold
+	// Webhook is the URL notified by the thresholds without their own.
+	Webhook string
+	// Debounce is the min time between two notifications of the same threshold and owner.
+	Debounce time.Duration
+	// QueueSize is the max number of alerts pending to be sent. Alerts are dropped when the queue is full.
+	QueueSize int
+	Client    *http.Client
+}
+
+// Validate returns an error if any of the thresholds, or the ones of the rules, has no webhook to notify.
+func (o Options) Validate(rules rate.Rules) error {
+	if o.Webhook != "" {
+		return nil
+	}
+
+	for _, t := range append(o.Thresholds, rules.AllAlerts()...) {
+		if t.Webhook == "" {
+			return fmt.Errorf("the alert threshold %+v has no webhook, and no default one is set", t)
+		}
+	}
+
+	return nil
+}
+
+// shards is the number of locks the state of the owners is split into, so concurrent decisions rarely wait for each
+// other.
+const shards = 32
+
+type shard struct {
+	mu     sync.Mutex
+	owners map[string]*ownerState
+}
+
+type ownerState struct {
+	lastSeen time.Time
+	// rejections are the hits rejected in a row of each key of the owner, if any.
+	rejections map[string]int
+	// notified is the last time each threshold was notified.
+	notified map[rate.AlertThreshold]time.Time
+}
+
+// Alerter is an audit.Sink checking the decisions against the alert thresholds of the rules, and notifying the ones
+// reached in the background, at most once per Debounce for each threshold and owner, whatever the resource or
+// descriptors reaching it. It is safe for concurrent use.
+type Alerter struct {
+	rules rate.Rules
+	o     Options
+
+	shards [shards]shard
+	// swept is the last time, in unix nanoseconds, idle owners were forgotten.
+	swept int64
+
+	closeMu sync.RWMutex
+	closed  bool
+
+	queue   chan Alert
+	dropped uint64
+	done    chan struct{}
+}
+
+// NewAlerter creates an Alerter. Close sends the pending alerts.
+func NewAlerter(rules rate.Rules, o Options) *Alerter {
+	if o.Debounce <= 0 {
+		o.Debounce = DefaultDebounce
+	}
+
+	if o.QueueSize <= 0 {
+		o.QueueSize = DefaultQueueSize
+	}
+
+	if o.Client == nil {
+		o.Client = &http.Client{Timeout: 5 * time.Second}
+	}
+
+	a := &Alerter{
+		rules: rules,
+		o:     o,
+		queue: make(chan Alert, o.QueueSize),
+		done:  make(chan struct{}),
+	}
+	for i := range a.shards {
+		a.shards[i].owners = make(map[string]*ownerState)
+	}
+
+	go a.work()
+
+	return a
+}
+
+// Emit implements audit.Sink. The hits decided by the access lists are ignored, as they are not checked against any
+// limit. Percent thresholds are checked against both the limit and the period limit of the event, if any.
+func (a *Alerter) Emit(e audit.Event) {
+	if e.Reason == audit.ReasonAllowed || e.Reason == audit.ReasonDenied {
+		return
+	}
+
+	thresholds := a.rules.Alerts(a.o.Thresholds, e.Owner, e.Resource, e.Descriptors...)
+	if len(thresholds) == 0 {
+		return
+	}
+
+	count := e.Count
+	var period *audit.Period
+	if e.Period != nil {
+		p := *e.Period
+		period = &p
+	}
+	if e.Decision == audit.DecisionOK {
+		count++
+		if period != nil {
+			period.Count++
+		}
+	}
+
+	a.closeMu.RLock()
+	defer a.closeMu.RUnlock()
+
+	if a.closed {
+		return
+	}
+
+	a.sweep(e.Time)
+
+	sh := a.shard(e.Owner)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	s, ok := sh.owners[e.Owner]
+	if !ok {
+		s = &ownerState{rejections: make(map[string]int), notified: make(map[rate.AlertThreshold]time.Time)}
+		sh.owners[e.Owner] = s
+	}
+	s.lastSeen = e.Time
+
+	key := rate.Key(e.Owner, e.Resource, e.Descriptors...)
+	if e.Decision == audit.DecisionOK {
+		delete(s.rejections, key)
+	} else {
+		s.rejections[key]++
+	}
+	rejections := s.rejections[key]
+
+	for _, t := range thresholds {
+		alert := Alert{
+			Time:        e.Time,
+			Owner:       e.Owner,
+			Resource:    e.Resource,
+			Descriptors: e.Descriptors,
+			Limit:       e.Limit,
+			Level:       e.Level,
+			Count:       count,
+			Rejections:  rejections,
+			Period:      period,
+			webhook:     t.Webhook,
+		}
+
+		switch {
+		case t.Percent > 0 && (reached(t.Percent, count, e.Limit.Quantity) ||
+			period != nil && reached(t.Percent, period.Count, period.Quantity)):
+			alert.Kind, alert.Threshold = KindPercent, t.Percent
+		case t.ConsecutiveRejections > 0 && rejections >= t.ConsecutiveRejections:
+			alert.Kind, alert.Threshold = KindConsecutiveRejections, t.ConsecutiveRejections
+		default:
+			continue
+		}
+
+		if last, ok := s.notified[t]; ok && e.Time.Sub(last) < a.o.Debounce {
+			continue
+		}
+		s.notified[t] = e.Time
+
+		if alert.webhook == "" {
+			alert.webhook = a.o.Webhook
+		}
+
+		select {
+		case a.queue <- alert:
+		default:
+			atomic.AddUint64(&a.dropped, 1)
+		}
+	}
+}
+
+// reached tells whether count is at least percent of quantity.
+func reached(percent, count, quantity int) bool {
+	return quantity > 0 && count*100 >= percent*quantity
+}
+
+func (a *Alerter) shard(owner string) *shard {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(owner))
+
+	return &a.shards[h.Sum32()%shards]
+}
+
+// sweep forgets the owners not seen for a debounce, at most once per minute, so they do not pile up.
+func (a *Alerter) sweep(now time.Time) {
+	last := atomic.LoadInt64(&a.swept)
+	if now.UnixNano()-last < int64(time.Minute) || !atomic.CompareAndSwapInt64(&a.swept, last, now.UnixNano()) {
+		return
+	}
+
+	for i := range a.shards {
+		sh := &a.shards[i]
+		sh.mu.Lock()
+		for owner, s := range sh.owners {
+			if now.Sub(s.lastSeen) > a.o.Debounce {
+				delete(sh.owners, owner)
+			}
+		}
+		sh.mu.Unlock()
+	}
+}
+
+// Dropped returns the number of alerts lost, either because the queue was full or because the webhook failed.
+func (a *Alerter) Dropped() uint64 {
+	return atomic.LoadUint64(&a.dropped)
+}
+
+// Close stops checking decisions and sends the pending alerts.
+func (a *Alerter) Close() error {
+	a.closeMu.Lock()
+	if a.closed {
+		a.closeMu.Unlock()
+		return nil
+	}
+	a.closed = true
+	close(a.queue)
+	a.closeMu.Unlock()
+
+	<-a.done
+
+	if dropped := a.Dropped(); dropped > 0 {
+		log.Printf("%d alerts were dropped in total\n", dropped)
+	}
+
+	return nil
+}
+
+func (a *Alerter) work() {
+	defer close(a.done)
+
+	for alert := range a.queue {
+		if err := a.send(alert); err != nil {
+			atomic.AddUint64(&a.dropped, 1)
+			log.Printf("error sending %s alert of %s -> %s: %s\n", alert.Kind, alert.Owner, alert.Resource, err.Error())
+		}
+	}
+}
+
+func (a *Alerter) send(alert Alert) error {
+	if alert.webhook == "" {
+		return errors.New("no webhook")
+	}
+
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	resp, err := a.o.Client.Post(alert.webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s answered %s", alert.webhook, resp.Status)
+	}
+
+	return nil
+}
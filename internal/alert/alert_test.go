@@ -0,0 +1,205 @@
+package alert
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smoya/ratio/internal/audit"
+	"github.com/smoya/ratio/pkg/rate"
+)
+
+type webhook struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	alerts []Alert
+}
+
+func newWebhook(t *testing.T) *webhook {
+	w := &webhook{}
+	w.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var a Alert
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&a))
+
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.alerts = append(w.alerts, a)
+	}))
+
+	return w
+}
+
+func (w *webhook) received() []Alert {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return append([]Alert{}, w.alerts...)
+}
+
+func event(t time.Time, owner string, hits int, allowed bool) audit.Event {
+	return audit.NewEvent(t, owner, "/pay", nil, rate.Decision{Allowed: allowed, Limit: rate.NewLimit(rate.PerMinute, 10), Hits: hits})
+}
+
+func TestAlerter_Percent(t *testing.T) {
+	w := newWebhook(t)
+	defer w.Close()
+
+	a := NewAlerter(nil, Options{
+		Thresholds: []rate.AlertThreshold{{Percent: 80}},
+		Webhook:    w.URL,
+		Debounce:   time.Minute,
+	})
+
+	now := time.Now()
+	for hits := 0; hits < 10; hits++ {
+		a.Emit(event(now, "checkout", hits, true))
+	}
+	// Debounced until a minute passes, whatever the resource.
+	a.Emit(audit.NewEvent(now, "checkout", "/orders", nil, rate.Decision{Allowed: true, Limit: rate.NewLimit(rate.PerMinute, 10), Hits: 9}))
+	a.Emit(event(now.Add(time.Minute), "checkout", 9, true))
+	a.Emit(event(now, "search", 1, true))
+	require.NoError(t, a.Close())
+
+	alerts := w.received()
+	require.Len(t, alerts, 2)
+	assert.Equal(t, KindPercent, alerts[0].Kind)
+	assert.Equal(t, 80, alerts[0].Threshold)
+	assert.Equal(t, "checkout", alerts[0].Owner)
+	assert.Equal(t, 8, alerts[0].Count, "the 8th hit should reach the 80%")
+	assert.Equal(t, audit.Limit{Quantity: 10, WindowMs: 60000}, alerts[0].Limit)
+	assert.Equal(t, 10, alerts[1].Count)
+}
+
+func TestAlerter_Period(t *testing.T) {
+	w := newWebhook(t)
+	defer w.Close()
+
+	a := NewAlerter(nil, Options{
+		Thresholds: []rate.AlertThreshold{{Percent: 90}, {ConsecutiveRejections: 2}},
+		Webhook:    w.URL,
+	})
+
+	now := time.Now()
+	day := rate.PeriodLimit{Period: rate.Period{Unit: rate.Daily, Location: time.UTC}, Quantity: 100}
+	period := func(hits int) *rate.PeriodUsage {
+		return &rate.PeriodUsage{Limit: day, Hits: hits}
+	}
+
+	a.Emit(audit.NewEvent(now, "checkout", "/pay", nil, rate.Decision{Allowed: true, Limit: rate.NewLimit(rate.PerMinute, 10), Hits: 1, Period: period(88)}))
+	a.Emit(audit.NewEvent(now, "checkout", "/pay", nil, rate.Decision{Allowed: true, Limit: rate.NewLimit(rate.PerMinute, 10), Hits: 2, Period: period(89)}))
+	// Rejected by the period, without any limit.
+	a.Emit(audit.NewEvent(now, "checkout", "/pay", nil, rate.Decision{Period: period(100)}))
+	a.Emit(audit.NewEvent(now, "checkout", "/pay", nil, rate.Decision{Period: period(100)}))
+	require.NoError(t, a.Close())
+
+	alerts := w.received()
+	require.Len(t, alerts, 2)
+	assert.Equal(t, KindPercent, alerts[0].Kind)
+	assert.Equal(t, 90, alerts[0].Threshold)
+	assert.Equal(t, 3, alerts[0].Count, "far from the limit")
+	require.NotNil(t, alerts[0].Period)
+	assert.Equal(t, 90, alerts[0].Period.Count, "the 90th hit of the period should reach the 90%")
+	assert.Equal(t, KindConsecutiveRejections, alerts[1].Kind)
+	assert.Equal(t, 2, alerts[1].Rejections, "period rejections are rejections")
+}
+
+func TestAlerter_ConsecutiveRejections(t *testing.T) {
+	w := newWebhook(t)
+	defer w.Close()
+
+	a := NewAlerter(nil, Options{
+		Thresholds: []rate.AlertThreshold{{ConsecutiveRejections: 3}},
+		Webhook:    w.URL,
+	})
+
+	now := time.Now()
+	a.Emit(event(now, "checkout", 10, false))
+	a.Emit(event(now, "checkout", 10, false))
+	a.Emit(event(now, "checkout", 9, true))
+	a.Emit(event(now, "checkout", 10, false))
+	a.Emit(event(now, "checkout", 10, false))
+	a.Emit(event(now, "checkout", 10, false))
+	a.Emit(event(now, "checkout", 10, false))
+	for i := 0; i < 3; i++ {
+		a.Emit(audit.NewListEvent(now, "scraper", "/pay", nil, false))
+	}
+	require.NoError(t, a.Close())
+
+	alerts := w.received()
+	require.Len(t, alerts, 1, "hits in the deny list should be ignored, an OK decision should reset the rejections, and then alerts are debounced")
+	assert.Equal(t, KindConsecutiveRejections, alerts[0].Kind)
+	assert.Equal(t, 3, alerts[0].Rejections)
+}
+
+func TestAlerter_Rules(t *testing.T) {
+	w := newWebhook(t)
+	defer w.Close()
+	other := newWebhook(t)
+	defer other.Close()
+
+	rules, err := rate.CompileRules([]rate.Rule{
+		{Owner: "acme", Alerts: []rate.AlertThreshold{{Percent: 50, Webhook: other.URL}}},
+	})
+	require.NoError(t, err)
+
+	a := NewAlerter(rules, Options{Thresholds: []rate.AlertThreshold{{Percent: 90}}, Webhook: w.URL})
+
+	now := time.Now()
+	a.Emit(event(now, "acme", 4, true))
+	a.Emit(event(now, "checkout", 4, true))
+	a.Emit(event(now, "checkout", 8, true))
+	require.NoError(t, a.Close())
+
+	require.Len(t, other.received(), 1)
+	assert.Equal(t, "acme", other.received()[0].Owner)
+	require.Len(t, w.received(), 1)
+	assert.Equal(t, "checkout", w.received()[0].Owner)
+	assert.Equal(t, 90, w.received()[0].Threshold)
+}
+
+func TestAlerter_Sweep(t *testing.T) {
+	a := NewAlerter(nil, Options{Thresholds: []rate.AlertThreshold{{Percent: 100}}, Debounce: time.Minute})
+	defer a.Close()
+
+	now := time.Now()
+	a.Emit(event(now, "checkout", 0, true))
+	a.Emit(event(now.Add(time.Hour), "search", 0, true))
+
+	var owners []string
+	for i := range a.shards {
+		a.shards[i].mu.Lock()
+		for owner := range a.shards[i].owners {
+			owners = append(owners, owner)
+		}
+		a.shards[i].mu.Unlock()
+	}
+	assert.Equal(t, []string{"search"}, owners, "idle owners should be forgotten")
+}
+
+func TestAlerter_WebhookErrors(t *testing.T) {
+	a := NewAlerter(nil, Options{Thresholds: []rate.AlertThreshold{{Percent: 10}}, Webhook: "http://127.0.0.1:1"})
+	a.Emit(event(time.Now(), "checkout", 5, true))
+	require.NoError(t, a.Close())
+
+	assert.Equal(t, uint64(1), a.Dropped())
+}
+
+func TestOptions_Validate(t *testing.T) {
+	rules, err := rate.CompileRules([]rate.Rule{
+		{Owner: "acme", Alerts: []rate.AlertThreshold{{Percent: 50, Webhook: "http://localhost/acme"}}},
+		{Owner: "checkout", Alerts: []rate.AlertThreshold{{Percent: 80}}},
+	})
+	require.NoError(t, err)
+
+	assert.NoError(t, Options{Thresholds: []rate.AlertThreshold{{Percent: 90}}, Webhook: "http://localhost/alerts"}.Validate(rules))
+	assert.NoError(t, Options{}.Validate(rules[:1]))
+	assert.Error(t, Options{}.Validate(rules), "a rule without webhook")
+	assert.Error(t, Options{Thresholds: []rate.AlertThreshold{{Percent: 90}}}.Validate(nil), "a default threshold without webhook")
+}
@@ -0,0 +1,379 @@
+// Package audit emits the rate limit decisions as events to pluggable outputs, like JSON lines files or webhooks.
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/smoya/ratio/pkg/rate"
+)
+
+// Decisions reported by an Event.
+const (
+	DecisionOK        = "OK"
+	DecisionOverLimit = "OVER_LIMIT"
+	DecisionDenied    = "DENIED"
+)
+
+// Reasons of the Event of a hit decided by the access lists instead of the limits.
+const (
+	ReasonAllowed = "ALLOWED"
+	ReasonDenied  = "DENIED"
+)
+
+// Reasons of the Event of a hit rejected by something else than the limits.
+const (
+	ReasonPeriod = "PERIOD"
+	ReasonBanned = "BANNED"
+)
+
+// Limit is the limit of an Event.
+type Limit struct {
+	Quantity int   `json:"quantity"`
+	WindowMs int64 `json:"window_ms"`
+}
+
+// Period is the usage of the calendar period limit of an Event.
+type Period struct {
+	Period   string `json:"period"`
+	Quantity int    `json:"quantity"`
+	// Count is the number of hits counted in the period, the current one excluded.
+	Count int       `json:"count"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Event is a rate limit decision.
+type Event struct {
+	Time        time.Time         `json:"time"`
+	Owner       string            `json:"owner"`
+	Resource    string            `json:"resource"`
+	Descriptors []rate.Descriptor `json:"descriptors,omitempty"`
+	Decision    string            `json:"decision"`
+	Limit       Limit             `json:"limit"`
+	// Count is the number of hits found in the window of the limit, the current one excluded.
+	Count int    `json:"count"`
+	Level string `json:"level"`
+	// Reason tells the hits in the allow (ALLOWED) or deny (DENIED) lists, which are not checked against any limit,
+	// and the ones rejected by a period limit (PERIOD) or a ban (BANNED) instead of Limit.
+	Reason string `json:"reason,omitempty"`
+	// Period is the usage of the period limit with less remaining hits, or the one exceeded, if any.
+	Period *Period `json:"period,omitempty"`
+	// BannedUntil is when the ban of a BANNED hit ends.
+	BannedUntil *time.Time `json:"banned_until,omitempty"`
+}
+
+// NewEvent creates the Event of a decision made at t.
+func NewEvent(t time.Time, owner, resource string, descriptors []rate.Descriptor, d rate.Decision) Event {
+	e := Event{
+		Time:        t,
+		Owner:       owner,
+		Resource:    resource,
+		Descriptors: descriptors,
+		Decision:    DecisionOverLimit,
+		Limit:       Limit{Quantity: d.Limit.Quantity, WindowMs: int64(d.Limit.Unit.Duration() / time.Millisecond)},
+		Count:       d.Hits,
+		Level:       d.Level.String(),
+	}
+	if d.Allowed {
+		e.Decision = DecisionOK
+	}
+
+	if d.Period != nil {
+		e.Period = &Period{
+			Period:   d.Period.Limit.Period.String(),
+			Quantity: d.Period.Limit.Quantity,
+			Count:    d.Period.Hits,
+			Start:    d.Period.Start,
+			End:      d.Period.End,
+		}
+	}
+
+	switch {
+	case d.Allowed:
+	case !d.BannedUntil.IsZero():
+		until := d.BannedUntil
+		e.Reason, e.BannedUntil = ReasonBanned, &until
+	case d.Period != nil && d.Period.Exceeded():
+		e.Reason = ReasonPeriod
+	}
+
+	return e
+}
+
+// NewListEvent creates the Event of a hit in the allow list, or in the deny list if not allowed, decided at t.
+func NewListEvent(t time.Time, owner, resource string, descriptors []rate.Descriptor, allowed bool) Event {
+	e := Event{
+		Time:        t,
+		Owner:       owner,
+		Resource:    resource,
+		Descriptors: descriptors,
+		Decision:    DecisionDenied,
+		Reason:      ReasonDenied,
+	}
+	if allowed {
+		e.Decision, e.Reason = DecisionOK, ReasonAllowed
+	}
+
+	return e
+}
+
+// Sink receives the events of the decisions. Emit should not block the caller.
+type Sink interface {
+	Emit(e Event)
+}
+
+// Output writes batches of events somewhere.
+type Output interface {
+	io.Closer
+	Write(events []Event) error
+}
+
+// NewOutput creates an Output from its string representation:
+//
+//   - stdout: JSON lines to the standard output.
+//   - file:///var/log/ratio/audit.jsonl: JSON lines appended to a file.
+//   - http://localhost:9000/events: A webhook receiving a POST with a JSON array of events per batch.
+func NewOutput(s string) (Output, error) {
+	if s == "stdout" {
+		return NewJSONLinesOutput(nopCloser{os.Stdout}), nil
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid audit output %s: %s", s, err.Error())
+	}
+
+	switch u.Scheme {
+	case "file":
+		f, err := os.OpenFile(u.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewJSONLinesOutput(f), nil
+	case "http", "https":
+		return NewWebhookOutput(s, &http.Client{Timeout: 5 * time.Second}), nil
+	}
+
+	return nil, fmt.Errorf("invalid audit output %s: use stdout, file:// or http(s)://", s)
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+type jsonLinesOutput struct {
+	w io.WriteCloser
+	e *json.Encoder
+}
+
+// NewJSONLinesOutput creates an Output writing every event as a JSON document in its own line.
+func NewJSONLinesOutput(w io.WriteCloser) Output {
+	return jsonLinesOutput{w: w, e: json.NewEncoder(w)}
+}
+
+func (o jsonLinesOutput) Write(events []Event) error {
+	for _, e := range events {
+		if err := o.e.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (o jsonLinesOutput) Close() error {
+	return o.w.Close()
+}
+
+type webhookOutput struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookOutput creates an Output sending every batch of events as a JSON array in the body of a POST to url.
+func NewWebhookOutput(url string, client *http.Client) Output {
+	return webhookOutput{url: url, client: client}
+}
+
+func (o webhookOutput) Write(events []Event) error {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+
+	resp, err := o.client.Post(o.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s answered %s", o.url, resp.Status)
+	}
+
+	return nil
+}
+
+func (o webhookOutput) Close() error {
+	return nil
+}
+
+// Default Options.
+const (
+	DefaultQueueSize     = 10000
+	DefaultBatchSize     = 100
+	DefaultFlushInterval = time.Second
+)
+
+// Options configures a BufferedSink.
+type Options struct {
+	// OKSampleRate is the ratio of OK decisions emitted, between 0 (none) and 1 (all). OVER_LIMIT and DENIED
+	// decisions are always emitted.
+	OKSampleRate float64
+	// QueueSize is the max number of events pending to be written. Events are dropped when the queue is full.
+	QueueSize int
+	// BatchSize is the max number of events written at once.
+	BatchSize int
+	// FlushInterval is the max time an event waits for its batch to be full.
+	FlushInterval time.Duration
+}
+
+// BufferedSink is a Sink queuing the events and writing them to an Output in batches, in the background, so callers
+// never wait. It is safe for concurrent use.
+type BufferedSink struct {
+	out Output
+	o   Options
+
+	queue   chan Event
+	dropped uint64
+
+	mu     sync.RWMutex
+	closed bool
+	done   chan struct{}
+}
+
+// NewBufferedSink creates a BufferedSink writing to out. Close writes the pending events and closes out.
+func NewBufferedSink(out Output, o Options) *BufferedSink {
+	if o.QueueSize <= 0 {
+		o.QueueSize = DefaultQueueSize
+	}
+
+	if o.BatchSize <= 0 {
+		o.BatchSize = DefaultBatchSize
+	}
+
+	if o.FlushInterval <= 0 {
+		o.FlushInterval = DefaultFlushInterval
+	}
+
+	s := &BufferedSink{
+		out:   out,
+		o:     o,
+		queue: make(chan Event, o.QueueSize),
+		done:  make(chan struct{}),
+	}
+
+	go s.work()
+
+	return s
+}
+
+// Emit queues the event, unless it is an OK decision left out by the sampling. The event is dropped if the queue is
+// full.
+func (s *BufferedSink) Emit(e Event) {
+	if e.Decision == DecisionOK && (s.o.OKSampleRate <= 0 || rand.Float64() >= s.o.OKSampleRate) {
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return
+	}
+
+	select {
+	case s.queue <- e:
+	default:
+		atomic.AddUint64(&s.dropped, 1)
+	}
+}
+
+// Dropped returns the number of events lost, either because the queue was full or because of write errors.
+func (s *BufferedSink) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Close stops accepting events, writes the pending ones and closes the Output.
+func (s *BufferedSink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.queue)
+	s.mu.Unlock()
+
+	<-s.done
+
+	if dropped := s.Dropped(); dropped > 0 {
+		log.Printf("%d audit events were dropped in total\n", dropped)
+	}
+
+	return s.out.Close()
+}
+
+func (s *BufferedSink) work() {
+	defer close(s.done)
+
+	t := time.NewTicker(s.o.FlushInterval)
+	defer t.Stop()
+
+	batch := make([]Event, 0, s.o.BatchSize)
+	for {
+		select {
+		case e, ok := <-s.queue:
+			if !ok {
+				s.write(batch)
+				return
+			}
+
+			batch = append(batch, e)
+			if len(batch) >= s.o.BatchSize {
+				s.write(batch)
+				batch = batch[:0]
+			}
+		case <-t.C:
+			s.write(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (s *BufferedSink) write(batch []Event) {
+	if len(batch) == 0 {
+		return
+	}
+
+	if err := s.out.Write(batch); err != nil {
+		atomic.AddUint64(&s.dropped, uint64(len(batch)))
+		log.Printf("error writing %d audit events: %s\n", len(batch), err.Error())
+	}
+}
@@ -0,0 +1,281 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smoya/ratio/pkg/rate"
+)
+
+// memoryOutput keeps the events written, failing if err is set. Writes wait for release, if set.
+type memoryOutput struct {
+	mu      sync.Mutex
+	batches [][]Event
+	err     error
+	release chan struct{}
+	closed  bool
+}
+
+func (o *memoryOutput) Write(events []Event) error {
+	if o.release != nil {
+		<-o.release
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.batches = append(o.batches, append([]Event{}, events...))
+	return o.err
+}
+
+func (o *memoryOutput) Close() error {
+	o.closed = true
+	return nil
+}
+
+func (o *memoryOutput) events() []Event {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var events []Event
+	for _, b := range o.batches {
+		events = append(events, b...)
+	}
+
+	return events
+}
+
+func event(owner string, allowed bool) Event {
+	return NewEvent(time.Now(), owner, "/pay", nil, rate.Decision{Allowed: allowed, Limit: rate.NewLimit(rate.PerMinute, 10)})
+}
+
+func TestNewEvent(t *testing.T) {
+	now := time.Now()
+	e := NewEvent(now, "checkout", "/pay", []rate.Descriptor{{Key: "customer", Value: "c1"}}, rate.Decision{
+		Limit: rate.NewLimit(rate.PerMinute, 10),
+		Hits:  10,
+		Level: rate.LevelOwner,
+	})
+
+	assert.Equal(t, Event{
+		Time:        now,
+		Owner:       "checkout",
+		Resource:    "/pay",
+		Descriptors: []rate.Descriptor{{Key: "customer", Value: "c1"}},
+		Decision:    DecisionOverLimit,
+		Limit:       Limit{Quantity: 10, WindowMs: 60000},
+		Count:       10,
+		Level:       "owner",
+	}, e)
+}
+
+func TestNewEvent_Reasons(t *testing.T) {
+	now := time.Now()
+	day := rate.Period{Unit: rate.Daily, Location: time.UTC}
+	start, end := day.Bounds(now)
+	period := &rate.PeriodUsage{Limit: rate.PeriodLimit{Period: day, Quantity: 100}, Hits: 100, Start: start, End: end}
+
+	e := NewEvent(now, "checkout", "/pay", nil, rate.Decision{Period: period})
+	assert.Equal(t, DecisionOverLimit, e.Decision)
+	assert.Equal(t, ReasonPeriod, e.Reason)
+	assert.Equal(t, &Period{Period: "day", Quantity: 100, Count: 100, Start: start, End: end}, e.Period)
+	assert.Nil(t, e.BannedUntil)
+
+	period.Hits = 10
+	e = NewEvent(now, "checkout", "/pay", nil, rate.Decision{Limit: rate.NewLimit(rate.PerMinute, 10), Hits: 10, Period: period})
+	assert.Empty(t, e.Reason, "rejected by the limit")
+	assert.Equal(t, 10, e.Period.Count)
+
+	until := now.Add(time.Hour)
+	e = NewEvent(now, "abuser", "/pay", nil, rate.Decision{BannedUntil: until})
+	assert.Equal(t, DecisionOverLimit, e.Decision)
+	assert.Equal(t, ReasonBanned, e.Reason)
+	assert.Equal(t, &until, e.BannedUntil)
+	assert.Nil(t, e.Period)
+}
+
+func TestNewListEvent(t *testing.T) {
+	now := time.Now()
+	assert.Equal(t, Event{
+		Time:     now,
+		Owner:    "scraper",
+		Resource: "/pay",
+		Decision: DecisionDenied,
+		Reason:   ReasonDenied,
+	}, NewListEvent(now, "scraper", "/pay", nil, false))
+
+	assert.Equal(t, Event{
+		Time:     now,
+		Owner:    "health-checker",
+		Resource: "/pay",
+		Decision: DecisionOK,
+		Reason:   ReasonAllowed,
+	}, NewListEvent(now, "health-checker", "/pay", nil, true))
+}
+
+func TestBufferedSink_Sampling(t *testing.T) {
+	cases := []struct {
+		rate float64
+		ok   int
+	}{
+		{rate: 0, ok: 0},
+		{rate: 1, ok: 100},
+	}
+
+	for _, c := range cases {
+		out := &memoryOutput{}
+		s := NewBufferedSink(out, Options{OKSampleRate: c.rate})
+		for i := 0; i < 100; i++ {
+			s.Emit(event("checkout", true))
+			s.Emit(event("checkout", false))
+		}
+		require.NoError(t, s.Close())
+
+		var ok, overLimit int
+		for _, e := range out.events() {
+			if e.Decision == DecisionOK {
+				ok++
+			} else {
+				overLimit++
+			}
+		}
+		assert.Equal(t, c.ok, ok)
+		assert.Equal(t, 100, overLimit, "OVER_LIMIT decisions should always be emitted")
+		assert.True(t, out.closed)
+	}
+}
+
+func TestBufferedSink_Batches(t *testing.T) {
+	out := &memoryOutput{}
+	s := NewBufferedSink(out, Options{BatchSize: 2, FlushInterval: time.Hour})
+
+	for i := 0; i < 5; i++ {
+		s.Emit(event("checkout", false))
+	}
+	require.NoError(t, s.Close())
+
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	require.Len(t, out.batches, 3)
+	assert.Len(t, out.batches[2], 1, "the pending events should be written on close")
+}
+
+func TestBufferedSink_FlushInterval(t *testing.T) {
+	out := &memoryOutput{}
+	s := NewBufferedSink(out, Options{FlushInterval: 10 * time.Millisecond})
+	defer s.Close()
+
+	s.Emit(event("checkout", false))
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, out.events(), 1, "events should be written without waiting for the batch to be full")
+}
+
+func TestBufferedSink_DoesNotBlock(t *testing.T) {
+	out := &memoryOutput{release: make(chan struct{})}
+	s := NewBufferedSink(out, Options{QueueSize: 1, BatchSize: 1})
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			s.Emit(event("checkout", false))
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Emit should not block when the queue is full")
+	}
+
+	close(out.release)
+	require.NoError(t, s.Close())
+	assert.Equal(t, uint64(10), s.Dropped()+uint64(len(out.events())))
+	assert.True(t, s.Dropped() > 0)
+
+	s.Emit(event("checkout", false))
+	assert.NoError(t, s.Close(), "closing twice should not fail")
+}
+
+func TestBufferedSink_WriteErrors(t *testing.T) {
+	out := &memoryOutput{err: errors.New("whatever error")}
+	s := NewBufferedSink(out, Options{})
+	s.Emit(event("checkout", false))
+	s.Emit(event("checkout", false))
+	require.NoError(t, s.Close())
+
+	assert.Equal(t, uint64(2), s.Dropped())
+}
+
+func TestNewOutput_File(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.jsonl")
+	out, err := NewOutput("file://" + path)
+	require.NoError(t, err)
+
+	require.NoError(t, out.Write([]Event{event("checkout", false), event("search", true)}))
+	require.NoError(t, out.Close())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var owners []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+		owners = append(owners, e.Owner)
+	}
+	assert.Equal(t, []string{"checkout", "search"}, owners)
+}
+
+func TestNewOutput_Webhook(t *testing.T) {
+	var received []Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+
+		if received[0].Owner == "fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	out, err := NewOutput(srv.URL)
+	require.NoError(t, err)
+
+	assert.NoError(t, out.Write([]Event{event("checkout", false), event("search", false)}))
+	require.Len(t, received, 2)
+	assert.Equal(t, "search", received[1].Owner)
+
+	assert.Error(t, out.Write([]Event{event("fail", false)}))
+}
+
+func TestNewOutput_Invalid(t *testing.T) {
+	out, err := NewOutput("stdout")
+	assert.NoError(t, err)
+	assert.NotNil(t, out)
+
+	_, err = NewOutput("kafka://localhost:9092")
+	assert.Error(t, err)
+
+	_, err = NewOutput("file:///nonexistent/dir/audit.jsonl")
+	assert.Error(t, err)
+}
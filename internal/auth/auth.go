@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+
+	"github.com/smoya/ratio/internal/server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ErrNoCredentials is returned by an Authenticator when the request carries no credentials it understands.
+var ErrNoCredentials = errors.New("no credentials")
+
+// Authenticator identifies the caller of a request.
+type Authenticator interface {
+	// Authenticate returns the identity of the caller. ErrNoCredentials is returned if the request does not carry
+	// credentials for this Authenticator; any other error means the credentials are invalid.
+	Authenticate(ctx context.Context) (string, error)
+}
+
+// AuthenticatorFunc is an adapter to allow the use of ordinary functions as Authenticator.
+type AuthenticatorFunc func(ctx context.Context) (string, error)
+
+// Authenticate calls f(ctx).
+func (f AuthenticatorFunc) Authenticate(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// Chain returns an Authenticator trying each of the given ones in order, until one finds credentials.
+func Chain(authenticators ...Authenticator) Authenticator {
+	return AuthenticatorFunc(func(ctx context.Context) (string, error) {
+		for _, a := range authenticators {
+			caller, err := a.Authenticate(ctx)
+			if err == ErrNoCredentials {
+				continue
+			}
+
+			return caller, err
+		}
+
+		return "", ErrNoCredentials
+	})
+}
+
+// APIKeyAuthenticator authenticates callers by a static API key sent in the x-api-key metadata.
+// keys maps each API key to its caller.
+func APIKeyAuthenticator(keys map[string]string) Authenticator {
+	return AuthenticatorFunc(func(ctx context.Context) (string, error) {
+		key := header(ctx, "x-api-key")
+		if key == "" {
+			return "", ErrNoCredentials
+		}
+
+		caller, ok := keys[key]
+		if !ok {
+			return "", errors.New("invalid API key")
+		}
+
+		return caller, nil
+	})
+}
+
+// PeerCertAuthenticator authenticates callers by the identity of their client certificate (mutual TLS).
+func PeerCertAuthenticator() Authenticator {
+	return AuthenticatorFunc(func(ctx context.Context) (string, error) {
+		id, ok := server.PeerIdentity(ctx)
+		if !ok {
+			return "", ErrNoCredentials
+		}
+
+		return id, nil
+	})
+}
+
+// Policy restricts the owners each caller may act for. It maps each caller to a list of owner patterns, using the
+// syntax of path.Match. Example: {"checkout": ["checkout", "checkout-*"], "platform": ["*"]}.
+type Policy map[string][]string
+
+// Allowed returns whether the caller may act for the owner.
+func (p Policy) Allowed(caller, owner string) bool {
+	for _, pattern := range p[caller] {
+		if ok, _ := path.Match(pattern, owner); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ownerRequest is a request acting for an owner, like ratio.RateLimitRequest or ratio.AcquireRequest.
+type ownerRequest interface {
+	GetOwner() string
+}
+
+// AdminMethods is the prefix of the methods of the AdminService, which only admins can call.
+const AdminMethods = "/AdminService/"
+
+// UnaryServerInterceptor authenticates the caller of every request, rejecting it with Unauthenticated when the
+// credentials are missing or invalid, and authorizes the owner of the requests acting for one against the policy, rejecting them
+// with PermissionDenied before reaching the limiter. Methods whose full name starts with any of the exempt prefixes
+// are not authenticated, nor are the requests sent by the peers of the cluster (see server.ClusterSecretInterceptor).
+// The methods of the AdminService are only allowed to the admins, whatever the owners the policy allows them, and even
+// to peers.
+func UnaryServerInterceptor(a Authenticator, p Policy, admins []string, exempt ...string) grpc.UnaryServerInterceptor {
+	isAdmin := make(map[string]bool, len(admins))
+	for _, admin := range admins {
+		isAdmin[admin] = true
+	}
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if strings.HasPrefix(info.FullMethod, AdminMethods) {
+			caller, err := a.Authenticate(ctx)
+			if err != nil {
+				return nil, status.Error(codes.Unauthenticated, err.Error())
+			}
+
+			if !isAdmin[caller] {
+				return nil, status.Errorf(codes.PermissionDenied, "%s is not an admin", caller)
+			}
+
+			return handler(ctx, req)
+		}
+
+		if server.FromPeer(ctx) {
+			return handler(ctx, req)
+		}
+
+		for _, prefix := range exempt {
+			if strings.HasPrefix(info.FullMethod, prefix) {
+				return handler(ctx, req)
+			}
+		}
+
+		caller, err := a.Authenticate(ctx)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+
+		if r, ok := req.(ownerRequest); ok && !p.Allowed(caller, r.GetOwner()) {
+			return nil, status.Errorf(codes.PermissionDenied, "%s is not allowed to act for owner %s", caller, r.GetOwner())
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func header(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
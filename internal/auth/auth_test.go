@@ -0,0 +1,299 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/smoya/ratio/internal/server"
+	"github.com/smoya/ratio/pkg/cluster"
+	"github.com/smoya/ratio/pkg/rate"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	ratio "github.com/smoya/ratio/api/proto"
+)
+
+func TestAPIKeyAuthenticator(t *testing.T) {
+	a := APIKeyAuthenticator(map[string]string{"s3cr3t": "checkout"})
+
+	caller, err := a.Authenticate(withMetadata("x-api-key", "s3cr3t"))
+	assert.NoError(t, err)
+	assert.Equal(t, "checkout", caller)
+
+	_, err = a.Authenticate(withMetadata("x-api-key", "whatever"))
+	assert.Error(t, err)
+	assert.NotEqual(t, ErrNoCredentials, err)
+
+	_, err = a.Authenticate(context.Background())
+	assert.Equal(t, ErrNoCredentials, err)
+}
+
+func TestChain(t *testing.T) {
+	noCredentials := AuthenticatorFunc(func(context.Context) (string, error) { return "", ErrNoCredentials })
+	invalid := AuthenticatorFunc(func(context.Context) (string, error) { return "", errors.New("invalid") })
+	valid := AuthenticatorFunc(func(context.Context) (string, error) { return "checkout", nil })
+
+	caller, err := Chain(noCredentials, valid, invalid).Authenticate(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "checkout", caller)
+
+	_, err = Chain(noCredentials, invalid, valid).Authenticate(context.Background())
+	assert.EqualError(t, err, "invalid", "invalid credentials should not fall through")
+
+	_, err = Chain(noCredentials).Authenticate(context.Background())
+	assert.Equal(t, ErrNoCredentials, err)
+}
+
+func TestPolicy_Allowed(t *testing.T) {
+	p := Policy{
+		"checkout": {"checkout", "checkout-*"},
+		"platform": {"*"},
+	}
+
+	assert.True(t, p.Allowed("checkout", "checkout"))
+	assert.True(t, p.Allowed("checkout", "checkout-worker"))
+	assert.False(t, p.Allowed("checkout", "payments"))
+	assert.True(t, p.Allowed("platform", "payments"))
+	assert.False(t, p.Allowed("unknown", "checkout"))
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor(
+		APIKeyAuthenticator(map[string]string{"s3cr3t": "checkout", "r00t": "sre"}),
+		Policy{"checkout": {"checkout"}},
+		[]string{"sre"},
+		"/grpc.health.v1.Health/",
+	)
+
+	handler := func(context.Context, interface{}) (interface{}, error) {
+		return &ratio.RateLimitResponse{Code: ratio.RateLimitResponse_OK}, nil
+	}
+	rateLimit := &grpc.UnaryServerInfo{FullMethod: "/RateLimitService/RateLimit"}
+
+	cases := []struct {
+		desc  string
+		ctx   context.Context
+		info  *grpc.UnaryServerInfo
+		owner string
+		code  codes.Code
+	}{
+		{desc: "Allowed owner", ctx: withMetadata("x-api-key", "s3cr3t"), info: rateLimit, owner: "checkout", code: codes.OK},
+		{desc: "Another owner", ctx: withMetadata("x-api-key", "s3cr3t"), info: rateLimit, owner: "payments", code: codes.PermissionDenied},
+		{desc: "Invalid credentials", ctx: withMetadata("x-api-key", "whatever"), info: rateLimit, owner: "checkout", code: codes.Unauthenticated},
+		{desc: "No credentials", ctx: context.Background(), info: rateLimit, owner: "checkout", code: codes.Unauthenticated},
+		{desc: "Exempt method", ctx: context.Background(), info: &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}, code: codes.OK},
+	}
+
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			_, err := interceptor(c.ctx, &ratio.RateLimitRequest{Owner: c.owner}, c.info, handler)
+			assert.Equal(t, c.code, status.Code(err))
+		})
+	}
+
+	peer := server.ClusterSecretInterceptor("cluster-s3cr3t")
+	ctx := withMetadata(cluster.SecretHeader, "cluster-s3cr3t")
+	_, err := peer(ctx, &ratio.RateLimitRequest{Owner: "payments"}, rateLimit, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return interceptor(ctx, req, rateLimit, handler)
+	})
+	assert.NoError(t, err, "requests of the peers were already authenticated")
+
+	acquire := &grpc.UnaryServerInfo{FullMethod: "/ConcurrencyService/Acquire"}
+	_, err = interceptor(withMetadata("x-api-key", "s3cr3t"), &ratio.AcquireRequest{Owner: "payments"}, acquire, handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Owners can not lift their own deny entries nor bans, nor read the access lists.
+	admin := []struct {
+		method string
+		req    interface{}
+	}{
+		{method: "RemoveAccessEntry", req: &ratio.AccessEntry{List: ratio.AccessEntry_DENY, Owner: "checkout"}},
+		{method: "AddAccessEntry", req: &ratio.AccessEntry{List: ratio.AccessEntry_ALLOW, Owner: "checkout"}},
+		{method: "ListAccessEntries", req: &ratio.ListAccessEntriesRequest{}},
+		{method: "ExportUsage", req: &ratio.ExportUsageRequest{Owner: "checkout"}},
+		{method: "Unban", req: &ratio.UnbanRequest{Owner: "checkout", Resource: "/pay"}},
+	}
+	for _, c := range admin {
+		t.Run(c.method, func(t *testing.T) {
+			info := &grpc.UnaryServerInfo{FullMethod: AdminMethods + c.method}
+
+			_, err := interceptor(withMetadata("x-api-key", "s3cr3t"), c.req, info, handler)
+			assert.Equal(t, codes.PermissionDenied, status.Code(err), "owner credentials")
+
+			_, err = peer(withMetadata(cluster.SecretHeader, "cluster-s3cr3t"), c.req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return interceptor(ctx, req, info, handler)
+			})
+			assert.Equal(t, codes.Unauthenticated, status.Code(err), "peers are not admins")
+
+			_, err = interceptor(withMetadata("x-api-key", "r00t"), c.req, info, handler)
+			assert.NoError(t, err, "admin credentials")
+		})
+	}
+}
+
+type recorderServer struct {
+	received []string
+}
+
+func (s *recorderServer) RateLimit(_ context.Context, r *ratio.RateLimitRequest) (*ratio.RateLimitResponse, error) {
+	s.received = append(s.received, r.Owner)
+	return &ratio.RateLimitResponse{Code: ratio.RateLimitResponse_OK}, nil
+}
+
+func TestUnaryServerInterceptor_MTLSForwarding(t *testing.T) {
+	caKey := mustECKey(t)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ratio-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDer, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	assert.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDer)
+	assert.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	cert := func(cn string) tls.Certificate {
+		key := mustECKey(t)
+		der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+			SerialNumber: big.NewInt(time.Now().UnixNano()),
+			Subject:      pkix.Name{CommonName: cn},
+			DNSNames:     []string{"localhost"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		}, ca, &key.PublicKey, caKey)
+		assert.NoError(t, err)
+
+		return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	}
+	dial := func(addr string, c tls.Certificate, opts ...grpc.DialOption) *grpc.ClientConn {
+		creds := credentials.NewTLS(&tls.Config{Certificates: []tls.Certificate{c}, RootCAs: pool, ServerName: "localhost"})
+		conn, err := grpc.Dial(addr, append(opts, grpc.WithTransportCredentials(creds))...)
+		assert.NoError(t, err)
+
+		return conn
+	}
+
+	// Each instance has its own identity, none of them allowed to act for the owners of the callers.
+	var addrs []string
+	var listeners []net.Listener
+	for i := 0; i < 2; i++ {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		listeners = append(listeners, l)
+		addrs = append(addrs, l.Addr().String())
+	}
+
+	var clusters []*cluster.Cluster
+	var recorders []*recorderServer
+	for i, name := range []string{"ratio-a", "ratio-b"} {
+		peerCert := cert(name)
+		creds := credentials.NewTLS(&tls.Config{Certificates: []tls.Certificate{peerCert}, RootCAs: pool, ServerName: "localhost"})
+		c := cluster.New(addrs[i], cluster.StaticDiscoverer(addrs...),
+			grpc.WithTransportCredentials(creds),
+			grpc.WithPerRPCCredentials(cluster.SecretCredentials("cluster-s3cr3t")),
+		)
+		defer c.Close()
+		assert.NoError(t, c.Refresh())
+
+		srv := grpc.NewServer(
+			grpc.Creds(credentials.NewTLS(&tls.Config{
+				Certificates: []tls.Certificate{peerCert},
+				ClientAuth:   tls.RequireAndVerifyClientCert,
+				ClientCAs:    pool,
+			})),
+			grpc.UnaryInterceptor(server.ChainUnaryInterceptors(
+				server.ClusterSecretInterceptor("cluster-s3cr3t"),
+				server.OwnerFromPeerIdentityInterceptor(),
+				UnaryServerInterceptor(PeerCertAuthenticator(), Policy{"checkout": {"checkout"}}, nil),
+			)),
+		)
+		recorder := &recorderServer{}
+		ratio.RegisterRateLimitServiceServer(srv, server.NewClusterGRPC(recorder, c))
+		go func(l net.Listener) { _ = srv.Serve(l) }(listeners[i])
+		defer srv.Stop()
+
+		clusters = append(clusters, c)
+		recorders = append(recorders, recorder)
+	}
+
+	// A resource of the caller owned by the second instance, so calls to the first one are forwarded.
+	var resource string
+	for i := 0; resource == ""; i++ {
+		if peer, _ := clusters[0].Owner(rate.Key("checkout", fmt.Sprintf("/v1/%d", i))); peer == addrs[1] {
+			resource = fmt.Sprintf("/v1/%d", i)
+		}
+	}
+
+	client := cert("checkout")
+	conn := dial(addrs[0], client)
+	defer conn.Close()
+	_, err = ratio.NewRateLimitServiceClient(conn).RateLimit(context.Background(), &ratio.RateLimitRequest{Owner: "checkout", Resource: resource})
+	assert.NoError(t, err)
+	assert.Empty(t, recorders[0].received, "forwarded requests should not fall back to local counting")
+	assert.Equal(t, []string{"checkout"}, recorders[1].received)
+
+	// Callers can not pass for peers by marking their requests as forwarded, nor with the identity of an instance.
+	conn = dial(addrs[1], client)
+	defer conn.Close()
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "ratio-forwarded-by", addrs[0])
+	_, err = ratio.NewRateLimitServiceClient(conn).RateLimit(ctx, &ratio.RateLimitRequest{Owner: "payments", Resource: resource})
+	assert.NoError(t, err)
+
+	conn = dial(addrs[1], cert("ratio-a"))
+	defer conn.Close()
+	_, err = ratio.NewRateLimitServiceClient(conn).RateLimit(context.Background(), &ratio.RateLimitRequest{Owner: "checkout", Resource: resource})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "the owner authorized is the one of the certificate")
+	assert.Equal(t, []string{"checkout", "checkout"}, recorders[1].received, "the spoofed owner should be replaced")
+}
+
+func TestLoadConfig(t *testing.T) {
+	f, err := ioutil.TempFile("", "ratio-auth")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(`{"api_keys": {"s3cr3t": "checkout"}, "policy": {"checkout": ["checkout"]}, "admins": ["sre"]}`)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	c, err := LoadConfig(f.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, Policy{"checkout": {"checkout"}}, c.Policy)
+	assert.Equal(t, []string{"sre"}, c.Admins)
+
+	a, err := c.Authenticator()
+	assert.NoError(t, err)
+
+	caller, err := a.Authenticate(withMetadata("x-api-key", "s3cr3t"))
+	assert.NoError(t, err)
+	assert.Equal(t, "checkout", caller)
+
+	_, err = Config{}.Authenticator()
+	assert.Error(t, err)
+}
+
+func withMetadata(kv ...string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(kv...))
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+)
+
+// Config configures the authentication of callers and their authorization policy.
+//
+// Example:
+//
+//	{
+//	  "api_keys": {"s3cr3t": "checkout"},
+//	  "jwt": {"jwks_file": "/etc/ratio/jwks.json", "issuer": "https://auth.example.com", "audience": "ratio"},
+//	  "mtls": true,
+//	  "policy": {"checkout": ["checkout", "checkout-*"], "platform": ["*"]},
+//	  "admins": ["sre"]
+//	}
+type Config struct {
+	APIKeys map[string]string `json:"api_keys"`
+	JWT     *JWTConfig        `json:"jwt"`
+	MTLS    bool              `json:"mtls"`
+	Policy  Policy            `json:"policy"`
+	// Admins are the callers allowed to call the AdminService.
+	Admins []string `json:"admins"`
+}
+
+// JWTConfig configures the JWT authentication.
+type JWTConfig struct {
+	JWKSFile string `json:"jwks_file"`
+	Issuer   string `json:"issuer"`
+	Audience string `json:"audience"`
+}
+
+// LoadConfig loads a Config from a JSON file.
+func LoadConfig(file string) (Config, error) {
+	var c Config
+
+	raw, err := ioutil.ReadFile(file)
+	if err != nil {
+		return c, err
+	}
+
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return c, err
+	}
+
+	return c, nil
+}
+
+// Authenticator builds the Authenticator accepting all the configured credentials. Client certificates are tried
+// first, then API keys and JWTs.
+func (c Config) Authenticator() (Authenticator, error) {
+	var authenticators []Authenticator
+	if c.MTLS {
+		authenticators = append(authenticators, PeerCertAuthenticator())
+	}
+
+	if len(c.APIKeys) > 0 {
+		authenticators = append(authenticators, APIKeyAuthenticator(c.APIKeys))
+	}
+
+	if c.JWT != nil {
+		jwks, err := LoadJWKS(c.JWT.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+
+		authenticators = append(authenticators, JWTAuthenticator(jwks, c.JWT.Issuer, c.JWT.Audience))
+	}
+
+	if len(authenticators) == 0 {
+		return nil, errors.New("no authentication method configured")
+	}
+
+	return Chain(authenticators...), nil
+}
@@ -0,0 +1,236 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// JWKS is a set of public keys, indexed by key ID, used for verifying JWTs.
+type JWKS map[string]crypto.PublicKey
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// LoadJWKS loads a JSON Web Key Set from a file. Only RSA and EC P-256 keys are supported; other keys are ignored.
+func LoadJWKS(file string) (JWKS, error) {
+	raw, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseJWKS(raw)
+}
+
+// ParseJWKS parses a JSON Web Key Set. Only RSA and EC P-256 keys are supported; other keys are ignored.
+func ParseJWKS(raw []byte) (JWKS, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil, err
+	}
+
+	jwks := make(JWKS, len(set.Keys))
+	for _, k := range set.Keys {
+		switch {
+		case k.Kty == "RSA":
+			n, err := decodeInt(k.N)
+			if err != nil {
+				return nil, fmt.Errorf("invalid key %s: %s", k.Kid, err.Error())
+			}
+
+			e, err := decodeInt(k.E)
+			if err != nil {
+				return nil, fmt.Errorf("invalid key %s: %s", k.Kid, err.Error())
+			}
+
+			jwks[k.Kid] = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		case k.Kty == "EC" && k.Crv == "P-256":
+			x, err := decodeInt(k.X)
+			if err != nil {
+				return nil, fmt.Errorf("invalid key %s: %s", k.Kid, err.Error())
+			}
+
+			y, err := decodeInt(k.Y)
+			if err != nil {
+				return nil, fmt.Errorf("invalid key %s: %s", k.Kid, err.Error())
+			}
+
+			jwks[k.Kid] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		}
+	}
+
+	return jwks, nil
+}
+
+// Claims are the registered claims of a JWT used by ratio.
+type Claims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt int64    `json:"exp"`
+	NotBefore int64    `json:"nbf"`
+}
+
+// audience can be either a single string or an array of strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+	*a = list
+
+	return nil
+}
+
+// VerifyJWT verifies the signature (RS256 or ES256) and the expiration of a JWT, returning its claims.
+func VerifyJWT(token string, jwks JWKS, now time.Time) (Claims, error) {
+	var claims Claims
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return claims, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJSON(parts[0], &header); err != nil {
+		return claims, fmt.Errorf("malformed token header: %s", err.Error())
+	}
+
+	key, ok := jwks[header.Kid]
+	if !ok {
+		return claims, fmt.Errorf("unknown key %q", header.Kid)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return claims, fmt.Errorf("malformed token signature: %s", err.Error())
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if header.Alg != "RS256" {
+			return claims, fmt.Errorf("unexpected algorithm %s for key %s", header.Alg, header.Kid)
+		}
+
+		if err := rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig); err != nil {
+			return claims, errors.New("invalid token signature")
+		}
+	case *ecdsa.PublicKey:
+		if header.Alg != "ES256" {
+			return claims, fmt.Errorf("unexpected algorithm %s for key %s", header.Alg, header.Kid)
+		}
+
+		if len(sig) != 64 {
+			return claims, errors.New("invalid token signature")
+		}
+
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(k, digest[:], r, s) {
+			return claims, errors.New("invalid token signature")
+		}
+	}
+
+	if err := decodeJSON(parts[1], &claims); err != nil {
+		return claims, fmt.Errorf("malformed token claims: %s", err.Error())
+	}
+
+	if claims.ExpiresAt == 0 || now.Unix() >= claims.ExpiresAt {
+		return claims, errors.New("token is expired")
+	}
+
+	if claims.NotBefore != 0 && now.Unix() < claims.NotBefore {
+		return claims, errors.New("token is not valid yet")
+	}
+
+	return claims, nil
+}
+
+// JWTAuthenticator authenticates callers by a JWT sent as bearer token in the authorization metadata, verified with
+// the given JWKS. The caller is the subject (sub) of the token. issuer and audience are checked only when not empty.
+func JWTAuthenticator(jwks JWKS, issuer, audience string) Authenticator {
+	return AuthenticatorFunc(func(ctx context.Context) (string, error) {
+		authorization := header(ctx, "authorization")
+		if !strings.HasPrefix(authorization, "Bearer ") {
+			return "", ErrNoCredentials
+		}
+
+		claims, err := VerifyJWT(strings.TrimPrefix(authorization, "Bearer "), jwks, time.Now())
+		if err != nil {
+			return "", err
+		}
+
+		if issuer != "" && claims.Issuer != issuer {
+			return "", fmt.Errorf("unexpected token issuer %s", claims.Issuer)
+		}
+
+		if audience != "" && !contains(claims.Audience, audience) {
+			return "", errors.New("token is not meant for ratio")
+		}
+
+		if claims.Subject == "" {
+			return "", errors.New("token has no subject")
+		}
+
+		return claims.Subject, nil
+	})
+}
+
+func decodeJSON(s string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(raw, v)
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(raw), nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
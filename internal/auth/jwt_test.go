@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyJWT(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	jwks, err := ParseJWKS([]byte(fmt.Sprintf(`{"keys": [
+		{"kty": "RSA", "kid": "rsa", "n": "%s", "e": "%s"},
+		{"kty": "EC", "kid": "ec", "crv": "P-256", "x": "%s", "y": "%s"},
+		{"kty": "oct", "kid": "ignored", "k": "whatever"}
+	]}`, encodeInt(rsaKey.N), encodeInt(big.NewInt(int64(rsaKey.E))), encodeInt(ecKey.X), encodeInt(ecKey.Y))))
+	assert.NoError(t, err)
+	assert.Len(t, jwks, 2)
+
+	now := time.Now()
+	valid := map[string]interface{}{"sub": "checkout", "aud": "ratio", "exp": now.Add(time.Hour).Unix()}
+
+	cases := []struct {
+		desc        string
+		token       string
+		shouldError bool
+	}{
+		{desc: "RS256", token: sign(t, "RS256", "rsa", rsaKey, valid)},
+		{desc: "ES256", token: sign(t, "ES256", "ec", ecKey, valid)},
+		{desc: "Unknown key", token: sign(t, "RS256", "whatever", rsaKey, valid), shouldError: true},
+		{desc: "Wrong algorithm", token: sign(t, "ES256", "rsa", ecKey, valid), shouldError: true},
+		{desc: "Wrong signature", token: sign(t, "ES256", "ec", mustECKey(t), valid), shouldError: true},
+		{desc: "Expired", token: sign(t, "RS256", "rsa", rsaKey, map[string]interface{}{"sub": "checkout", "exp": now.Add(-time.Second).Unix()}), shouldError: true},
+		{desc: "No expiration", token: sign(t, "RS256", "rsa", rsaKey, map[string]interface{}{"sub": "checkout"}), shouldError: true},
+		{desc: "Not valid yet", token: sign(t, "RS256", "rsa", rsaKey, map[string]interface{}{"sub": "checkout", "exp": now.Add(time.Hour).Unix(), "nbf": now.Add(time.Minute).Unix()}), shouldError: true},
+		{desc: "Malformed", token: "whatever", shouldError: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			claims, err := VerifyJWT(c.token, jwks, now)
+			if c.shouldError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, "checkout", claims.Subject)
+			assert.Equal(t, audience{"ratio"}, claims.Audience)
+		})
+	}
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	key := mustECKey(t)
+	jwks := JWKS{"ec": &key.PublicKey}
+	exp := time.Now().Add(time.Hour).Unix()
+
+	a := JWTAuthenticator(jwks, "https://auth.example.com", "ratio")
+
+	token := sign(t, "ES256", "ec", key, map[string]interface{}{"sub": "checkout", "iss": "https://auth.example.com", "aud": []string{"ratio", "other"}, "exp": exp})
+	caller, err := a.Authenticate(withMetadata("authorization", "Bearer "+token))
+	assert.NoError(t, err)
+	assert.Equal(t, "checkout", caller)
+
+	token = sign(t, "ES256", "ec", key, map[string]interface{}{"sub": "checkout", "iss": "https://auth.example.com", "aud": "other", "exp": exp})
+	_, err = a.Authenticate(withMetadata("authorization", "Bearer "+token))
+	assert.Error(t, err)
+
+	token = sign(t, "ES256", "ec", key, map[string]interface{}{"sub": "checkout", "iss": "https://evil.example.com", "aud": "ratio", "exp": exp})
+	_, err = a.Authenticate(withMetadata("authorization", "Bearer "+token))
+	assert.Error(t, err)
+
+	_, err = a.Authenticate(withMetadata("authorization", "Basic whatever"))
+	assert.Equal(t, ErrNoCredentials, err)
+}
+
+func sign(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]interface{}) string {
+	header, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	assert.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	assert.NoError(t, err)
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		assert.NoError(t, err)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		assert.NoError(t, err)
+		sig = make([]byte, 64)
+		rb, sb := r.Bytes(), s.Bytes()
+		copy(sig[32-len(rb):32], rb)
+		copy(sig[64-len(sb):], sb)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func mustECKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	return key
+}
+
+func encodeInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
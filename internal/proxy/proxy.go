@@ -0,0 +1,159 @@
+// Package proxy implements a reverse proxy enforcing ratio limits before traffic reaches the upstreams.
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/smoya/ratio/internal/server"
+	"github.com/smoya/ratio/pkg/middleware"
+	"github.com/smoya/ratio/pkg/rate"
+)
+
+// Config is the list of routes of the proxy.
+//
+// Example:
+//
+//	{
+//	  "routes": [
+//	    {
+//	      "prefix": "/v1/orders",
+//	      "upstream": "http://orders:8080",
+//	      "limit": "100/m",
+//	      "owner": {"header": "X-Service"},
+//	      "resource": {"path_templates": ["/v1/orders/{id}", "/v1/orders/{id}/pay"]}
+//	    }
+//	  ]
+//	}
+type Config struct {
+	Routes []Route `json:"routes"`
+}
+
+// Route proxies the requests whose path is Prefix, or starts with Prefix followed by "/", to Upstream.
+type Route struct {
+	Prefix   string `json:"prefix"`
+	Upstream string `json:"upstream"`
+	// Limit overrides the default limit for this route.
+	Limit    string `json:"limit"`
+	Owner    Rule   `json:"owner"`
+	Resource Rule   `json:"resource"`
+}
+
+// Rule describes how the owner or the resource are derived from a request. Only one field should be set.
+// When none is set, the owner is the remote IP and the resource is the path.
+type Rule struct {
+	Static        string   `json:"static"`
+	Header        string   `json:"header"`
+	RemoteIP      bool     `json:"remote_ip"`
+	PathTemplates []string `json:"path_templates"`
+	// Method prefixes the value with the HTTP method. e.g. "GET /v1/orders/{id}".
+	Method bool `json:"method"`
+}
+
+func (r Rule) extractor(fallback middleware.HTTPExtractor) middleware.HTTPExtractor {
+	e := fallback
+	switch {
+	case r.Static != "":
+		e = middleware.Static(r.Static)
+	case r.Header != "":
+		e = middleware.Header(r.Header)
+	case r.RemoteIP:
+		e = middleware.RemoteIP()
+	case len(r.PathTemplates) > 0:
+		e = middleware.PathTemplate(r.PathTemplates...)
+	}
+
+	if r.Method {
+		e = middleware.Join(" ", middleware.Method(), e)
+	}
+
+	return e
+}
+
+// LoadConfig loads a Config from a JSON file.
+func LoadConfig(file string) (Config, error) {
+	var c Config
+
+	raw, err := ioutil.ReadFile(file)
+	if err != nil {
+		return c, err
+	}
+
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return c, err
+	}
+
+	return c, nil
+}
+
+type route struct {
+	prefix  string
+	handler http.Handler
+}
+
+// matches tells whether the path is in the route, matching whole path segments only, so "/v1/orders" matches
+// "/v1/orders/1" but not "/v1/ordersX".
+func (r route) matches(path string) bool {
+	if !strings.HasPrefix(path, r.prefix) {
+		return false
+	}
+
+	return len(path) == len(r.prefix) || strings.HasSuffix(r.prefix, "/") || path[len(r.prefix)] == '/'
+}
+
+// New creates a reverse proxy handler. Each request is matched against the route with the longest prefix, checked
+// in-process with the limiter and, if allowed, proxied to the upstream of the route. Requests over the limit are
+// answered with 429 Too Many Requests and requests not matching any route with 404 Not Found.
+func New(c Config, defaultLimits rate.Limits, limiter rate.Limiter) (http.Handler, error) {
+	if len(c.Routes) == 0 {
+		return nil, errors.New("no routes configured")
+	}
+
+	routes := make([]route, 0, len(c.Routes))
+	for _, r := range c.Routes {
+		upstream, err := url.Parse(r.Upstream)
+		if err != nil || upstream.Scheme == "" || upstream.Host == "" {
+			return nil, fmt.Errorf("invalid upstream %q for route %s", r.Upstream, r.Prefix)
+		}
+
+		limits := defaultLimits
+		if r.Limit != "" {
+			limits, err = rate.ParseLimits(r.Limit)
+			if err != nil {
+				return nil, fmt.Errorf("invalid limit for route %s: %s", r.Prefix, err.Error())
+			}
+		}
+
+		check := middleware.HTTP(
+			server.NewLocalClient(server.NewGRPC(limits, limiter, nil, nil)),
+			r.Owner.extractor(middleware.RemoteIP()),
+			r.Resource.extractor(middleware.Path()),
+			middleware.Options{},
+		)
+
+		routes = append(routes, route{
+			prefix:  r.Prefix,
+			handler: check(httputil.NewSingleHostReverseProxy(upstream)),
+		})
+	}
+
+	sort.Slice(routes, func(i, j int) bool { return len(routes[i].prefix) > len(routes[j].prefix) })
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		for _, r := range routes {
+			if r.matches(req.URL.Path) {
+				r.handler.ServeHTTP(w, req)
+				return
+			}
+		}
+
+		http.NotFound(w, req)
+	}), nil
+}
@@ -0,0 +1,106 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smoya/ratio/pkg/middleware"
+	"github.com/smoya/ratio/pkg/rate"
+)
+
+func TestProxy(t *testing.T) {
+	orders := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("orders " + r.URL.Path))
+	}))
+	defer orders.Close()
+
+	users := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("users " + r.URL.Path))
+	}))
+	defer users.Close()
+
+	c := Config{Routes: []Route{
+		{Prefix: "/", Upstream: users.URL},
+		{
+			Prefix:   "/v1/orders",
+			Upstream: orders.URL,
+			Limit:    "2/m",
+			Owner:    Rule{Header: "X-Service"},
+			Resource: Rule{PathTemplates: []string{"/v1/orders/{id}"}},
+		},
+	}}
+
+	h, err := New(c, rate.Limits{rate.NewLimit(rate.PerMinute, 100)}, rate.SlideWindowRateLimiter(rate.NewInMemorySlideWindowStorage(map[string][]time.Time{})))
+	require.NoError(t, err)
+
+	get := func(path, service string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Service", service)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/v1/orders/1", "svc")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "orders /v1/orders/1", rec.Body.String())
+	assert.Equal(t, "2", rec.Header().Get(middleware.HeaderLimit))
+	assert.Equal(t, "1", rec.Header().Get(middleware.HeaderRemaining))
+
+	// Same owner and resource template.
+	assert.Equal(t, http.StatusOK, get("/v1/orders/2", "svc").Code)
+	rec = get("/v1/orders/3", "svc")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "60", rec.Header().Get(middleware.HeaderRetry))
+
+	// Another owner has its own hits.
+	assert.Equal(t, http.StatusOK, get("/v1/orders/3", "other").Code)
+
+	// Prefixes match whole path segments.
+	rec = get("/v1/ordersX", "svc")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "users /v1/ordersX", rec.Body.String())
+	assert.Equal(t, "orders /v1/orders", get("/v1/orders", "other").Body.String())
+
+	rec = get("/v1/users/1", "svc")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "users /v1/users/1", rec.Body.String())
+	assert.Equal(t, "100", rec.Header().Get(middleware.HeaderLimit))
+}
+
+func TestProxyNotFound(t *testing.T) {
+	c := Config{Routes: []Route{{Prefix: "/v1", Upstream: "http://localhost:1"}}}
+	h, err := New(c, rate.Limits{rate.NewLimit(rate.PerMinute, 100)}, rate.SlideWindowRateLimiter(rate.NewInMemorySlideWindowStorage(map[string][]time.Time{})))
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v10", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code, "not in the /v1 segment")
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	limit := rate.Limits{rate.NewLimit(rate.PerMinute, 100)}
+	limiter := rate.SlideWindowRateLimiter(rate.NewInMemorySlideWindowStorage(map[string][]time.Time{}))
+
+	cases := map[string]Config{
+		"No routes":        {},
+		"Invalid upstream": {Routes: []Route{{Prefix: "/", Upstream: "orders"}}},
+		"Invalid limit":    {Routes: []Route{{Prefix: "/", Upstream: "http://orders", Limit: "2/never"}}},
+	}
+
+	for desc, c := range cases {
+		t.Run(desc, func(t *testing.T) {
+			_, err := New(c, limit, limiter)
+			assert.Error(t, err)
+		})
+	}
+}
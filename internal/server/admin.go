@@ -0,0 +1,164 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/smoya/ratio/internal/access"
+	"github.com/smoya/ratio/pkg/rate"
+
+	ratio "github.com/smoya/ratio/api/proto"
+)
+
+// errNotPersistent is the error of the changes of access lists not written to a file.
+const errNotPersistent = "the access lists have no file to save the change to, so it would only apply to this instance"
+
+type adminGRPC struct {
+	usage   *rate.UsageAccountant
+	lists   *access.Lists
+	penalty *rate.PenaltyBox
+}
+
+// NewAdminGRPC creates a new GRPC AdminServiceServer. A nil usage means usage accounting is disabled, nil lists mean
+// the access lists are, and a nil penalty means no key is ever banned. The access lists can only be changed when
+// persisted, so the changes reach every instance loading their file.
+func NewAdminGRPC(usage *rate.UsageAccountant, lists *access.Lists, penalty *rate.PenaltyBox) ratio.AdminServiceServer {
+	return &adminGRPC{usage: usage, lists: lists, penalty: penalty}
+}
+
+// NewPenaltyGRPC creates a new GRPC PenaltyServiceServer lifting the bans of penalty, for the peers fanning the Unban
+// calls of their AdminService out (see NewClusterAdminGRPC). A nil penalty means no key is ever banned.
+func NewPenaltyGRPC(penalty *rate.PenaltyBox) ratio.PenaltyServiceServer {
+	return &adminGRPC{penalty: penalty}
+}
+
+// ExportUsage implements ratio.AdminService
+func (s *adminGRPC) ExportUsage(ctx context.Context, r *ratio.ExportUsageRequest) (*ratio.ExportUsageResponse, error) {
+	if s.usage == nil {
+		return nil, status.Error(codes.FailedPrecondition, "usage accounting is disabled")
+	}
+
+	if r.ToMs <= r.FromMs {
+		return nil, status.Error(codes.InvalidArgument, "to_ms should be after from_ms")
+	}
+
+	usages, err := s.usage.Export(fromMilliseconds(r.FromMs), fromMilliseconds(r.ToMs), r.Owner)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	resp := &ratio.ExportUsageResponse{
+		Usage:        make([]*ratio.Usage, 0, len(usages)),
+		BucketSizeMs: int64(s.usage.Bucket() / time.Millisecond),
+	}
+	for _, u := range usages {
+		resp.Usage = append(resp.Usage, &ratio.Usage{
+			Owner:    u.Owner,
+			Resource: u.Resource,
+			BucketMs: u.Bucket.UnixNano() / int64(time.Millisecond),
+			Allowed:  uint64(u.Allowed),
+			Rejected: uint64(u.Rejected),
+		})
+	}
+
+	return resp, nil
+}
+
+// ListAccessEntries implements ratio.AdminService
+func (s *adminGRPC) ListAccessEntries(ctx context.Context, r *ratio.ListAccessEntriesRequest) (*ratio.ListAccessEntriesResponse, error) {
+	if s.lists == nil {
+		return nil, status.Error(codes.FailedPrecondition, "access lists are disabled")
+	}
+
+	c := s.lists.Config()
+	resp := &ratio.ListAccessEntriesResponse{Entries: make([]*ratio.AccessEntry, 0, len(c.Allow)+len(c.Deny))}
+	for _, e := range c.Allow {
+		resp.Entries = append(resp.Entries, &ratio.AccessEntry{List: ratio.AccessEntry_ALLOW, Owner: e.Owner, Resource: e.Resource})
+	}
+
+	for _, e := range c.Deny {
+		resp.Entries = append(resp.Entries, &ratio.AccessEntry{List: ratio.AccessEntry_DENY, Owner: e.Owner, Resource: e.Resource})
+	}
+
+	return resp, nil
+}
+
+// AddAccessEntry implements ratio.AdminService
+func (s *adminGRPC) AddAccessEntry(ctx context.Context, r *ratio.AccessEntry) (*ratio.AddAccessEntryResponse, error) {
+	if s.lists == nil {
+		return nil, status.Error(codes.FailedPrecondition, "access lists are disabled")
+	}
+
+	if !s.lists.Persistent() {
+		return nil, status.Error(codes.FailedPrecondition, errNotPersistent)
+	}
+
+	e := access.Entry{Owner: r.Owner, Resource: r.Resource}
+	if err := e.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	added, err := s.lists.Add(fromProtoList(r.List), e)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &ratio.AddAccessEntryResponse{Added: added}, nil
+}
+
+// RemoveAccessEntry implements ratio.AdminService
+func (s *adminGRPC) RemoveAccessEntry(ctx context.Context, r *ratio.AccessEntry) (*ratio.RemoveAccessEntryResponse, error) {
+	if s.lists == nil {
+		return nil, status.Error(codes.FailedPrecondition, "access lists are disabled")
+	}
+
+	if !s.lists.Persistent() {
+		return nil, status.Error(codes.FailedPrecondition, errNotPersistent)
+	}
+
+	e := access.Entry{Owner: r.Owner, Resource: r.Resource}
+	if err := e.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	removed, err := s.lists.Remove(fromProtoList(r.List), e)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &ratio.RemoveAccessEntryResponse{Removed: removed}, nil
+}
+
+// Unban implements ratio.AdminService and ratio.PenaltyService
+func (s *adminGRPC) Unban(ctx context.Context, r *ratio.UnbanRequest) (*ratio.UnbanResponse, error) {
+	if s.penalty == nil {
+		return nil, status.Error(codes.FailedPrecondition, "penalties are disabled")
+	}
+
+	descriptors, err := fromProtoDescriptors(r.Descriptors)
+	if err != nil {
+		return nil, err
+	}
+
+	unbanned, err := s.penalty.Unban(r.Owner, r.Resource, descriptors...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ratio.UnbanResponse{Unbanned: unbanned}, nil
+}
+
+func fromProtoList(l ratio.AccessEntry_List) string {
+	if l == ratio.AccessEntry_DENY {
+		return access.Deny
+	}
+
+	return access.Allow
+}
+
+func fromMilliseconds(ms int64) time.Time {
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/smoya/ratio/internal/access"
+	"github.com/smoya/ratio/pkg/rate"
+
+	ratio "github.com/smoya/ratio/api/proto"
+)
+
+func TestAdminGRPC_ExportUsage(t *testing.T) {
+	storage := rate.NewInMemorySlideWindowStorage(make(map[string][]time.Time))
+	usage := rate.NewUsageAccountant(storage.(rate.UsageStorage), rate.UsageOptions{Bucket: time.Hour})
+	defer usage.Close()
+
+	bucket := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	usage.Record("checkout", "/pay", true, bucket.Add(time.Minute))
+	usage.Record("checkout", "/pay", false, bucket.Add(time.Minute))
+	usage.Record("search", "/q", true, bucket.Add(time.Minute))
+
+	s := NewAdminGRPC(usage, nil, nil)
+	ms := func(t time.Time) int64 { return t.UnixNano() / int64(time.Millisecond) }
+
+	resp, err := s.ExportUsage(context.Background(), &ratio.ExportUsageRequest{
+		FromMs: ms(bucket),
+		ToMs:   ms(bucket.Add(time.Hour)),
+		Owner:  "checkout",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3600000), resp.BucketSizeMs)
+	assert.Equal(t, []*ratio.Usage{
+		{Owner: "checkout", Resource: "/pay", BucketMs: ms(bucket), Allowed: 1, Rejected: 1},
+	}, resp.Usage)
+
+	_, err = s.ExportUsage(context.Background(), &ratio.ExportUsageRequest{FromMs: ms(bucket), ToMs: ms(bucket)})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = NewAdminGRPC(nil, nil, nil).ExportUsage(context.Background(), &ratio.ExportUsageRequest{FromMs: 0, ToMs: 1})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestAdminGRPC_AccessEntries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "access")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := access.Config{Allow: []access.Entry{{Owner: "health-checker"}}}
+	lists, err := access.NewLists(c)
+	require.NoError(t, err)
+
+	s := NewAdminGRPC(nil, lists, nil)
+	ctx := context.Background()
+
+	_, err = s.AddAccessEntry(ctx, &ratio.AccessEntry{List: ratio.AccessEntry_DENY, Owner: "scraper"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err), "changes not saved to a file only apply to one instance")
+	_, err = s.RemoveAccessEntry(ctx, &ratio.AccessEntry{List: ratio.AccessEntry_ALLOW, Owner: "health-checker"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.Equal(t, c, lists.Config())
+
+	file := filepath.Join(dir, "access.json")
+	require.NoError(t, access.SaveConfig(file, c))
+	lists.Persist(file)
+
+	added, err := s.AddAccessEntry(ctx, &ratio.AccessEntry{List: ratio.AccessEntry_DENY, Owner: "scraper", Resource: "/v1/*"})
+	require.NoError(t, err)
+	assert.True(t, added.Added)
+	assert.Equal(t, access.Denied, lists.Check("scraper", "/v1/search"))
+
+	added, err = s.AddAccessEntry(ctx, &ratio.AccessEntry{List: ratio.AccessEntry_DENY, Owner: "scraper", Resource: "/v1/*"})
+	require.NoError(t, err)
+	assert.False(t, added.Added)
+
+	_, err = s.AddAccessEntry(ctx, &ratio.AccessEntry{List: ratio.AccessEntry_ALLOW})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	entries, err := s.ListAccessEntries(ctx, &ratio.ListAccessEntriesRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, []*ratio.AccessEntry{
+		{List: ratio.AccessEntry_ALLOW, Owner: "health-checker"},
+		{List: ratio.AccessEntry_DENY, Owner: "scraper", Resource: "/v1/*"},
+	}, entries.Entries)
+
+	removed, err := s.RemoveAccessEntry(ctx, &ratio.AccessEntry{List: ratio.AccessEntry_ALLOW, Owner: "scraper", Resource: "/v1/*"})
+	require.NoError(t, err)
+	assert.False(t, removed.Removed)
+
+	removed, err = s.RemoveAccessEntry(ctx, &ratio.AccessEntry{List: ratio.AccessEntry_DENY, Owner: "scraper", Resource: "/v1/*"})
+	require.NoError(t, err)
+	assert.True(t, removed.Removed)
+	assert.Equal(t, access.None, lists.Check("scraper", "/v1/search"))
+
+	saved, err := access.LoadConfig(file)
+	require.NoError(t, err)
+	assert.Equal(t, c.Allow, saved.Allow)
+	assert.Empty(t, saved.Deny)
+
+	require.NoError(t, os.Remove(file))
+	_, err = s.AddAccessEntry(ctx, &ratio.AccessEntry{List: ratio.AccessEntry_DENY, Owner: "scraper"})
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	_, err = NewAdminGRPC(nil, nil, nil).ListAccessEntries(ctx, &ratio.ListAccessEntriesRequest{})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestAdminGRPC_Unban(t *testing.T) {
+	storage := rate.NewInMemorySlideWindowStorage(make(map[string][]time.Time))
+	penalty, err := rate.NewPenaltyBox(storage.(rate.PenaltyStorage), rate.PenaltyPolicy{Violations: 1, Window: time.Minute, Ban: time.Hour})
+	require.NoError(t, err)
+
+	limiter := rate.PenaltyRateLimiter(noopLimiter(false, nil), penalty)
+	d, err := limiter(rate.Limits{rate.NewLimit(rate.PerMinute, 5)}, "svc", "/pay", rate.Descriptor{Key: "customer", Value: "c1"})
+	require.NoError(t, err)
+	require.False(t, d.BannedUntil.IsZero())
+
+	s := NewAdminGRPC(nil, nil, penalty)
+	ctx := context.Background()
+
+	resp, err := s.Unban(ctx, &ratio.UnbanRequest{Owner: "svc", Resource: "/pay"})
+	require.NoError(t, err)
+	assert.False(t, resp.Unbanned, "descriptors are part of the banned key")
+
+	resp, err = s.Unban(ctx, &ratio.UnbanRequest{Owner: "svc", Resource: "/pay", Descriptors: []*ratio.Descriptor{{Key: "customer", Value: "c1"}}})
+	require.NoError(t, err)
+	assert.True(t, resp.Unbanned)
+
+	until, err := penalty.BannedUntil("svc", "/pay", rate.Descriptor{Key: "customer", Value: "c1"})
+	require.NoError(t, err)
+	assert.True(t, until.IsZero())
+
+	_, err = s.Unban(ctx, &ratio.UnbanRequest{Owner: "svc", Descriptors: []*ratio.Descriptor{{Key: "ratio.level", Value: "x"}}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = NewAdminGRPC(nil, nil, nil).Unban(ctx, &ratio.UnbanRequest{Owner: "svc"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
@@ -0,0 +1,233 @@
+package server
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/smoya/ratio/pkg/cluster"
+	"github.com/smoya/ratio/pkg/rate"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	ratio "github.com/smoya/ratio/api/proto"
+)
+
+// forwardedHeader marks a request already forwarded by a peer, so it is never forwarded again.
+const forwardedHeader = "ratio-forwarded-by"
+
+// peerKey is the context key marking the requests sent by a peer of the cluster.
+type peerKey struct{}
+
+// ClusterSecretInterceptor marks the requests carrying the secret shared by the peers of the cluster (see
+// cluster.SecretCredentials) as sent by a peer, so the following interceptors can trust them (see FromPeer). Methods
+// whose full name starts with any of the peerOnly prefixes, like the GossipService, are rejected with Unauthenticated
+// when not called by a peer.
+func ClusterSecretInterceptor(secret string, peerOnly ...string) gogrpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *gogrpc.UnaryServerInfo, handler gogrpc.UnaryHandler) (interface{}, error) {
+		if cluster.FromPeer(ctx, secret) {
+			return handler(context.WithValue(ctx, peerKey{}, true), req)
+		}
+
+		for _, prefix := range peerOnly {
+			if strings.HasPrefix(info.FullMethod, prefix) {
+				return nil, status.Error(codes.Unauthenticated, "only the peers of the cluster can call this method")
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// FromPeer returns whether the request was sent by a peer of the cluster, as marked by ClusterSecretInterceptor.
+func FromPeer(ctx context.Context) bool {
+	fromPeer, _ := ctx.Value(peerKey{}).(bool)
+	return fromPeer
+}
+
+type clusterGRPC struct {
+	local   ratio.RateLimitServiceServer
+	cluster *cluster.Cluster
+}
+
+// NewClusterGRPC creates a RateLimitServiceServer that forwards each request to the peer owning its owner-resource key,
+// so all the hits of a key are stored by the same instance. Requests owned by the local instance, the ones already
+// forwarded by a peer, or those whose owner is unreachable, are served by local. Peers must be dialed with the
+// cluster.SecretCredentials checked by their ClusterSecretInterceptor.
+func NewClusterGRPC(local ratio.RateLimitServiceServer, c *cluster.Cluster) ratio.RateLimitServiceServer {
+	return &clusterGRPC{local: local, cluster: c}
+}
+
+// RateLimit implements ratio.RateLimitService
+func (s *clusterGRPC) RateLimit(ctx context.Context, r *ratio.RateLimitRequest) (*ratio.RateLimitResponse, error) {
+	conn, fctx := forwarding(ctx, s.cluster, r.Owner, r.Resource)
+	if conn == nil {
+		return s.local.RateLimit(ctx, r)
+	}
+
+	resp, err := ratio.NewRateLimitServiceClient(conn).RateLimit(fctx, r)
+	if err != nil {
+		log.Printf("error forwarding to peer %s, serving locally: %s\n", conn.Target(), err.Error())
+		return s.local.RateLimit(ctx, r)
+	}
+
+	return resp, nil
+}
+
+type clusterConcurrencyGRPC struct {
+	local   ratio.ConcurrencyServiceServer
+	cluster *cluster.Cluster
+}
+
+// NewClusterConcurrencyGRPC creates a ConcurrencyServiceServer that forwards each request to the peer owning its
+// owner-resource key, like NewClusterGRPC, so the slots of a key are held by the same instance instead of by each one.
+func NewClusterConcurrencyGRPC(local ratio.ConcurrencyServiceServer, c *cluster.Cluster) ratio.ConcurrencyServiceServer {
+	return &clusterConcurrencyGRPC{local: local, cluster: c}
+}
+
+// Acquire implements ratio.ConcurrencyService
+func (s *clusterConcurrencyGRPC) Acquire(ctx context.Context, r *ratio.AcquireRequest) (*ratio.AcquireResponse, error) {
+	conn, fctx := forwarding(ctx, s.cluster, r.Owner, r.Resource)
+	if conn == nil {
+		return s.local.Acquire(ctx, r)
+	}
+
+	resp, err := ratio.NewConcurrencyServiceClient(conn).Acquire(fctx, r)
+	if err != nil {
+		log.Printf("error forwarding to peer %s, serving locally: %s\n", conn.Target(), err.Error())
+		return s.local.Acquire(ctx, r)
+	}
+
+	return resp, nil
+}
+
+// Release implements ratio.ConcurrencyService
+func (s *clusterConcurrencyGRPC) Release(ctx context.Context, r *ratio.ReleaseRequest) (*ratio.ReleaseResponse, error) {
+	conn, fctx := forwarding(ctx, s.cluster, r.Owner, r.Resource)
+	if conn == nil {
+		return s.local.Release(ctx, r)
+	}
+
+	resp, err := ratio.NewConcurrencyServiceClient(conn).Release(fctx, r)
+	if err != nil {
+		log.Printf("error forwarding to peer %s, serving locally: %s\n", conn.Target(), err.Error())
+		return s.local.Release(ctx, r)
+	}
+
+	return resp, nil
+}
+
+type clusterQuotaGRPC struct {
+	local   ratio.QuotaServiceServer
+	cluster *cluster.Cluster
+}
+
+// NewClusterQuotaGRPC creates a QuotaServiceServer that forwards each request to the peer owning its owner-resource
+// key, like NewClusterGRPC, so reservations count against the same hits as the RateLimit calls of the key.
+func NewClusterQuotaGRPC(local ratio.QuotaServiceServer, c *cluster.Cluster) ratio.QuotaServiceServer {
+	return &clusterQuotaGRPC{local: local, cluster: c}
+}
+
+// Reserve implements ratio.QuotaService
+func (s *clusterQuotaGRPC) Reserve(ctx context.Context, r *ratio.ReserveRequest) (*ratio.ReserveResponse, error) {
+	conn, fctx := forwarding(ctx, s.cluster, r.Owner, r.Resource)
+	if conn == nil {
+		return s.local.Reserve(ctx, r)
+	}
+
+	resp, err := ratio.NewQuotaServiceClient(conn).Reserve(fctx, r)
+	if err != nil {
+		log.Printf("error forwarding to peer %s, serving locally: %s\n", conn.Target(), err.Error())
+		return s.local.Reserve(ctx, r)
+	}
+
+	return resp, nil
+}
+
+// Return implements ratio.QuotaService
+func (s *clusterQuotaGRPC) Return(ctx context.Context, r *ratio.ReturnRequest) (*ratio.ReturnResponse, error) {
+	conn, fctx := forwarding(ctx, s.cluster, r.Owner, r.Resource)
+	if conn == nil {
+		return s.local.Return(ctx, r)
+	}
+
+	resp, err := ratio.NewQuotaServiceClient(conn).Return(fctx, r)
+	if err != nil {
+		log.Printf("error forwarding to peer %s, serving locally: %s\n", conn.Target(), err.Error())
+		return s.local.Return(ctx, r)
+	}
+
+	return resp, nil
+}
+
+type clusterAdminGRPC struct {
+	ratio.AdminServiceServer
+	cluster *cluster.Cluster
+}
+
+// NewClusterAdminGRPC creates an AdminServiceServer that fans the Unban calls out to the PenaltyService of every peer,
+// so bans are lifted wherever they are held: by the owner of the key, by the previous ones, or by every instance when
+// they gossip their counters. Other calls are served by local.
+func NewClusterAdminGRPC(local ratio.AdminServiceServer, c *cluster.Cluster) ratio.AdminServiceServer {
+	return &clusterAdminGRPC{AdminServiceServer: local, cluster: c}
+}
+
+// Unban implements ratio.AdminService
+func (s *clusterAdminGRPC) Unban(ctx context.Context, r *ratio.UnbanRequest) (*ratio.UnbanResponse, error) {
+	resp, err := s.AdminServiceServer.Unban(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	var failed []string
+	for _, peer := range s.cluster.Peers() {
+		if peer == s.cluster.Self() {
+			continue
+		}
+
+		conn, err := s.cluster.Conn(peer)
+		if err == nil {
+			var peerResp *ratio.UnbanResponse
+			if peerResp, err = ratio.NewPenaltyServiceClient(conn).Unban(ctx, r); err == nil {
+				resp.Unbanned = resp.Unbanned || peerResp.Unbanned
+				continue
+			}
+		}
+
+		log.Printf("error lifting the ban of %s -> %s at peer %s: %s\n", r.Owner, r.Resource, peer, err.Error())
+		failed = append(failed, peer)
+	}
+
+	if len(failed) > 0 {
+		return nil, status.Errorf(codes.Unavailable, "the ban may still be held by %s", strings.Join(failed, ", "))
+	}
+
+	return resp, nil
+}
+
+// forwarding returns the connection to the peer owning the owner-resource key, and the context to forward the request
+// with. The connection is nil when the request is to be served locally: when the local instance owns the key, when the
+// request was already forwarded by a peer, or when the owner is unreachable.
+func forwarding(ctx context.Context, c *cluster.Cluster, owner, resource string) (*gogrpc.ClientConn, context.Context) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get(forwardedHeader)) > 0 && FromPeer(ctx) {
+		return nil, ctx
+	}
+
+	// Descriptors are not part of the key, so all the requests of a resource are served by the same instance.
+	peer, local := c.Owner(rate.Key(owner, resource))
+	if local {
+		return nil, ctx
+	}
+
+	conn, err := c.Conn(peer)
+	if err != nil {
+		log.Printf("error connecting to peer %s, serving locally: %s\n", peer, err.Error())
+		return nil, ctx
+	}
+
+	// The caller was already authenticated here, so the owner of the key trusts the request as it comes from a peer
+	// (see ClusterSecretInterceptor) instead of authenticating this instance as the caller.
+	return conn, metadata.AppendToOutgoingContext(ctx, forwardedHeader, c.Self())
+}
@@ -0,0 +1,270 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/smoya/ratio/pkg/cluster"
+	"github.com/smoya/ratio/pkg/rate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	ratio "github.com/smoya/ratio/api/proto"
+)
+
+type recorderServer struct {
+	received    []string
+	forwardedBy []string
+}
+
+func (s *recorderServer) RateLimit(ctx context.Context, r *ratio.RateLimitRequest) (*ratio.RateLimitResponse, error) {
+	s.received = append(s.received, r.Owner)
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		s.forwardedBy = append(s.forwardedBy, md.Get(forwardedHeader)...)
+	}
+
+	return &ratio.RateLimitResponse{Code: ratio.RateLimitResponse_OK}, nil
+}
+
+func TestClusterGRPC_RateLimit(t *testing.T) {
+	a, b := &recorderServer{}, &recorderServer{}
+	addrA, stopA := serve(t, a)
+	defer stopA()
+	addrB, stopB := serve(t, b)
+	defer stopB()
+
+	c := cluster.New(addrA, cluster.StaticDiscoverer(addrA, addrB), gogrpc.WithInsecure())
+	defer c.Close()
+	assert.NoError(t, c.Refresh())
+
+	s := NewClusterGRPC(a, c)
+
+	ctx := context.Background()
+
+	var owned int
+	for _, owner := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		resp, err := s.RateLimit(ctx, &ratio.RateLimitRequest{Owner: owner})
+		assert.NoError(t, err)
+		assert.Equal(t, ratio.RateLimitResponse_OK, resp.Code)
+
+		if peer, _ := c.Owner(rate.Key(owner, "")); peer == addrB {
+			owned++
+			assert.Contains(t, b.received, owner)
+		} else {
+			assert.Contains(t, a.received, owner)
+		}
+	}
+
+	assert.Len(t, b.received, owned)
+	assert.Len(t, a.received, 8-owned)
+	assert.Len(t, b.forwardedBy, owned, "requests should be marked as forwarded")
+}
+
+func TestClusterGRPC_RateLimit_UnreachablePeer(t *testing.T) {
+	a := &recorderServer{}
+	c := cluster.New("127.0.0.1:1", cluster.StaticDiscoverer("127.0.0.1:2"), gogrpc.WithInsecure())
+	defer c.Close()
+	assert.NoError(t, c.Refresh())
+
+	s := NewClusterGRPC(a, c)
+	for _, owner := range []string{"a", "b", "c", "d"} {
+		_, err := s.RateLimit(context.Background(), &ratio.RateLimitRequest{Owner: owner})
+		assert.NoError(t, err)
+	}
+
+	assert.Len(t, a.received, 4, "requests should be served locally when the owner is unreachable")
+}
+
+type concurrencyRecorder struct {
+	acquired []string
+	released []string
+}
+
+func (s *concurrencyRecorder) Acquire(_ context.Context, r *ratio.AcquireRequest) (*ratio.AcquireResponse, error) {
+	s.acquired = append(s.acquired, r.Owner)
+	return &ratio.AcquireResponse{Code: ratio.RateLimitResponse_OK, LeaseId: r.Owner}, nil
+}
+
+func (s *concurrencyRecorder) Release(_ context.Context, r *ratio.ReleaseRequest) (*ratio.ReleaseResponse, error) {
+	s.released = append(s.released, r.Owner)
+	return &ratio.ReleaseResponse{Released: true}, nil
+}
+
+func TestClusterConcurrencyGRPC(t *testing.T) {
+	a, b := &concurrencyRecorder{}, &concurrencyRecorder{}
+	addrA, stopA := serveWith(t, func(srv *gogrpc.Server) { ratio.RegisterConcurrencyServiceServer(srv, a) })
+	defer stopA()
+	addrB, stopB := serveWith(t, func(srv *gogrpc.Server) { ratio.RegisterConcurrencyServiceServer(srv, b) })
+	defer stopB()
+
+	c := cluster.New(addrA, cluster.StaticDiscoverer(addrA, addrB), gogrpc.WithInsecure())
+	defer c.Close()
+	assert.NoError(t, c.Refresh())
+
+	s := NewClusterConcurrencyGRPC(a, c)
+	ctx := context.Background()
+
+	var owned []string
+	for _, owner := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		resp, err := s.Acquire(ctx, &ratio.AcquireRequest{Owner: owner, Resource: "/export"})
+		assert.NoError(t, err)
+		assert.Equal(t, ratio.RateLimitResponse_OK, resp.Code)
+
+		released, err := s.Release(ctx, &ratio.ReleaseRequest{Owner: owner, Resource: "/export", LeaseId: resp.LeaseId})
+		assert.NoError(t, err)
+		assert.True(t, released.Released)
+
+		if peer, _ := c.Owner(rate.Key(owner, "/export")); peer == addrB {
+			owned = append(owned, owner)
+		}
+	}
+
+	assert.Equal(t, owned, b.acquired, "the slots of a key should be held by its owner")
+	assert.Equal(t, owned, b.released, "and released there")
+	assert.Len(t, a.acquired, 8-len(owned))
+}
+
+type quotaRecorder struct {
+	reserved []string
+	returned []string
+}
+
+func (s *quotaRecorder) Reserve(_ context.Context, r *ratio.ReserveRequest) (*ratio.ReserveResponse, error) {
+	s.reserved = append(s.reserved, r.Owner)
+	return &ratio.ReserveResponse{Code: ratio.RateLimitResponse_OK, Permits: r.Permits, LeaseId: r.Owner}, nil
+}
+
+func (s *quotaRecorder) Return(_ context.Context, r *ratio.ReturnRequest) (*ratio.ReturnResponse, error) {
+	s.returned = append(s.returned, r.Owner)
+	return &ratio.ReturnResponse{Returned: r.Unused}, nil
+}
+
+func TestClusterQuotaGRPC(t *testing.T) {
+	a, b := &quotaRecorder{}, &quotaRecorder{}
+	addrA, stopA := serveWith(t, func(srv *gogrpc.Server) { ratio.RegisterQuotaServiceServer(srv, a) })
+	defer stopA()
+	addrB, stopB := serveWith(t, func(srv *gogrpc.Server) { ratio.RegisterQuotaServiceServer(srv, b) })
+	defer stopB()
+
+	c := cluster.New(addrA, cluster.StaticDiscoverer(addrA, addrB), gogrpc.WithInsecure())
+	defer c.Close()
+	assert.NoError(t, c.Refresh())
+
+	s := NewClusterQuotaGRPC(a, c)
+	ctx := context.Background()
+
+	var owned []string
+	for _, owner := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		resp, err := s.Reserve(ctx, &ratio.ReserveRequest{Owner: owner, Resource: "/q", Permits: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(10), resp.Permits)
+
+		returned, err := s.Return(ctx, &ratio.ReturnRequest{Owner: owner, Resource: "/q", LeaseId: resp.LeaseId, Unused: 3})
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(3), returned.Returned)
+
+		if peer, _ := c.Owner(rate.Key(owner, "/q")); peer == addrB {
+			owned = append(owned, owner)
+		}
+	}
+
+	assert.Equal(t, owned, b.reserved, "reservations should count where the hits of the key do")
+	assert.Equal(t, owned, b.returned, "and be returned there")
+	assert.Len(t, a.reserved, 8-len(owned))
+}
+
+func TestClusterAdminGRPC_Unban(t *testing.T) {
+	boxes := make([]*rate.PenaltyBox, 2)
+	for i := range boxes {
+		storage := rate.NewInMemorySlideWindowStorage(make(map[string][]time.Time))
+		box, err := rate.NewPenaltyBox(storage.(rate.PenaltyStorage), rate.PenaltyPolicy{Violations: 1, Window: time.Minute, Ban: time.Hour})
+		require.NoError(t, err)
+		boxes[i] = box
+	}
+
+	addrA, stopA := serveWith(t, func(srv *gogrpc.Server) { ratio.RegisterPenaltyServiceServer(srv, NewPenaltyGRPC(boxes[0])) })
+	defer stopA()
+	addrB, stopB := serveWith(t, func(srv *gogrpc.Server) { ratio.RegisterPenaltyServiceServer(srv, NewPenaltyGRPC(boxes[1])) })
+	defer stopB()
+
+	c := cluster.New(addrA, cluster.StaticDiscoverer(addrA, addrB), gogrpc.WithInsecure())
+	defer c.Close()
+	require.NoError(t, c.Refresh())
+
+	s := NewClusterAdminGRPC(NewAdminGRPC(nil, nil, boxes[0]), c)
+	limits := rate.Limits{rate.NewLimit(rate.PerMinute, 1)}
+	for i, box := range boxes {
+		// Every instance holds its own bans, e.g. the previous owner of the key.
+		owner := fmt.Sprintf("owner-%d", i)
+		_, err := rate.PenaltyRateLimiter(noopLimiter(false, nil), box)(limits, owner, "/pay")
+		require.NoError(t, err)
+
+		resp, err := s.Unban(context.Background(), &ratio.UnbanRequest{Owner: owner, Resource: "/pay"})
+		require.NoError(t, err)
+		assert.True(t, resp.Unbanned, owner)
+
+		until, err := box.BannedUntil(owner, "/pay")
+		require.NoError(t, err)
+		assert.True(t, until.IsZero(), "the ban of %s should be lifted where it is held", owner)
+	}
+
+	resp, err := s.Unban(context.Background(), &ratio.UnbanRequest{Owner: "svc", Resource: "/pay"})
+	require.NoError(t, err)
+	assert.False(t, resp.Unbanned)
+
+	stopB()
+	_, err = s.Unban(context.Background(), &ratio.UnbanRequest{Owner: "svc", Resource: "/pay"})
+	assert.Equal(t, codes.Unavailable, status.Code(err), "unreachable peers may hold the ban")
+}
+
+func TestClusterSecretInterceptor(t *testing.T) {
+	interceptor := ClusterSecretInterceptor("s3cr3t", "/GossipService/")
+
+	var fromPeer bool
+	handler := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		fromPeer = FromPeer(ctx)
+		return nil, nil
+	}
+	gossip := &gogrpc.UnaryServerInfo{FullMethod: "/GossipService/Gossip"}
+	rateLimit := &gogrpc.UnaryServerInfo{FullMethod: "/RateLimitService/RateLimit"}
+	peer := metadata.NewIncomingContext(context.Background(), metadata.Pairs(cluster.SecretHeader, "s3cr3t"))
+	spoofed := metadata.NewIncomingContext(context.Background(), metadata.Pairs(cluster.SecretHeader, "guess"))
+
+	_, err := interceptor(peer, &ratio.GossipRequest{}, gossip, handler)
+	assert.NoError(t, err)
+	assert.True(t, fromPeer)
+
+	_, err = interceptor(spoofed, &ratio.GossipRequest{}, gossip, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = interceptor(context.Background(), &ratio.GossipRequest{}, gossip, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = interceptor(spoofed, &ratio.RateLimitRequest{}, rateLimit, handler)
+	assert.NoError(t, err)
+	assert.False(t, fromPeer, "other methods are served but not trusted")
+}
+
+func serve(t *testing.T, s ratio.RateLimitServiceServer) (string, func()) {
+	return serveWith(t, func(srv *gogrpc.Server) { ratio.RegisterRateLimitServiceServer(srv, s) })
+}
+
+// serveWith serves the services registered by register.
+func serveWith(t *testing.T, register func(*gogrpc.Server)) (string, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := gogrpc.NewServer()
+	register(srv)
+	go func() { _ = srv.Serve(l) }()
+
+	return l.Addr().String(), srv.Stop
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/smoya/ratio/pkg/rate"
+
+	ratio "github.com/smoya/ratio/api/proto"
+)
+
+type concurrencyGRPC struct {
+	max     int
+	ttl     time.Duration
+	maxTTL  time.Duration
+	limiter rate.ConcurrencyLimiter
+}
+
+// NewConcurrencyGRPC creates a new GRPC ConcurrencyServiceServer allowing max slots held at once per owner and
+// resource. Leases last ttl unless the caller asks for another one, up to maxTTL.
+func NewConcurrencyGRPC(max int, ttl, maxTTL time.Duration, limiter rate.ConcurrencyLimiter) ratio.ConcurrencyServiceServer {
+	return &concurrencyGRPC{max: max, ttl: ttl, maxTTL: maxTTL, limiter: limiter}
+}
+
+// Acquire implements ratio.ConcurrencyService
+func (s *concurrencyGRPC) Acquire(ctx context.Context, r *ratio.AcquireRequest) (*ratio.AcquireResponse, error) {
+	log.Printf("Acquire request: %s -> %s\n", r.Owner, r.Resource)
+
+	descriptors, err := fromProtoDescriptors(r.Descriptors)
+	if err != nil {
+		return &ratio.AcquireResponse{Code: ratio.RateLimitResponse_UNKNOWN}, err
+	}
+
+	ttl := s.ttl
+	if r.TtlMs > 0 {
+		ttl = time.Duration(r.TtlMs) * time.Millisecond
+	}
+	if ttl > s.maxTTL {
+		ttl = s.maxTTL
+	}
+
+	l, err := s.limiter.Acquire(s.max, ttl, r.Owner, r.Resource, descriptors...)
+	if err != nil {
+		return &ratio.AcquireResponse{Code: ratio.RateLimitResponse_UNKNOWN}, err
+	}
+
+	resp := &ratio.AcquireResponse{
+		Code:      ratio.RateLimitResponse_OVER_LIMIT,
+		Limit:     uint32(l.Max),
+		Remaining: uint32(l.Remaining()),
+	}
+	if l.Acquired {
+		resp.Code = ratio.RateLimitResponse_OK
+		resp.LeaseId = l.ID
+		resp.ExpireAtMs = int64(l.ExpireAt.UnixNano() / int64(time.Millisecond))
+	}
+
+	return resp, nil
+}
+
+// Release implements ratio.ConcurrencyService
+func (s *concurrencyGRPC) Release(ctx context.Context, r *ratio.ReleaseRequest) (*ratio.ReleaseResponse, error) {
+	descriptors, err := fromProtoDescriptors(r.Descriptors)
+	if err != nil {
+		return nil, err
+	}
+
+	released, err := s.limiter.Release(r.LeaseId, r.Owner, r.Resource, descriptors...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ratio.ReleaseResponse{Released: released}, nil
+}
+
+// fromProtoDescriptors converts the descriptors of a request, rejecting the reserved ones with InvalidArgument.
+func fromProtoDescriptors(pd []*ratio.Descriptor) ([]rate.Descriptor, error) {
+	descriptors := make([]rate.Descriptor, 0, len(pd))
+	for _, d := range pd {
+		if strings.HasPrefix(d.Key, rate.ReservedDescriptorPrefix) {
+			return nil, status.Errorf(codes.InvalidArgument, "descriptor key %s is reserved", d.Key)
+		}
+
+		descriptors = append(descriptors, rate.Descriptor{Key: d.Key, Value: d.Value})
+	}
+
+	return descriptors, nil
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/smoya/ratio/pkg/rate"
+
+	ratio "github.com/smoya/ratio/api/proto"
+)
+
+func TestConcurrencyGRPC(t *testing.T) {
+	storage := rate.NewInMemorySlideWindowStorage(make(map[string][]time.Time)).(rate.ConcurrencyStorage)
+	s := NewConcurrencyGRPC(1, time.Minute, time.Hour, rate.NewConcurrencyLimiter(storage))
+	ctx := context.Background()
+
+	before := time.Now()
+	resp, err := s.Acquire(ctx, &ratio.AcquireRequest{Owner: "svc", Resource: "/export", TtlMs: int64(2 * time.Hour / time.Millisecond)})
+	require.NoError(t, err)
+	assert.Equal(t, ratio.RateLimitResponse_OK, resp.Code)
+	assert.NotEmpty(t, resp.LeaseId)
+	assert.Equal(t, uint32(1), resp.Limit)
+	assert.Equal(t, uint32(0), resp.Remaining)
+	assert.InDelta(t, before.Add(time.Hour).UnixNano()/int64(time.Millisecond), resp.ExpireAtMs, 1000, "the ttl is capped")
+	lease := resp.LeaseId
+
+	resp, err = s.Acquire(ctx, &ratio.AcquireRequest{Owner: "svc", Resource: "/export"})
+	require.NoError(t, err)
+	assert.Equal(t, ratio.RateLimitResponse_OVER_LIMIT, resp.Code)
+	assert.Empty(t, resp.LeaseId)
+
+	released, err := s.Release(ctx, &ratio.ReleaseRequest{Owner: "svc", Resource: "/export", LeaseId: lease})
+	require.NoError(t, err)
+	assert.True(t, released.Released)
+
+	resp, err = s.Acquire(ctx, &ratio.AcquireRequest{Owner: "svc", Resource: "/export"})
+	require.NoError(t, err)
+	assert.Equal(t, ratio.RateLimitResponse_OK, resp.Code)
+
+	_, err = s.Acquire(ctx, &ratio.AcquireRequest{Descriptors: []*ratio.Descriptor{{Key: rate.ConcurrencyDescriptor}}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/smoya/ratio/pkg/rate"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// HealthChecker sets the serving status of a GRPC health server based on the reachability of the storage,
+// so instances stop receiving traffic while their storage is down.
+type HealthChecker struct {
+	health   *health.Server
+	storage  rate.SlideWindowStorage
+	interval time.Duration
+
+	done      chan struct{}
+	wg        sync.WaitGroup
+	drainOnce sync.Once
+}
+
+// NewHealthChecker creates a HealthChecker that pings the storage every interval.
+func NewHealthChecker(h *health.Server, s rate.SlideWindowStorage, interval time.Duration) *HealthChecker {
+	return &HealthChecker{
+		health:   h,
+		storage:  s,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+}
+
+// Check pings the storage once and updates the serving status of the whole server accordingly.
+func (c *HealthChecker) Check() {
+	status := healthpb.HealthCheckResponse_SERVING
+	if err := c.storage.Ping(); err != nil {
+		log.Printf("storage is unreachable: %s\n", err.Error())
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+
+	c.health.SetServingStatus("", status)
+}
+
+// Start checks the storage every interval until Close is called.
+func (c *HealthChecker) Start() {
+	c.Check()
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		t := time.NewTicker(c.interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-c.done:
+				return
+			case <-t.C:
+				c.Check()
+			}
+		}
+	}()
+}
+
+// Drain stops checking the storage and sets all the services as NOT_SERVING, so no more traffic is sent while
+// shutting down. It should be called before stopping the server, so probes still get an answer.
+func (c *HealthChecker) Drain() {
+	c.drainOnce.Do(func() {
+		close(c.done)
+		c.wg.Wait()
+		c.health.Shutdown()
+	})
+}
+
+// Close drains the HealthChecker, if not drained yet.
+func (c *HealthChecker) Close() error {
+	c.Drain()
+
+	return nil
+}
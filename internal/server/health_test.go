@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/smoya/ratio/pkg/rate"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type pingStorage struct {
+	rate.SlideWindowStorage
+	err error
+}
+
+func (s *pingStorage) Ping() error {
+	return s.err
+}
+
+func TestHealthChecker_Check(t *testing.T) {
+	h := health.NewServer()
+	s := &pingStorage{}
+	c := NewHealthChecker(h, s, time.Hour)
+
+	c.Check()
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus(t, h))
+
+	s.err = errors.New("whatever error")
+	c.Check()
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(t, h))
+
+	s.err = nil
+	c.Check()
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus(t, h))
+}
+
+func TestHealthChecker_Close(t *testing.T) {
+	h := health.NewServer()
+	c := NewHealthChecker(h, &pingStorage{}, time.Millisecond)
+
+	c.Start()
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus(t, h))
+
+	assert.NoError(t, c.Close())
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(t, h))
+}
+
+func TestHealthChecker_Drain(t *testing.T) {
+	h := health.NewServer()
+	c := NewHealthChecker(h, &pingStorage{}, time.Millisecond)
+
+	c.Start()
+	c.Drain()
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(t, h))
+
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(t, h), "checks should not set it back to SERVING")
+	assert.NoError(t, c.Close(), "closing a drained HealthChecker should do nothing")
+}
+
+func servingStatus(t *testing.T, h *health.Server) healthpb.HealthCheckResponse_ServingStatus {
+	resp, err := h.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+
+	return resp.Status
+}
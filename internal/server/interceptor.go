@@ -0,0 +1,22 @@
+package server
+
+import (
+	"context"
+
+	gogrpc "google.golang.org/grpc"
+)
+
+// ChainUnaryInterceptors chains unary interceptors into one, executing them in the given order.
+func ChainUnaryInterceptors(interceptors ...gogrpc.UnaryServerInterceptor) gogrpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *gogrpc.UnaryServerInfo, handler gogrpc.UnaryHandler) (interface{}, error) {
+		chained := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, next := interceptors[i], chained
+			chained = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return interceptor(ctx, req, info, next)
+			}
+		}
+
+		return chained(ctx, req)
+	}
+}
@@ -0,0 +1,29 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	gogrpc "google.golang.org/grpc"
+)
+
+func TestChainUnaryInterceptors(t *testing.T) {
+	var calls []string
+	interceptor := func(name string) gogrpc.UnaryServerInterceptor {
+		return func(ctx context.Context, req interface{}, info *gogrpc.UnaryServerInfo, handler gogrpc.UnaryHandler) (interface{}, error) {
+			calls = append(calls, name)
+			return handler(ctx, req)
+		}
+	}
+
+	chained := ChainUnaryInterceptors(interceptor("first"), interceptor("second"))
+	resp, err := chained(context.Background(), "req", &gogrpc.UnaryServerInfo{}, func(_ context.Context, req interface{}) (interface{}, error) {
+		calls = append(calls, "handler")
+		return req, nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "req", resp)
+	assert.Equal(t, []string{"first", "second", "handler"}, calls)
+}
@@ -0,0 +1,23 @@
+package server
+
+import (
+	"context"
+
+	gogrpc "google.golang.org/grpc"
+
+	ratio "github.com/smoya/ratio/api/proto"
+)
+
+type localClient struct {
+	srv ratio.RateLimitServiceServer
+}
+
+// NewLocalClient creates a RateLimitServiceClient calling the given server in-process, without any network round trip.
+func NewLocalClient(srv ratio.RateLimitServiceServer) ratio.RateLimitServiceClient {
+	return &localClient{srv: srv}
+}
+
+// RateLimit implements ratio.RateLimitServiceClient. Call options are ignored.
+func (c *localClient) RateLimit(ctx context.Context, r *ratio.RateLimitRequest, _ ...gogrpc.CallOption) (*ratio.RateLimitResponse, error) {
+	return c.srv.RateLimit(ctx, r)
+}
@@ -0,0 +1,189 @@
+package server
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/smoya/ratio/internal/access"
+	"github.com/smoya/ratio/internal/audit"
+	"github.com/smoya/ratio/pkg/rate"
+
+	ratio "github.com/smoya/ratio/api/proto"
+)
+
+type quotaGRPC struct {
+	limits  rate.Limits
+	rules   rate.Rules
+	ttl     time.Duration
+	leaser  rate.QuotaLeaser
+	lists   *access.Lists
+	penalty *rate.PenaltyBox
+	usage   *rate.UsageAccountant
+	sinks   []audit.Sink
+}
+
+// NewQuotaGRPC creates a new GRPC QuotaServiceServer reserving permits against the limits, or the ones of the rule
+// matching the request. Leases last ttl unless the caller asks for another one, up to the smallest window of the
+// limits, so permits are consumed in the window they were reserved for.
+// Reservations are checked against the access lists and the penalty box first, if any, like the hits of RateLimit,
+// and their decisions are emitted to the sinks. Neither the limits of a hierarchy nor the period limits are checked.
+// The reserved permits are accounted in usage, if any, as allowed hits, and rejected reservations as a rejected hit.
+func NewQuotaGRPC(
+	limits rate.Limits,
+	rules rate.Rules,
+	ttl time.Duration,
+	leaser rate.QuotaLeaser,
+	lists *access.Lists,
+	penalty *rate.PenaltyBox,
+	usage *rate.UsageAccountant,
+	sinks ...audit.Sink,
+) ratio.QuotaServiceServer {
+	return &quotaGRPC{
+		limits:  limits,
+		rules:   rules,
+		ttl:     ttl,
+		leaser:  leaser,
+		lists:   lists,
+		penalty: penalty,
+		usage:   usage,
+		sinks:   sinks,
+	}
+}
+
+// Reserve implements ratio.QuotaService
+func (s *quotaGRPC) Reserve(ctx context.Context, r *ratio.ReserveRequest) (*ratio.ReserveResponse, error) {
+	log.Printf("Reserve request: %d of %s -> %s\n", r.Permits, r.Owner, r.Resource)
+
+	if r.Permits == 0 {
+		return &ratio.ReserveResponse{Code: ratio.RateLimitResponse_UNKNOWN}, status.Error(codes.InvalidArgument, "permits should be greater than 0")
+	}
+
+	descriptors, err := fromProtoDescriptors(r.Descriptors)
+	if err != nil {
+		return &ratio.ReserveResponse{Code: ratio.RateLimitResponse_UNKNOWN}, err
+	}
+
+	limits := s.rules.Limits(s.limits, r.Owner, r.Resource, descriptors...)
+	switch s.lists.Check(r.Owner, r.Resource) {
+	case access.Denied:
+		s.emit(audit.NewListEvent(time.Now(), r.Owner, r.Resource, descriptors, false))
+		s.account(r.Owner, r.Resource, 0)
+		return &ratio.ReserveResponse{Code: ratio.RateLimitResponse_DENIED}, nil
+	case access.Allowed:
+		// Allowed hits are not limited, so the permits are granted without reserving them, up to the smallest limit.
+		permits := maxPermits(limits, int(r.Permits))
+		s.emit(audit.NewListEvent(time.Now(), r.Owner, r.Resource, descriptors, true))
+		s.account(r.Owner, r.Resource, permits)
+		return &ratio.ReserveResponse{
+			Code:       ratio.RateLimitResponse_OK,
+			Permits:    uint32(permits),
+			ExpireAtMs: time.Now().Add(s.leaseTTL(limits, r.TtlMs)).UnixNano() / int64(time.Millisecond),
+		}, nil
+	}
+
+	if s.penalty != nil {
+		until, err := s.penalty.BannedUntil(r.Owner, r.Resource, descriptors...)
+		if err != nil {
+			return &ratio.ReserveResponse{Code: ratio.RateLimitResponse_UNKNOWN}, err
+		}
+
+		if !until.IsZero() {
+			s.emit(audit.NewEvent(time.Now(), r.Owner, r.Resource, descriptors, rate.Decision{BannedUntil: until}))
+			s.account(r.Owner, r.Resource, 0)
+			return &ratio.ReserveResponse{Code: ratio.RateLimitResponse_OVER_LIMIT}, nil
+		}
+	}
+
+	res, err := s.leaser.Reserve(limits, int(r.Permits), r.Owner, r.Resource, descriptors...)
+	if err != nil {
+		return &ratio.ReserveResponse{Code: ratio.RateLimitResponse_UNKNOWN}, err
+	}
+	s.emit(audit.NewEvent(time.Now(), r.Owner, r.Resource, descriptors, rate.Decision{Allowed: res.Permits > 0, Limit: res.Limit, Hits: res.Hits}))
+	s.account(r.Owner, r.Resource, res.Permits)
+
+	resp := &ratio.ReserveResponse{
+		Code:      ratio.RateLimitResponse_OVER_LIMIT,
+		Limit:     toProtoLimit(res.Limit),
+		Remaining: uint32(res.Remaining()),
+	}
+	if res.Permits == 0 {
+		return resp, nil
+	}
+
+	resp.Code = ratio.RateLimitResponse_OK
+	resp.LeaseId = res.ID
+	resp.Permits = uint32(res.Permits)
+	resp.ExpireAtMs = int64(time.Now().Add(s.leaseTTL(limits, r.TtlMs)).UnixNano() / int64(time.Millisecond))
+
+	return resp, nil
+}
+
+// leaseTTL is the ttl asked by the caller, or the default one, up to the smallest window of the limits.
+func (s *quotaGRPC) leaseTTL(limits rate.Limits, ttlMs int64) time.Duration {
+	ttl := s.ttl
+	if ttlMs > 0 {
+		ttl = time.Duration(ttlMs) * time.Millisecond
+	}
+	for _, l := range limits {
+		if w := l.Unit.Duration(); w < ttl {
+			ttl = w
+		}
+	}
+
+	return ttl
+}
+
+// maxPermits caps the permits to the quantity of the smallest of the limits.
+func maxPermits(limits rate.Limits, permits int) int {
+	for _, l := range limits {
+		if l.Quantity < permits {
+			permits = l.Quantity
+		}
+	}
+
+	return permits
+}
+
+// account accounts the permits reserved by the owner on the resource, a rejected hit if none.
+func (s *quotaGRPC) account(owner, resource string, permits int) {
+	if s.usage == nil {
+		return
+	}
+
+	if permits > 0 {
+		s.usage.RecordN(owner, resource, true, permits, time.Now())
+	} else {
+		s.usage.Record(owner, resource, false, time.Now())
+	}
+}
+
+// emit emits the event of a reservation to the sinks.
+func (s *quotaGRPC) emit(e audit.Event) {
+	for _, sink := range s.sinks {
+		sink.Emit(e)
+	}
+}
+
+// Return implements ratio.QuotaService
+func (s *quotaGRPC) Return(ctx context.Context, r *ratio.ReturnRequest) (*ratio.ReturnResponse, error) {
+	descriptors, err := fromProtoDescriptors(r.Descriptors)
+	if err != nil {
+		return nil, err
+	}
+
+	returned, reservedAt, err := s.leaser.Return(r.LeaseId, int(r.Unused), r.Owner, r.Resource, descriptors...)
+	if err != nil {
+		return nil, err
+	}
+
+	// The returned permits were accounted as allowed hits when reserved, so they are taken back from their bucket.
+	if s.usage != nil && returned > 0 {
+		s.usage.RecordN(r.Owner, r.Resource, true, -returned, reservedAt)
+	}
+
+	return &ratio.ReturnResponse{Returned: uint32(returned)}, nil
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/smoya/ratio/internal/access"
+	"github.com/smoya/ratio/internal/audit"
+	"github.com/smoya/ratio/pkg/rate"
+
+	ratio "github.com/smoya/ratio/api/proto"
+)
+
+func TestQuotaGRPC(t *testing.T) {
+	storage := rate.NewInMemorySlideWindowStorage(make(map[string][]time.Time)).(rate.WeightedSlideWindowStorage)
+	rules, err := rate.CompileRules([]rate.Rule{{Owner: "search", Limit: "10/m"}})
+	require.NoError(t, err)
+
+	s := NewQuotaGRPC(rate.Limits{rate.NewLimit(rate.PerHour, 100)}, rules, time.Hour, rate.NewSlideWindowQuotaLeaser(storage), nil, nil, nil)
+	ctx := context.Background()
+
+	before := time.Now()
+	resp, err := s.Reserve(ctx, &ratio.ReserveRequest{Owner: "search", Resource: "/q", Permits: 8})
+	require.NoError(t, err)
+	assert.Equal(t, ratio.RateLimitResponse_OK, resp.Code)
+	assert.NotEmpty(t, resp.LeaseId)
+	assert.Equal(t, uint32(8), resp.Permits)
+	assert.Equal(t, &ratio.Limit{Quantity: 10, WindowMs: 60000}, resp.Limit, "the limit of the rule")
+	assert.Equal(t, uint32(2), resp.Remaining)
+	assert.InDelta(t, before.Add(time.Minute).UnixNano()/int64(time.Millisecond), resp.ExpireAtMs, 1000, "the ttl is capped by the window")
+
+	over, err := s.Reserve(ctx, &ratio.ReserveRequest{Owner: "search", Resource: "/q", Permits: 8})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(2), over.Permits)
+
+	over, err = s.Reserve(ctx, &ratio.ReserveRequest{Owner: "search", Resource: "/q", Permits: 8})
+	require.NoError(t, err)
+	assert.Equal(t, ratio.RateLimitResponse_OVER_LIMIT, over.Code)
+	assert.Empty(t, over.LeaseId)
+
+	returned, err := s.Return(ctx, &ratio.ReturnRequest{Owner: "search", Resource: "/q", LeaseId: resp.LeaseId, Unused: 3})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(3), returned.Returned)
+
+	// Other owners get the default limits.
+	resp, err = s.Reserve(ctx, &ratio.ReserveRequest{Owner: "checkout", Resource: "/q", Permits: 8, TtlMs: 1000})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(8), resp.Permits)
+	assert.Equal(t, &ratio.Limit{Quantity: 100, WindowMs: 3600000}, resp.Limit)
+	assert.InDelta(t, time.Now().Add(time.Second).UnixNano()/int64(time.Millisecond), resp.ExpireAtMs, 1000)
+}
+
+func TestQuotaGRPC_Reserve_Checks(t *testing.T) {
+	storage := rate.NewInMemorySlideWindowStorage(make(map[string][]time.Time))
+	lists, err := access.NewLists(access.Config{
+		Allow: []access.Entry{{Owner: "health-checker"}},
+		Deny:  []access.Entry{{Owner: "scraper"}},
+	})
+	require.NoError(t, err)
+	penalty, err := rate.NewPenaltyBox(storage.(rate.PenaltyStorage), rate.PenaltyPolicy{Violations: 1, Window: time.Minute, Ban: time.Hour})
+	require.NoError(t, err)
+
+	limits := rate.Limits{rate.NewLimit(rate.PerMinute, 5)}
+	_, err = rate.PenaltyRateLimiter(noopLimiter(false, nil), penalty)(limits, "abuser", "/q")
+	require.NoError(t, err)
+
+	sink := &recordingSink{}
+	leaser := rate.NewSlideWindowQuotaLeaser(storage.(rate.WeightedSlideWindowStorage))
+	usage := rate.NewUsageAccountant(storage.(rate.UsageStorage), rate.UsageOptions{FlushInterval: time.Hour})
+	defer usage.Close()
+	s := NewQuotaGRPC(limits, nil, time.Hour, leaser, lists, penalty, usage, sink)
+	ctx := context.Background()
+
+	_, err = s.Reserve(ctx, &ratio.ReserveRequest{Owner: "svc", Resource: "/q"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "permits are required")
+
+	resp, err := s.Reserve(ctx, &ratio.ReserveRequest{Owner: "scraper", Resource: "/q", Permits: 1})
+	require.NoError(t, err)
+	assert.Equal(t, ratio.RateLimitResponse_DENIED, resp.Code)
+	assert.Zero(t, resp.Permits)
+
+	resp, err = s.Reserve(ctx, &ratio.ReserveRequest{Owner: "health-checker", Resource: "/q", Permits: 50})
+	require.NoError(t, err)
+	assert.Equal(t, ratio.RateLimitResponse_OK, resp.Code)
+	assert.Equal(t, uint32(5), resp.Permits, "allowed owners are not limited, up to the smallest limit")
+	assert.Empty(t, resp.LeaseId, "nor their permits reserved")
+
+	resp, err = s.Reserve(ctx, &ratio.ReserveRequest{Owner: "abuser", Resource: "/q", Permits: 1})
+	require.NoError(t, err)
+	assert.Equal(t, ratio.RateLimitResponse_OVER_LIMIT, resp.Code, "banned keys can not reserve")
+	assert.Zero(t, resp.Permits)
+
+	resp, err = s.Reserve(ctx, &ratio.ReserveRequest{Owner: "svc", Resource: "/q", Permits: 3})
+	require.NoError(t, err)
+	assert.Equal(t, ratio.RateLimitResponse_OK, resp.Code)
+
+	require.Len(t, sink.events, 4)
+	assert.Equal(t, "scraper", sink.events[0].Owner)
+	assert.Equal(t, audit.DecisionDenied, sink.events[0].Decision)
+	assert.Equal(t, audit.ReasonDenied, sink.events[0].Reason)
+	assert.Equal(t, "health-checker", sink.events[1].Owner)
+	assert.Equal(t, audit.DecisionOK, sink.events[1].Decision)
+	assert.Equal(t, audit.ReasonAllowed, sink.events[1].Reason)
+	assert.Equal(t, "abuser", sink.events[2].Owner)
+	assert.Equal(t, audit.DecisionOverLimit, sink.events[2].Decision)
+	assert.Equal(t, audit.ReasonBanned, sink.events[2].Reason)
+	assert.Equal(t, "svc", sink.events[3].Owner)
+	assert.Equal(t, audit.DecisionOK, sink.events[3].Decision)
+	assert.Empty(t, sink.events[3].Reason)
+	assert.Equal(t, 0, sink.events[3].Count, "the hits before reserving")
+
+	usages, err := usage.Export(time.Now(), time.Now(), "")
+	require.NoError(t, err)
+	for i := range usages {
+		usages[i].Bucket = time.Time{}
+	}
+	assert.ElementsMatch(t, []rate.Usage{
+		{Owner: "scraper", Resource: "/q", Rejected: 1},
+		{Owner: "health-checker", Resource: "/q", Allowed: 5},
+		{Owner: "abuser", Resource: "/q", Rejected: 1},
+		{Owner: "svc", Resource: "/q", Allowed: 3},
+	}, usages, "reserved permits are accounted")
+}
+
+func TestQuotaGRPC_Return_Usage(t *testing.T) {
+	storage := rate.NewInMemorySlideWindowStorage(make(map[string][]time.Time))
+	leaser := rate.NewSlideWindowQuotaLeaser(storage.(rate.WeightedSlideWindowStorage))
+	usage := rate.NewUsageAccountant(storage.(rate.UsageStorage), rate.UsageOptions{FlushInterval: time.Hour})
+	defer usage.Close()
+	s := NewQuotaGRPC(rate.Limits{rate.NewLimit(rate.PerMinute, 10)}, nil, time.Hour, leaser, nil, nil, usage)
+	ctx := context.Background()
+
+	resp, err := s.Reserve(ctx, &ratio.ReserveRequest{Owner: "svc", Resource: "/q", Permits: 8})
+	require.NoError(t, err)
+	require.NoError(t, usage.Flush())
+
+	returned, err := s.Return(ctx, &ratio.ReturnRequest{Owner: "svc", Resource: "/q", LeaseId: resp.LeaseId, Unused: 3})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(3), returned.Returned)
+
+	usages, err := usage.Export(time.Now(), time.Now(), "")
+	require.NoError(t, err)
+	require.Len(t, usages, 1)
+	assert.Equal(t, 5, usages[0].Allowed, "returned permits are taken back")
+}
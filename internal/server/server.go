@@ -3,39 +3,124 @@ package server
 import (
 	"context"
 	"log"
+	"time"
 
+	"github.com/smoya/ratio/internal/access"
+	"github.com/smoya/ratio/internal/audit"
 	"github.com/smoya/ratio/pkg/rate"
 
 	ratio "github.com/smoya/ratio/api/proto"
 )
 
 type grpc struct {
-	limit   rate.Limit
+	limits  rate.Limits
 	limiter rate.Limiter
+	lists   *access.Lists
+	usage   *rate.UsageAccountant
+	sinks   []audit.Sink
 }
 
-// NewGRPC creates a new GRPC RateLimitServiceServer
-func NewGRPC(limit rate.Limit, limiter rate.Limiter) ratio.RateLimitServiceServer {
-	return &grpc{limit: limit, limiter: limiter}
+// NewGRPC creates a new GRPC RateLimitServiceServer. Every hit is checked against the access lists first, if any, then
+// against all the limits, and its decision is emitted to the sinks. Hits in the lists do not reach the limiter, so
+// they are accounted in usage here, if any, and emitted with the list as reason.
+func NewGRPC(limits rate.Limits, limiter rate.Limiter, lists *access.Lists, usage *rate.UsageAccountant, sinks ...audit.Sink) ratio.RateLimitServiceServer {
+	return &grpc{limits: limits, limiter: limiter, lists: lists, usage: usage, sinks: sinks}
 }
 
 // RateLimit implements ratio.RateLimitService
 func (s *grpc) RateLimit(ctx context.Context, r *ratio.RateLimitRequest) (*ratio.RateLimitResponse, error) {
 	log.Printf("RateLimit request: %s -> %s\n", r.Owner, r.Resource)
 
-	ok, err := s.limiter(s.limit, r.Owner, r.Resource)
+	descriptors, err := fromProtoDescriptors(r.Descriptors)
 	if err != nil {
 		return &ratio.RateLimitResponse{
 			Code: ratio.RateLimitResponse_UNKNOWN,
 		}, err
 	}
 
+	if v := s.lists.Check(r.Owner, r.Resource); v != access.None {
+		now, allowed := time.Now(), v == access.Allowed
+		if s.usage != nil {
+			s.usage.Record(r.Owner, r.Resource, allowed, now)
+		}
+		s.emit(audit.NewListEvent(now, r.Owner, r.Resource, descriptors, allowed))
+
+		if allowed {
+			return &ratio.RateLimitResponse{Code: ratio.RateLimitResponse_OK}, nil
+		}
+
+		return &ratio.RateLimitResponse{Code: ratio.RateLimitResponse_DENIED}, nil
+	}
+
+	d, err := s.limiter(s.limits, r.Owner, r.Resource, descriptors...)
+	if err != nil {
+		return &ratio.RateLimitResponse{
+			Code: ratio.RateLimitResponse_UNKNOWN,
+		}, err
+	}
+
+	if len(s.sinks) > 0 {
+		s.emit(audit.NewEvent(time.Now(), r.Owner, r.Resource, descriptors, d))
+	}
+
 	code := ratio.RateLimitResponse_OK
-	if !ok {
+	if !d.Allowed {
 		code = ratio.RateLimitResponse_OVER_LIMIT
 	}
 
-	return &ratio.RateLimitResponse{
-		Code: code,
-	}, nil
+	resp := &ratio.RateLimitResponse{
+		Code:      code,
+		Remaining: uint32(d.Remaining()),
+		Level:     toProtoLevel(d.Level),
+		Period:    toProtoPeriod(d.Period),
+	}
+	if !d.BannedUntil.IsZero() {
+		resp.BannedUntilMs = d.BannedUntil.UnixNano() / int64(time.Millisecond)
+	}
+	// Banned hits, and the ones rejected by a period, are rejected without checking the limits.
+	if d.Limit != (rate.Limit{}) {
+		resp.Limit = toProtoLimit(d.Limit)
+	}
+
+	return resp, nil
+}
+
+func (s *grpc) emit(e audit.Event) {
+	for _, sink := range s.sinks {
+		sink.Emit(e)
+	}
+}
+
+func toProtoPeriod(u *rate.PeriodUsage) *ratio.PeriodLimit {
+	if u == nil {
+		return nil
+	}
+
+	return &ratio.PeriodLimit{
+		Quantity:  uint32(u.Limit.Quantity),
+		Period:    u.Limit.Period.String(),
+		Remaining: uint32(u.Remaining()),
+		StartMs:   u.Start.UnixNano() / int64(time.Millisecond),
+		ResetAtMs: u.End.UnixNano() / int64(time.Millisecond),
+	}
+}
+
+func toProtoLevel(l rate.Level) ratio.RateLimitResponse_Level {
+	switch l {
+	case rate.LevelGlobal:
+		return ratio.RateLimitResponse_GLOBAL
+	case rate.LevelOwner:
+		return ratio.RateLimitResponse_OWNER
+	case rate.LevelResource:
+		return ratio.RateLimitResponse_RESOURCE
+	}
+
+	return ratio.RateLimitResponse_KEY
+}
+
+func toProtoLimit(l rate.Limit) *ratio.Limit {
+	return &ratio.Limit{
+		Quantity: uint32(l.Quantity),
+		WindowMs: int64(l.Unit.Duration() / time.Millisecond),
+	}
 }
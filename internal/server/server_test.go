@@ -4,42 +4,231 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/smoya/ratio/internal/access"
+	"github.com/smoya/ratio/internal/audit"
 	"github.com/smoya/ratio/pkg/rate"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	ratio "github.com/smoya/ratio/api/proto"
 )
 
 func noopLimiter(ok bool, err error) rate.Limiter {
-	return func(_ rate.Limit, _, _ string) (bool, error) {
-		return ok, err
+	return func(l rate.Limits, _, _ string, _ ...rate.Descriptor) (rate.Decision, error) {
+		hits := 2
+		if !ok {
+			hits = l[0].Quantity
+		}
+
+		return rate.Decision{Allowed: ok, Limit: l[0], Hits: hits}, err
 	}
 }
 
 func TestGRPC_RateLimit(t *testing.T) {
 	cases := []struct {
-		code ratio.RateLimitResponse_Code
-		ok   bool
-		err  error
+		code      ratio.RateLimitResponse_Code
+		remaining uint32
+		ok        bool
+		err       error
 	}{
-		{code: ratio.RateLimitResponse_OK, ok: true},
+		{code: ratio.RateLimitResponse_OK, remaining: 2, ok: true},
 		{code: ratio.RateLimitResponse_OVER_LIMIT, ok: false},
 		{code: ratio.RateLimitResponse_UNKNOWN, err: errors.New("whatever error")},
 	}
 
 	for _, c := range cases {
-		s := NewGRPC(rate.NewLimit(rate.PerMinute, 5), noopLimiter(c.ok, c.err))
+		s := NewGRPC(rate.Limits{rate.NewLimit(rate.PerMinute, 5)}, noopLimiter(c.ok, c.err), nil, nil)
 		resp, err := s.RateLimit(context.Background(), &ratio.RateLimitRequest{})
 
 		if c.err != nil {
 			assert.EqualError(t, err, c.err.Error())
 		} else {
 			assert.NoError(t, err)
+			assert.Equal(t, &ratio.Limit{Quantity: 5, WindowMs: 60000}, resp.Limit)
+			assert.Equal(t, c.remaining, resp.Remaining)
 		}
 
 		assert.Equal(t, c.code, resp.Code)
 	}
 
 }
+
+func TestGRPC_RateLimit_Level(t *testing.T) {
+	limiter := rate.HierarchicalRateLimiter(
+		rate.SlideWindowRateLimiter(rate.NewInMemorySlideWindowStorage(make(map[string][]time.Time))),
+		rate.Hierarchy{Owner: rate.Limits{rate.NewLimit(rate.PerMinute, 1)}},
+	)
+	s := NewGRPC(rate.Limits{rate.NewLimit(rate.PerMinute, 5)}, limiter, nil, nil)
+
+	resp, err := s.RateLimit(context.Background(), &ratio.RateLimitRequest{Owner: "svc", Resource: "/pay"})
+	assert.NoError(t, err)
+	assert.Equal(t, ratio.RateLimitResponse_OK, resp.Code)
+	assert.Equal(t, ratio.RateLimitResponse_OWNER, resp.Level)
+
+	resp, err = s.RateLimit(context.Background(), &ratio.RateLimitRequest{Owner: "svc", Resource: "/orders"})
+	assert.NoError(t, err)
+	assert.Equal(t, ratio.RateLimitResponse_OVER_LIMIT, resp.Code)
+	assert.Equal(t, ratio.RateLimitResponse_OWNER, resp.Level)
+	assert.Equal(t, &ratio.Limit{Quantity: 1, WindowMs: 60000}, resp.Limit)
+}
+
+func TestGRPC_RateLimit_Descriptors(t *testing.T) {
+	var received []rate.Descriptor
+	s := NewGRPC(rate.Limits{rate.NewLimit(rate.PerMinute, 5)}, func(l rate.Limits, _, _ string, descriptors ...rate.Descriptor) (rate.Decision, error) {
+		received = descriptors
+		return rate.Decision{Allowed: true, Limit: l[0]}, nil
+	}, nil, nil)
+
+	_, err := s.RateLimit(context.Background(), &ratio.RateLimitRequest{
+		Owner:       "svc",
+		Resource:    "/pay",
+		Descriptors: []*ratio.Descriptor{{Key: "customer", Value: "c1"}, {Key: "plan", Value: "free"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []rate.Descriptor{{Key: "customer", Value: "c1"}, {Key: "plan", Value: "free"}}, received)
+
+	_, err = s.RateLimit(context.Background(), &ratio.RateLimitRequest{
+		Descriptors: []*ratio.Descriptor{{Key: rate.LevelDescriptor, Value: "global"}},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGRPC_RateLimit_Period(t *testing.T) {
+	storage := rate.NewInMemorySlideWindowStorage(make(map[string][]time.Time))
+	period := rate.Period{Unit: rate.Monthly, BillingDay: 15}
+	limiter := rate.PeriodRateLimiter(
+		rate.SlideWindowRateLimiter(storage),
+		storage.(rate.CounterStorage),
+		rate.PeriodLimits{{Period: period, Quantity: 2}},
+		nil,
+	)
+	s := NewGRPC(rate.Limits{rate.NewLimit(rate.PerMinute, 5)}, limiter, nil, nil)
+	start, end := period.Bounds(time.Now())
+
+	resp, err := s.RateLimit(context.Background(), &ratio.RateLimitRequest{Owner: "svc", Resource: "/pay"})
+	assert.NoError(t, err)
+	assert.Equal(t, ratio.RateLimitResponse_OK, resp.Code)
+	assert.Equal(t, &ratio.PeriodLimit{
+		Quantity:  2,
+		Period:    "month@15",
+		Remaining: 1,
+		StartMs:   start.UnixNano() / int64(time.Millisecond),
+		ResetAtMs: end.UnixNano() / int64(time.Millisecond),
+	}, resp.Period)
+
+	_, err = s.RateLimit(context.Background(), &ratio.RateLimitRequest{Owner: "svc", Resource: "/pay"})
+	assert.NoError(t, err)
+
+	resp, err = s.RateLimit(context.Background(), &ratio.RateLimitRequest{Owner: "svc", Resource: "/pay"})
+	assert.NoError(t, err)
+	assert.Equal(t, ratio.RateLimitResponse_OVER_LIMIT, resp.Code)
+	assert.Equal(t, uint32(0), resp.Period.Remaining)
+	assert.Nil(t, resp.Limit, "the window is not checked")
+
+	hits, err := storage.Count(rate.Key("svc", "/pay"), time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, hits, "the hit rejected by the period does not count in the window")
+}
+
+type recordingSink struct {
+	events []audit.Event
+}
+
+func (s *recordingSink) Emit(e audit.Event) {
+	s.events = append(s.events, e)
+}
+
+func TestGRPC_RateLimit_Events(t *testing.T) {
+	sink := &recordingSink{}
+	s := NewGRPC(rate.Limits{rate.NewLimit(rate.PerMinute, 5)}, noopLimiter(false, nil), nil, nil, sink)
+
+	_, err := s.RateLimit(context.Background(), &ratio.RateLimitRequest{
+		Owner:       "svc",
+		Resource:    "/pay",
+		Descriptors: []*ratio.Descriptor{{Key: "customer", Value: "c1"}},
+	})
+	assert.NoError(t, err)
+
+	require.Len(t, sink.events, 1)
+	e := sink.events[0]
+	assert.Equal(t, "svc", e.Owner)
+	assert.Equal(t, "/pay", e.Resource)
+	assert.Equal(t, []rate.Descriptor{{Key: "customer", Value: "c1"}}, e.Descriptors)
+	assert.Equal(t, audit.DecisionOverLimit, e.Decision)
+	assert.Equal(t, audit.Limit{Quantity: 5, WindowMs: 60000}, e.Limit)
+	assert.Equal(t, 5, e.Count)
+	assert.WithinDuration(t, time.Now(), e.Time, time.Second)
+
+	s = NewGRPC(rate.Limits{rate.NewLimit(rate.PerMinute, 5)}, noopLimiter(true, errors.New("whatever error")), nil, nil, sink)
+	_, err = s.RateLimit(context.Background(), &ratio.RateLimitRequest{Owner: "svc"})
+	assert.Error(t, err)
+	assert.Len(t, sink.events, 1, "failed decisions should not be emitted")
+}
+
+func TestGRPC_RateLimit_AccessLists(t *testing.T) {
+	lists, err := access.NewLists(access.Config{
+		Allow: []access.Entry{{Owner: "health-checker"}},
+		Deny:  []access.Entry{{Owner: "10.6.6.0/24"}},
+	})
+	require.NoError(t, err)
+
+	calls := 0
+	sink := &recordingSink{}
+	storage := rate.NewInMemorySlideWindowStorage(make(map[string][]time.Time))
+	usage := rate.NewUsageAccountant(storage.(rate.UsageStorage), rate.UsageOptions{FlushInterval: time.Hour})
+	defer usage.Close()
+	s := NewGRPC(rate.Limits{rate.NewLimit(rate.PerMinute, 5)}, func(l rate.Limits, _, _ string, _ ...rate.Descriptor) (rate.Decision, error) {
+		calls++
+		return rate.Decision{Allowed: false, Limit: l[0], Hits: 5}, nil
+	}, lists, usage, sink)
+
+	resp, err := s.RateLimit(context.Background(), &ratio.RateLimitRequest{Owner: "10.6.6.6", Resource: "/pay"})
+	assert.NoError(t, err)
+	assert.Equal(t, ratio.RateLimitResponse_DENIED, resp.Code)
+
+	resp, err = s.RateLimit(context.Background(), &ratio.RateLimitRequest{Owner: "health-checker", Resource: "/pay"})
+	assert.NoError(t, err)
+	assert.Equal(t, ratio.RateLimitResponse_OK, resp.Code)
+	assert.Equal(t, 0, calls, "hits in the lists should not reach the limiter")
+
+	require.Len(t, sink.events, 2, "hits in the lists should be emitted")
+	assert.Equal(t, "10.6.6.6", sink.events[0].Owner)
+	assert.Equal(t, audit.DecisionDenied, sink.events[0].Decision)
+	assert.Equal(t, audit.ReasonDenied, sink.events[0].Reason)
+	assert.Equal(t, "health-checker", sink.events[1].Owner)
+	assert.Equal(t, audit.DecisionOK, sink.events[1].Decision)
+	assert.Equal(t, audit.ReasonAllowed, sink.events[1].Reason)
+
+	usages, err := usage.Export(time.Now(), time.Now(), "")
+	require.NoError(t, err)
+	for i := range usages {
+		usages[i].Bucket = time.Time{}
+	}
+	assert.ElementsMatch(t, []rate.Usage{
+		{Owner: "10.6.6.6", Resource: "/pay", Rejected: 1},
+		{Owner: "health-checker", Resource: "/pay", Allowed: 1},
+	}, usages, "hits in the lists should be accounted")
+
+	resp, err = s.RateLimit(context.Background(), &ratio.RateLimitRequest{Owner: "checkout", Resource: "/pay"})
+	assert.NoError(t, err)
+	assert.Equal(t, ratio.RateLimitResponse_OVER_LIMIT, resp.Code)
+	assert.Equal(t, 1, calls)
+}
+
+func TestGRPC_RateLimit_Banned(t *testing.T) {
+	until := time.Now().Add(time.Minute)
+	s := NewGRPC(rate.Limits{rate.NewLimit(rate.PerMinute, 5)}, func(l rate.Limits, _, _ string, _ ...rate.Descriptor) (rate.Decision, error) {
+		return rate.Decision{BannedUntil: until}, nil
+	}, nil, nil)
+
+	resp, err := s.RateLimit(context.Background(), &ratio.RateLimitRequest{Owner: "svc", Resource: "/pay"})
+	assert.NoError(t, err)
+	assert.Equal(t, ratio.RateLimitResponse_OVER_LIMIT, resp.Code)
+	assert.Equal(t, until.UnixNano()/int64(time.Millisecond), resp.BannedUntilMs)
+	assert.Nil(t, resp.Limit, "banned hits are not checked against any limit")
+}
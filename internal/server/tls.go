@@ -0,0 +1,290 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	ratio "github.com/smoya/ratio/api/proto"
+)
+
+// CertReloader keeps a certificate key pair and, optionally, a client CA loaded from disk, reloading them whenever
+// the files change, so certificates can be rotated without restarting.
+type CertReloader struct {
+	certFile, keyFile, caFile string
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	pool     *x509.CertPool
+	modTimes map[string]time.Time
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewCertReloader loads the certificate key pair and the client CA. caFile is optional; when set, clients are required
+// to present a certificate signed by it (mutual TLS).
+func NewCertReloader(certFile, keyFile, caFile string) (*CertReloader, error) {
+	r := &CertReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		caFile:   caFile,
+		modTimes: make(map[string]time.Time),
+		done:     make(chan struct{}),
+	}
+
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Reload loads the files again in case any of them changed since the last load. Returns whether they were reloaded.
+// On error, the previous certificates are kept.
+func (r *CertReloader) Reload() (bool, error) {
+	modTimes := make(map[string]time.Time)
+	changed := false
+	for _, f := range []string{r.certFile, r.keyFile, r.caFile} {
+		if f == "" {
+			continue
+		}
+
+		info, err := os.Stat(f)
+		if err != nil {
+			return false, err
+		}
+
+		modTimes[f] = info.ModTime()
+
+		r.mu.RLock()
+		if !info.ModTime().Equal(r.modTimes[f]) {
+			changed = true
+		}
+		r.mu.RUnlock()
+	}
+
+	if !changed {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, err
+	}
+
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		pem, err := ioutil.ReadFile(r.caFile)
+		if err != nil {
+			return false, err
+		}
+
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return false, fmt.Errorf("no certificates found in %s", r.caFile)
+		}
+	}
+
+	if cert.Leaf == nil {
+		cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return false, err
+		}
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.pool = pool
+	r.modTimes = modTimes
+	r.mu.Unlock()
+
+	return true, nil
+}
+
+// Identity returns the identity of the loaded certificate.
+func (r *CertReloader) Identity() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return identity(r.cert.Leaf)
+}
+
+// ServerConfig returns a TLS config for servers, always using the last loaded files.
+func (r *CertReloader) ServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+
+			c := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*r.cert},
+			}
+
+			if r.pool != nil {
+				c.ClientAuth = tls.RequireAndVerifyClientCert
+				c.ClientCAs = r.pool
+			}
+
+			return c, nil
+		},
+	}
+}
+
+// ClientConfig returns a TLS config for connecting to other ratio instances. The loaded certificate is presented
+// as client certificate and, if loaded, the client CA is used for verifying the server. Both are looked up on every
+// handshake, so rotated certificates and CAs reach the connections opened afterwards.
+func (r *CertReloader) ClientConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// The server is verified by VerifyConnection against the current CA instead of a RootCAs copied here.
+		InsecureSkipVerify: true,
+		VerifyConnection:   r.verifyServer,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+
+			return r.cert, nil
+		},
+	}
+}
+
+// verifyServer verifies the certificate chain of a server against the current CA, or the system roots if no CA is
+// loaded.
+func (r *CertReloader) verifyServer(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("the server presented no certificate")
+	}
+
+	r.mu.RLock()
+	pool := r.pool
+	r.mu.RUnlock()
+
+	opts := x509.VerifyOptions{
+		Roots:         pool,
+		DNSName:       cs.ServerName,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, c := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(c)
+	}
+
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+// Start reloads the files every interval until Close is called.
+func (r *CertReloader) Start(interval time.Duration) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-r.done:
+				return
+			case <-t.C:
+				reloaded, err := r.Reload()
+				if err != nil {
+					log.Printf("error reloading certificates: %s\n", err.Error())
+				} else if reloaded {
+					log.Println("certificates reloaded")
+				}
+			}
+		}
+	}()
+}
+
+// Close stops reloading the files.
+func (r *CertReloader) Close() error {
+	close(r.done)
+	r.wg.Wait()
+
+	return nil
+}
+
+// PeerIdentity returns the identity of the client certificate of the caller, if any.
+// The identity is the certificate Common Name or, if empty, its first URI or DNS Subject Alternative Name.
+func PeerIdentity(ctx context.Context) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", false
+	}
+
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+
+	id := identity(info.State.VerifiedChains[0][0])
+	return id, id != ""
+}
+
+func identity(cert *x509.Certificate) string {
+	switch {
+	case cert == nil:
+		return ""
+	case cert.Subject.CommonName != "":
+		return cert.Subject.CommonName
+	case len(cert.URIs) > 0:
+		return cert.URIs[0].String()
+	case len(cert.DNSNames) > 0:
+		return cert.DNSNames[0]
+	}
+
+	return ""
+}
+
+// OwnerFromPeerIdentityInterceptor sets the owner of every request acting for one (RateLimit, Acquire, Release, Reserve
+// and Return) to the identity of the client certificate of the caller, so the owner can not be spoofed. Requests
+// without a verified certificate are rejected.
+// Requests coming from a peer of the cluster (other ratio instances forwarding requests, see FromPeer) keep their
+// owner. It should run before any authorization, so the owner authorized is the one of the certificate.
+func OwnerFromPeerIdentityInterceptor() gogrpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *gogrpc.UnaryServerInfo, handler gogrpc.UnaryHandler) (interface{}, error) {
+		var owner *string
+		switch r := req.(type) {
+		case *ratio.RateLimitRequest:
+			owner = &r.Owner
+		case *ratio.AcquireRequest:
+			owner = &r.Owner
+		case *ratio.ReleaseRequest:
+			owner = &r.Owner
+		case *ratio.ReserveRequest:
+			owner = &r.Owner
+		case *ratio.ReturnRequest:
+			owner = &r.Owner
+		default:
+			return handler(ctx, req)
+		}
+
+		id, ok := PeerIdentity(ctx)
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "a client certificate is required")
+		}
+
+		if !FromPeer(ctx) {
+			*owner = id
+		}
+
+		return handler(ctx, req)
+	}
+}
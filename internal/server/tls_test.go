@@ -0,0 +1,238 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/smoya/ratio/pkg/cluster"
+
+	ratio "github.com/smoya/ratio/api/proto"
+)
+
+func TestCertReloader_Reload(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	ca, caKey := createCA(t, dir)
+	certFile, keyFile := createCert(t, dir, "server", "ratio", ca, caKey)
+
+	r, err := NewCertReloader(certFile, keyFile, filepath.Join(dir, "ca.pem"))
+	assert.NoError(t, err)
+	assert.Equal(t, "ratio", r.Identity())
+
+	reloaded, err := r.Reload()
+	assert.NoError(t, err)
+	assert.False(t, reloaded, "files did not change")
+
+	createCert(t, dir, "server", "ratio-rotated", ca, caKey)
+	future := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(certFile, future, future))
+
+	reloaded, err = r.Reload()
+	assert.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.Equal(t, "ratio-rotated", r.Identity())
+
+	assert.NoError(t, ioutil.WriteFile(keyFile, []byte("broken"), 0600))
+	assert.NoError(t, os.Chtimes(keyFile, future.Add(time.Minute), future.Add(time.Minute)))
+	_, err = r.Reload()
+	assert.Error(t, err)
+	assert.Equal(t, "ratio-rotated", r.Identity(), "previous certificates should be kept on error")
+}
+
+func TestOwnerFromPeerIdentityInterceptor(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	ca, caKey := createCA(t, dir)
+	serverCert, serverKey := createCert(t, dir, "server", "ratio", ca, caKey)
+	clientCert, clientKey := createCert(t, dir, "client", "my-awesome-service", ca, caKey)
+
+	r, err := NewCertReloader(serverCert, serverKey, filepath.Join(dir, "ca.pem"))
+	assert.NoError(t, err)
+
+	recorder := &recorderServer{}
+	srv := gogrpc.NewServer(
+		gogrpc.Creds(credentials.NewTLS(r.ServerConfig())),
+		gogrpc.UnaryInterceptor(ChainUnaryInterceptors(
+			ClusterSecretInterceptor("cluster-s3cr3t"),
+			OwnerFromPeerIdentityInterceptor(),
+		)),
+	)
+	ratio.RegisterRateLimitServiceServer(srv, recorder)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go func() { _ = srv.Serve(l) }()
+	defer srv.Stop()
+
+	call := func(certFile, keyFile, owner string, opts ...gogrpc.DialOption) error {
+		pool := x509.NewCertPool()
+		pool.AddCert(ca)
+
+		c := &tls.Config{RootCAs: pool, ServerName: "localhost"}
+		if certFile != "" {
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			assert.NoError(t, err)
+			c.Certificates = []tls.Certificate{cert}
+		}
+
+		conn, err := gogrpc.Dial(l.Addr().String(), append(opts, gogrpc.WithTransportCredentials(credentials.NewTLS(c)))...)
+		assert.NoError(t, err)
+		defer conn.Close()
+
+		_, err = ratio.NewRateLimitServiceClient(conn).RateLimit(context.Background(), &ratio.RateLimitRequest{Owner: owner})
+		return err
+	}
+
+	assert.NoError(t, call(clientCert, clientKey, "spoofed-service"))
+	assert.NoError(t, call(serverCert, serverKey, "spoofed-service"), "sharing the identity of the server is not enough")
+	assert.NoError(t, call(serverCert, serverKey, "forwarded-service", gogrpc.WithPerRPCCredentials(cluster.SecretCredentials("cluster-s3cr3t"))), "peers of the cluster keep the owner")
+	assert.Error(t, call("", "", "spoofed-service"), "a client certificate is required")
+
+	assert.Equal(t, []string{"my-awesome-service", "ratio", "forwarded-service"}, recorder.received)
+}
+
+func TestCertReloader_ClientConfig(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	ca, caKey := createCA(t, dir)
+	serverCert, serverKey := createCert(t, dir, "server", "ratio", ca, caKey)
+	clientCert, clientKey := createCert(t, dir, "client", "ratio", ca, caKey)
+
+	srv, err := NewCertReloader(serverCert, serverKey, "")
+	assert.NoError(t, err)
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", srv.ServerConfig())
+	assert.NoError(t, err)
+	defer l.Close()
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			_ = conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	client, err := NewCertReloader(clientCert, clientKey, filepath.Join(dir, "ca.pem"))
+	assert.NoError(t, err)
+
+	c := client.ClientConfig()
+	c.ServerName = "localhost"
+	handshake := func() error {
+		conn, err := tls.Dial("tcp", l.Addr().String(), c)
+		if err != nil {
+			return err
+		}
+
+		return conn.Close()
+	}
+
+	assert.NoError(t, handshake())
+
+	c.ServerName = "ratio.example.com"
+	assert.Error(t, handshake(), "the server name is verified")
+	c.ServerName = "localhost"
+
+	future := time.Now().Add(time.Minute)
+	rotatedCA, rotatedKey := createCA(t, dir)
+	assert.NoError(t, os.Chtimes(filepath.Join(dir, "ca.pem"), future, future))
+	_, err = client.Reload()
+	assert.NoError(t, err)
+
+	assert.Error(t, handshake(), "the server certificate is not signed by the rotated CA")
+
+	createCert(t, dir, "server", "ratio", rotatedCA, rotatedKey)
+	assert.NoError(t, os.Chtimes(serverCert, future, future))
+	_, err = srv.Reload()
+	assert.NoError(t, err)
+
+	assert.NoError(t, handshake(), "the rotated CA is used without creating the config again")
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "ratio")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+func createCA(t *testing.T, dir string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ratio-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.NoError(t, err)
+	writePEM(t, filepath.Join(dir, "ca.pem"), "CERTIFICATE", der)
+
+	ca, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+
+	return ca, key
+}
+
+func createCert(t *testing.T, dir, name, cn string, ca *x509.Certificate, caKey *ecdsa.PrivateKey) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	assert.NoError(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	certFile, keyFile := filepath.Join(dir, name+".pem"), filepath.Join(dir, name+"-key.pem")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDer)
+
+	return certFile, keyFile
+}
+
+func writePEM(t *testing.T, file, typ string, der []byte) {
+	assert.NoError(t, ioutil.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0600))
+}
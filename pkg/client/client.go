@@ -0,0 +1,251 @@
+// Package client provides a Go client for ratio, with retries, timeouts, a configurable behaviour when ratio is
+// unreachable and a local cache of OVER_LIMIT decisions.
+package client
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	ratio "github.com/smoya/ratio/api/proto"
+)
+
+// Default Options.
+const (
+	DefaultTimeout         = 100 * time.Millisecond
+	DefaultRetries         = 2
+	DefaultBackoff         = 10 * time.Millisecond
+	DefaultMaxBackoff      = 200 * time.Millisecond
+	DefaultMaxCacheEntries = 10000
+)
+
+// Options configures a Client.
+type Options struct {
+	// Timeout is the timeout of each call to ratio, retries included.
+	Timeout time.Duration
+	// Retries is the max number of retries of a call when ratio is unreachable. Use a negative value for no retries.
+	Retries int
+	// Backoff is the base wait before the first retry. It doubles on each retry, with jitter, up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// FailOpen makes the client allow the requests when ratio is unreachable. Otherwise, they are considered over limit.
+	FailOpen bool
+	// CacheTTL is the time an OVER_LIMIT decision is cached locally, so ratio is not called again for the same
+	// owner and resource meanwhile. Zero disables the cache.
+	CacheTTL time.Duration
+	// MaxCacheEntries is the max number of decisions cached.
+	MaxCacheEntries int
+	// DialOptions are used when dialing ratio. Defaults to an insecure connection.
+	DialOptions []grpc.DialOption
+}
+
+func (o *Options) setDefaults() {
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultTimeout
+	}
+
+	if o.Retries == 0 {
+		o.Retries = DefaultRetries
+	}
+
+	if o.Backoff <= 0 {
+		o.Backoff = DefaultBackoff
+	}
+
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = DefaultMaxBackoff
+	}
+
+	if o.MaxCacheEntries <= 0 {
+		o.MaxCacheEntries = DefaultMaxCacheEntries
+	}
+
+	if len(o.DialOptions) == 0 {
+		o.DialOptions = []grpc.DialOption{grpc.WithInsecure()}
+	}
+}
+
+// Client is a ratio client. It is safe for concurrent use.
+type Client struct {
+	rpc   ratio.RateLimitServiceClient
+	quota ratio.QuotaServiceClient
+	conn  *grpc.ClientConn
+	o     Options
+
+	mu    sync.Mutex
+	cache map[string]cached
+}
+
+type cached struct {
+	resp      *ratio.RateLimitResponse
+	expiresAt time.Time
+}
+
+// New creates a Client connected to the ratio server at target (e.g. localhost:50051).
+// The connection is established in the background and re-established when lost.
+func New(target string, o Options) (*Client, error) {
+	o.setDefaults()
+
+	conn, err := grpc.Dial(target, o.DialOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	c := NewFromRPC(ratio.NewRateLimitServiceClient(conn), o)
+	c.quota = ratio.NewQuotaServiceClient(conn)
+	c.conn = conn
+
+	return c, nil
+}
+
+// NewFromRPC creates a Client on top of an existing RateLimitServiceClient.
+func NewFromRPC(rpc ratio.RateLimitServiceClient, o Options) *Client {
+	o.setDefaults()
+
+	return &Client{
+		rpc:   rpc,
+		o:     o,
+		cache: make(map[string]cached),
+	}
+}
+
+// Allow returns whether a hit of the owner on the resource, with the given descriptors, is allowed.
+// If ratio is unreachable, the decision depends on Options.FailOpen, and the error is returned as well.
+func (c *Client) Allow(ctx context.Context, owner, resource string, descriptors ...*ratio.Descriptor) (bool, error) {
+	resp, err := c.RateLimit(ctx, &ratio.RateLimitRequest{Owner: owner, Resource: resource, Descriptors: descriptors})
+	return resp.GetCode() == ratio.RateLimitResponse_OK, err
+}
+
+// RateLimit calls ratio, retrying when it is unreachable. It implements ratio.RateLimitServiceClient.
+// If ratio is still unreachable, a response with code OK (fail open) or OVER_LIMIT (fail closed) is returned along with
+// the error. Other errors are returned with an UNKNOWN response.
+func (c *Client) RateLimit(ctx context.Context, r *ratio.RateLimitRequest, opts ...grpc.CallOption) (*ratio.RateLimitResponse, error) {
+	key := r.Owner + "\x00" + r.Resource
+	for _, d := range r.Descriptors {
+		key += "\x00" + d.Key + "\x00" + d.Value
+	}
+	if resp, ok := c.lookup(key); ok {
+		return resp, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.o.Timeout)
+	defer cancel()
+
+	var (
+		resp *ratio.RateLimitResponse
+		err  error
+	)
+	for attempt := 0; ; attempt++ {
+		resp, err = c.rpc.RateLimit(ctx, r, opts...)
+		if err == nil || !retryable(err) || attempt >= c.o.Retries || !c.wait(ctx, attempt) {
+			break
+		}
+	}
+
+	switch {
+	case err == nil:
+		if resp.Code == ratio.RateLimitResponse_OVER_LIMIT {
+			c.store(key, resp)
+		}
+
+		return resp, nil
+	case retryable(err) && c.o.FailOpen:
+		return &ratio.RateLimitResponse{Code: ratio.RateLimitResponse_OK}, err
+	case retryable(err):
+		return &ratio.RateLimitResponse{Code: ratio.RateLimitResponse_OVER_LIMIT}, err
+	}
+
+	return &ratio.RateLimitResponse{Code: ratio.RateLimitResponse_UNKNOWN}, err
+}
+
+// Close closes the connection to ratio, if it was created by the Client.
+func (c *Client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+
+	return c.conn.Close()
+}
+
+// wait sleeps before retrying, with exponential backoff and jitter. Returns false if ctx is done meanwhile.
+func (c *Client) wait(ctx context.Context, attempt int) bool {
+	backoff := c.o.Backoff << uint(attempt)
+	if backoff > c.o.MaxBackoff || backoff <= 0 {
+		backoff = c.o.MaxBackoff
+	}
+
+	// Full jitter between backoff/2 and backoff.
+	backoff = backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+
+	t := time.NewTimer(backoff)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+func (c *Client) lookup(key string) (*ratio.RateLimitResponse, bool) {
+	if c.o.CacheTTL <= 0 {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.cache[key]
+	if !ok {
+		return nil, false
+	}
+
+	if time.Now().After(entry.expiresAt) {
+		delete(c.cache, key)
+		return nil, false
+	}
+
+	return entry.resp, true
+}
+
+func (c *Client) store(key string, resp *ratio.RateLimitResponse) {
+	if c.o.CacheTTL <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if len(c.cache) >= c.o.MaxCacheEntries {
+		for k, entry := range c.cache {
+			if now.After(entry.expiresAt) {
+				delete(c.cache, k)
+			}
+		}
+
+		if len(c.cache) >= c.o.MaxCacheEntries {
+			return
+		}
+	}
+
+	c.cache[key] = cached{resp: resp, expiresAt: now.Add(c.o.CacheTTL)}
+}
+
+// retryable returns whether the error means ratio is unreachable or overloaded.
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Aborted:
+		return true
+	}
+
+	return false
+}
+
+var _ ratio.RateLimitServiceClient = (*Client)(nil)
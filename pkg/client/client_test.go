@@ -0,0 +1,153 @@
+package client
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	ratio "github.com/smoya/ratio/api/proto"
+)
+
+// fakeRPC answers with the given responses and errors in order, repeating the last one.
+type fakeRPC struct {
+	codes []ratio.RateLimitResponse_Code
+	errs  []error
+	calls int
+}
+
+func (f *fakeRPC) RateLimit(_ context.Context, _ *ratio.RateLimitRequest, _ ...grpc.CallOption) (*ratio.RateLimitResponse, error) {
+	i := f.calls
+	f.calls++
+
+	if i >= len(f.errs) {
+		i = len(f.errs) - 1
+	}
+
+	if f.errs[i] != nil {
+		return nil, f.errs[i]
+	}
+
+	return &ratio.RateLimitResponse{Code: f.codes[i]}, nil
+}
+
+func TestClient_Allow(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+	denied := status.Error(codes.PermissionDenied, "denied")
+
+	cases := []struct {
+		desc     string
+		rpc      *fakeRPC
+		failOpen bool
+		ok       bool
+		err      bool
+		calls    int
+	}{
+		{
+			desc:  "OK",
+			rpc:   &fakeRPC{codes: []ratio.RateLimitResponse_Code{ratio.RateLimitResponse_OK}, errs: []error{nil}},
+			ok:    true,
+			calls: 1,
+		},
+		{
+			desc:  "Over limit",
+			rpc:   &fakeRPC{codes: []ratio.RateLimitResponse_Code{ratio.RateLimitResponse_OVER_LIMIT}, errs: []error{nil}},
+			ok:    false,
+			calls: 1,
+		},
+		{
+			desc:  "Retried until reachable",
+			rpc:   &fakeRPC{codes: []ratio.RateLimitResponse_Code{0, 0, ratio.RateLimitResponse_OK}, errs: []error{unavailable, unavailable, nil}},
+			ok:    true,
+			calls: 3,
+		},
+		{
+			desc:     "Unreachable, fail open",
+			rpc:      &fakeRPC{errs: []error{unavailable}},
+			failOpen: true,
+			ok:       true,
+			err:      true,
+			calls:    3,
+		},
+		{
+			desc:  "Unreachable, fail closed",
+			rpc:   &fakeRPC{errs: []error{unavailable}},
+			ok:    false,
+			err:   true,
+			calls: 3,
+		},
+		{
+			desc:     "Not retryable",
+			rpc:      &fakeRPC{errs: []error{denied}},
+			failOpen: true,
+			ok:       false,
+			err:      true,
+			calls:    1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			client := NewFromRPC(c.rpc, Options{Backoff: time.Millisecond, FailOpen: c.failOpen})
+
+			ok, err := client.Allow(context.Background(), "myservice", "resource1")
+			if c.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, c.ok, ok)
+			assert.Equal(t, c.calls, c.rpc.calls)
+		})
+	}
+}
+
+func TestClient_Cache(t *testing.T) {
+	rpc := &fakeRPC{
+		codes: []ratio.RateLimitResponse_Code{ratio.RateLimitResponse_OVER_LIMIT, ratio.RateLimitResponse_OK},
+		errs:  []error{nil, nil},
+	}
+	client := NewFromRPC(rpc, Options{CacheTTL: 20 * time.Millisecond})
+
+	for i := 0; i < 3; i++ {
+		ok, err := client.Allow(context.Background(), "myservice", "resource1")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	}
+	assert.Equal(t, 1, rpc.calls, "OVER_LIMIT decisions should be cached")
+
+	time.Sleep(30 * time.Millisecond)
+
+	ok, err := client.Allow(context.Background(), "myservice", "resource1")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 2, rpc.calls)
+
+	ok, err = client.Allow(context.Background(), "myservice", "resource1")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 3, rpc.calls, "OK decisions should not be cached")
+}
+
+func TestClient_Timeout(t *testing.T) {
+	// A listener that never answers.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer l.Close()
+
+	client, err := New(l.Addr().String(), Options{Timeout: 20 * time.Millisecond, FailOpen: true})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	started := time.Now()
+	ok, err := client.Allow(context.Background(), "myservice", "resource1")
+	assert.Error(t, err)
+	assert.True(t, ok)
+	assert.True(t, time.Since(started) < time.Second, "calls should time out")
+}
@@ -0,0 +1,121 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	ratio "github.com/smoya/ratio/api/proto"
+)
+
+// ErrQuotaUnsupported is returned when reserving permits with a Client not connected through New.
+var ErrQuotaUnsupported = errors.New("the client has no connection to the ratio quota service")
+
+// Lease is a batch of permits reserved in ratio, consumed locally with Take. It is safe for concurrent use.
+type Lease struct {
+	rpc         ratio.QuotaServiceClient
+	owner       string
+	resource    string
+	descriptors []*ratio.Descriptor
+	timeout     time.Duration
+
+	mu       sync.Mutex
+	id       string
+	permits  int
+	taken    int
+	expireAt time.Time
+	released bool
+}
+
+// Reserve asks ratio for a lease of up to permits permits of the owner on the resource, consumable during ttl (zero
+// means the server default). A lease with no permits is returned when the owner is over limit.
+func (c *Client) Reserve(ctx context.Context, owner, resource string, permits uint32, ttl time.Duration, descriptors ...*ratio.Descriptor) (*Lease, error) {
+	if c.quota == nil {
+		return nil, ErrQuotaUnsupported
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.o.Timeout)
+	defer cancel()
+
+	resp, err := c.quota.Reserve(ctx, &ratio.ReserveRequest{
+		Owner:       owner,
+		Resource:    resource,
+		Descriptors: descriptors,
+		Permits:     permits,
+		TtlMs:       int64(ttl / time.Millisecond),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Lease{
+		rpc:         c.quota,
+		owner:       owner,
+		resource:    resource,
+		descriptors: descriptors,
+		timeout:     c.o.Timeout,
+		id:          resp.LeaseId,
+		permits:     int(resp.Permits),
+		expireAt:    time.Unix(0, resp.ExpireAtMs*int64(time.Millisecond)),
+	}, nil
+}
+
+// Take consumes a permit of the lease. Returns false once the lease is exhausted, expired or released.
+func (l *Lease) Take() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.released || l.taken >= l.permits || !time.Now().Before(l.expireAt) {
+		return false
+	}
+
+	l.taken++
+
+	return true
+}
+
+// Remaining returns the permits not consumed yet.
+func (l *Lease) Remaining() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.permits - l.taken
+}
+
+// ExpireAt returns until when the permits can be consumed.
+func (l *Lease) ExpireAt() time.Time {
+	return l.expireAt
+}
+
+// Release ends the lease, giving the unused permits back to ratio. Returns the permits given back.
+func (l *Lease) Release(ctx context.Context) (int, error) {
+	l.mu.Lock()
+	if l.released {
+		l.mu.Unlock()
+		return 0, nil
+	}
+	l.released = true
+	unused := l.permits - l.taken
+	l.mu.Unlock()
+
+	if unused == 0 || l.id == "" {
+		return 0, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+
+	resp, err := l.rpc.Return(ctx, &ratio.ReturnRequest{
+		Owner:       l.owner,
+		Resource:    l.resource,
+		Descriptors: l.descriptors,
+		LeaseId:     l.id,
+		Unused:      uint32(unused),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int(resp.Returned), nil
+}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+
+	ratio "github.com/smoya/ratio/api/proto"
+)
+
+// fakeQuotaRPC reserves up to available permits and records the returned ones.
+type fakeQuotaRPC struct {
+	available uint32
+	ttl       time.Duration
+	returned  uint32
+}
+
+func (f *fakeQuotaRPC) Reserve(_ context.Context, r *ratio.ReserveRequest, _ ...grpc.CallOption) (*ratio.ReserveResponse, error) {
+	permits := r.Permits
+	if permits > f.available {
+		permits = f.available
+	}
+	f.available -= permits
+
+	if permits == 0 {
+		return &ratio.ReserveResponse{Code: ratio.RateLimitResponse_OVER_LIMIT}, nil
+	}
+
+	return &ratio.ReserveResponse{
+		Code:       ratio.RateLimitResponse_OK,
+		LeaseId:    "lease1",
+		Permits:    permits,
+		ExpireAtMs: time.Now().Add(f.ttl).UnixNano() / int64(time.Millisecond),
+	}, nil
+}
+
+func (f *fakeQuotaRPC) Return(_ context.Context, r *ratio.ReturnRequest, _ ...grpc.CallOption) (*ratio.ReturnResponse, error) {
+	f.returned += r.Unused
+	f.available += r.Unused
+	return &ratio.ReturnResponse{Returned: r.Unused}, nil
+}
+
+func TestClient_Reserve(t *testing.T) {
+	rpc := &fakeQuotaRPC{available: 3, ttl: time.Minute}
+	c := NewFromRPC(&fakeRPC{}, Options{})
+	c.quota = rpc
+
+	l, err := c.Reserve(context.Background(), "svc", "/pay", 5, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, l.Remaining())
+
+	assert.True(t, l.Take())
+	assert.True(t, l.Take())
+
+	returned, err := l.Release(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, returned)
+	assert.Equal(t, uint32(1), rpc.returned)
+	assert.False(t, l.Take(), "released leases should not give permits")
+
+	returned, err = l.Release(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, returned, "permits should be returned once")
+}
+
+func TestClient_Reserve_Exhausted(t *testing.T) {
+	c := NewFromRPC(&fakeRPC{}, Options{})
+	c.quota = &fakeQuotaRPC{available: 1, ttl: time.Minute}
+
+	l, err := c.Reserve(context.Background(), "svc", "/pay", 1, 0)
+	assert.NoError(t, err)
+	assert.True(t, l.Take())
+	assert.False(t, l.Take())
+
+	l, err = c.Reserve(context.Background(), "svc", "/pay", 1, 0)
+	assert.NoError(t, err)
+	assert.False(t, l.Take(), "over limit leases should have no permits")
+}
+
+func TestClient_Reserve_Expired(t *testing.T) {
+	c := NewFromRPC(&fakeRPC{}, Options{})
+	c.quota = &fakeQuotaRPC{available: 5, ttl: -time.Second}
+
+	l, err := c.Reserve(context.Background(), "svc", "/pay", 5, 0)
+	assert.NoError(t, err)
+	assert.False(t, l.Take())
+}
+
+func TestClient_Reserve_Unsupported(t *testing.T) {
+	_, err := NewFromRPC(&fakeRPC{}, Options{}).Reserve(context.Background(), "svc", "/pay", 5, 0)
+	assert.Equal(t, ErrQuotaUnsupported, err)
+}
@@ -0,0 +1,186 @@
+package cluster
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// Cluster keeps track of the ratio instances (peers) that compose the cluster and the connections to them.
+type Cluster struct {
+	self       string
+	discoverer Discoverer
+	replicas   int
+	dialOpts   []grpc.DialOption
+
+	mu    sync.RWMutex
+	peers []string
+	ring  *Ring
+	conns map[string]*grpc.ClientConn
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a Cluster. self is the address the local instance is known by its peers.
+// The local instance is always part of the cluster, even if the discoverer does not return it.
+func New(self string, d Discoverer, dialOpts ...grpc.DialOption) *Cluster {
+	return &Cluster{
+		self:       self,
+		discoverer: d,
+		replicas:   DefaultReplicas,
+		dialOpts:   dialOpts,
+		peers:      []string{self},
+		ring:       NewRing(DefaultReplicas, self),
+		conns:      make(map[string]*grpc.ClientConn),
+		done:       make(chan struct{}),
+	}
+}
+
+// Self returns the address of the local instance.
+func (c *Cluster) Self() string {
+	return c.self
+}
+
+// Peers returns the current members of the cluster, including the local instance.
+func (c *Cluster) Peers() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return append([]string(nil), c.peers...)
+}
+
+// Owner returns the peer owning the given key and whether it is the local instance.
+func (c *Cluster) Owner(key string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	peer := c.ring.Get(key)
+	return peer, peer == c.self || peer == ""
+}
+
+// Conn returns a (lazily created) connection to the given peer.
+func (c *Cluster) Conn(peer string) (*grpc.ClientConn, error) {
+	c.mu.RLock()
+	conn, ok := c.conns[peer]
+	c.mu.RUnlock()
+	if ok {
+		return conn, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if conn, ok := c.conns[peer]; ok {
+		return conn, nil
+	}
+
+	conn, err := grpc.Dial(peer, c.dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+	c.conns[peer] = conn
+
+	return conn, nil
+}
+
+// Refresh discovers the peers and rebalances the ring in case the membership changed.
+func (c *Cluster) Refresh() error {
+	discovered, err := c.discoverer.Peers()
+	if err != nil {
+		return err
+	}
+
+	peers := []string{c.self}
+	for _, p := range discovered {
+		if p != c.self {
+			peers = append(peers, p)
+		}
+	}
+	sort.Strings(peers)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if equal(peers, c.peers) {
+		return nil
+	}
+
+	log.Printf("cluster membership changed: %v -> %v\n", c.peers, peers)
+
+	c.peers = peers
+	c.ring = NewRing(c.replicas, peers...)
+
+	// Close connections to the peers that left.
+	members := make(map[string]bool, len(peers))
+	for _, p := range peers {
+		members[p] = true
+	}
+	for p, conn := range c.conns {
+		if !members[p] {
+			_ = conn.Close()
+			delete(c.conns, p)
+		}
+	}
+
+	return nil
+}
+
+// Start refreshes the membership of the cluster periodically, until Close is called.
+func (c *Cluster) Start(interval time.Duration) {
+	if err := c.Refresh(); err != nil {
+		log.Printf("error discovering cluster peers: %s\n", err.Error())
+	}
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-c.done:
+				return
+			case <-t.C:
+				if err := c.Refresh(); err != nil {
+					log.Printf("error discovering cluster peers: %s\n", err.Error())
+				}
+			}
+		}
+	}()
+}
+
+// Close stops the membership refresh and closes all the connections to the peers.
+func (c *Cluster) Close() error {
+	close(c.done)
+	c.wg.Wait()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for p, conn := range c.conns {
+		_ = conn.Close()
+		delete(c.conns, p)
+	}
+
+	return nil
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,43 @@
+package cluster
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+func TestCluster_Refresh(t *testing.T) {
+	peers := []string{"b:1", "a:1"}
+	c := New("a:1", DiscovererFunc(func() ([]string, error) {
+		return peers, nil
+	}), grpc.WithInsecure())
+	defer c.Close()
+
+	assert.Equal(t, []string{"a:1"}, c.Peers())
+	_, local := c.Owner("whatever")
+	assert.True(t, local)
+
+	assert.NoError(t, c.Refresh())
+	assert.Equal(t, []string{"a:1", "b:1"}, c.Peers())
+
+	conn, err := c.Conn("b:1")
+	assert.NoError(t, err)
+	assert.NotNil(t, conn)
+
+	peers = []string{"c:1"}
+	assert.NoError(t, c.Refresh())
+	assert.Equal(t, []string{"a:1", "c:1"}, c.Peers())
+	assert.NotContains(t, c.conns, "b:1", "connections to gone peers should be closed")
+}
+
+func TestCluster_RefreshError(t *testing.T) {
+	c := New("a:1", DiscovererFunc(func() ([]string, error) {
+		return nil, errors.New("whatever error")
+	}))
+	defer c.Close()
+
+	assert.Error(t, c.Refresh())
+	assert.Equal(t, []string{"a:1"}, c.Peers())
+}
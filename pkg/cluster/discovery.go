@@ -0,0 +1,47 @@
+package cluster
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Discoverer returns the current list of peers (host:port) of the cluster, including the local instance.
+type Discoverer interface {
+	Peers() ([]string, error)
+}
+
+// DiscovererFunc is an adapter to allow the use of ordinary functions as Discoverer.
+type DiscovererFunc func() ([]string, error)
+
+// Peers calls f().
+func (f DiscovererFunc) Peers() ([]string, error) {
+	return f()
+}
+
+// StaticDiscoverer always returns the same list of peers.
+func StaticDiscoverer(peers ...string) Discoverer {
+	return DiscovererFunc(func() ([]string, error) {
+		return peers, nil
+	})
+}
+
+// DNSSRVDiscoverer discovers peers by resolving the given DNS SRV record.
+// Example: _grpc._tcp.ratio.default.svc.cluster.local
+func DNSSRVDiscoverer(name string) Discoverer {
+	return DiscovererFunc(func() ([]string, error) {
+		_, records, err := net.LookupSRV("", "", name)
+		if err != nil {
+			return nil, err
+		}
+
+		peers := make([]string, 0, len(records))
+		for _, r := range records {
+			peers = append(peers, net.JoinHostPort(strings.TrimSuffix(r.Target, "."), strconv.Itoa(int(r.Port))))
+		}
+		sort.Strings(peers)
+
+		return peers, nil
+	})
+}
@@ -0,0 +1,204 @@
+package cluster
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/smoya/ratio/pkg/rate"
+
+	ratio "github.com/smoya/ratio/api/proto"
+)
+
+// Gossiper periodically exchanges the counters of a GCounterSlideWindowStorage with all the peers of the cluster,
+// so the counts of every instance converge to the global ones.
+type Gossiper struct {
+	storage  rate.GCounterSlideWindowStorage
+	cluster  *Cluster
+	interval time.Duration
+	started  time.Time
+
+	mu       sync.RWMutex
+	lastSync map[string]time.Time
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewGossiper creates a Gossiper. Counters are exchanged every interval.
+func NewGossiper(s rate.GCounterSlideWindowStorage, c *Cluster, interval time.Duration) *Gossiper {
+	return &Gossiper{
+		storage:  s,
+		cluster:  c,
+		interval: interval,
+		started:  time.Now(),
+		lastSync: make(map[string]time.Time),
+		done:     make(chan struct{}),
+	}
+}
+
+// Gossip exchanges the counters with all the peers once.
+func (g *Gossiper) Gossip(ctx context.Context) {
+	req := &ratio.GossipRequest{
+		Node:     g.storage.Node(),
+		Counters: toProto(g.storage.State()),
+	}
+
+	var wg sync.WaitGroup
+	for _, peer := range g.cluster.Peers() {
+		if peer == g.cluster.Self() {
+			continue
+		}
+
+		wg.Add(1)
+		go func(peer string) {
+			defer wg.Done()
+
+			if err := g.exchange(ctx, peer, req); err != nil {
+				log.Printf("error gossiping with peer %s: %s\n", peer, err.Error())
+				return
+			}
+
+			g.mu.Lock()
+			g.lastSync[peer] = time.Now()
+			g.mu.Unlock()
+		}(peer)
+	}
+	wg.Wait()
+}
+
+func (g *Gossiper) exchange(ctx context.Context, peer string, req *ratio.GossipRequest) error {
+	conn, err := g.cluster.Conn(peer)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, g.interval)
+	defer cancel()
+
+	resp, err := ratio.NewGossipServiceClient(conn).Gossip(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	g.storage.Merge(fromProto(resp.Counters))
+
+	return nil
+}
+
+// Lag returns, per peer, the time elapsed since the last successful exchange of counters. It is an upper bound of
+// how outdated the counts of that peer are in the local instance.
+// Peers never synced report the time elapsed since the Gossiper was created.
+func (g *Gossiper) Lag() map[string]time.Duration {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	now := time.Now()
+	lag := make(map[string]time.Duration)
+	for _, peer := range g.cluster.Peers() {
+		if peer == g.cluster.Self() {
+			continue
+		}
+
+		last, ok := g.lastSync[peer]
+		if !ok {
+			last = g.started
+		}
+		lag[peer] = now.Sub(last)
+	}
+
+	return lag
+}
+
+// Start gossips every interval until Close is called. A convergence lag over 3 intervals gets logged.
+func (g *Gossiper) Start() {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+
+		t := time.NewTicker(g.interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-g.done:
+				return
+			case <-t.C:
+				g.Gossip(context.Background())
+
+				for peer, lag := range g.Lag() {
+					if lag > 3*g.interval {
+						log.Printf("gossip convergence lag with peer %s is %s\n", peer, lag)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// Close stops gossiping.
+func (g *Gossiper) Close() error {
+	close(g.done)
+	g.wg.Wait()
+
+	return nil
+}
+
+type gossipServer struct {
+	storage rate.GCounterSlideWindowStorage
+}
+
+// NewGossipServer creates a GossipServiceServer that merges the received counters into the storage and answers with
+// the local ones.
+func NewGossipServer(s rate.GCounterSlideWindowStorage) ratio.GossipServiceServer {
+	return &gossipServer{storage: s}
+}
+
+// Gossip implements ratio.GossipService
+func (s *gossipServer) Gossip(_ context.Context, r *ratio.GossipRequest) (*ratio.GossipResponse, error) {
+	// The local state is taken before merging, so the caller does not receive back its own counters.
+	state := s.storage.State()
+	s.storage.Merge(fromProto(r.Counters))
+
+	return &ratio.GossipResponse{
+		Node:     s.storage.Node(),
+		Counters: toProto(state),
+	}, nil
+}
+
+func toProto(counters []rate.GCounter) []*ratio.GCounter {
+	list := make([]*ratio.GCounter, 0, len(counters))
+	for _, c := range counters {
+		list = append(list, &ratio.GCounter{
+			Key:      c.Key,
+			Bucket:   toMilliseconds(c.Bucket),
+			ExpireAt: toMilliseconds(c.ExpireAt),
+			Counts:   c.Counts,
+		})
+	}
+
+	return list
+}
+
+func fromProto(counters []*ratio.GCounter) []rate.GCounter {
+	list := make([]rate.GCounter, 0, len(counters))
+	for _, c := range counters {
+		list = append(list, rate.GCounter{
+			Key:      c.Key,
+			Bucket:   fromMilliseconds(c.Bucket),
+			ExpireAt: fromMilliseconds(c.ExpireAt),
+			Counts:   c.Counts,
+		})
+	}
+
+	return list
+}
+
+func toMilliseconds(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func fromMilliseconds(ms int64) time.Time {
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
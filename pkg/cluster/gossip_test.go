@@ -0,0 +1,73 @@
+package cluster
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/smoya/ratio/pkg/rate"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+
+	ratio "github.com/smoya/ratio/api/proto"
+)
+
+func TestGossiper_Gossip(t *testing.T) {
+	node1 := rate.NewGCounterSlideWindowStorage("node1", time.Second)
+	node2 := rate.NewGCounterSlideWindowStorage("node2", time.Second)
+
+	addr1, stop1 := serveGossip(t, node1)
+	defer stop1()
+	addr2, stop2 := serveGossip(t, node2)
+	defer stop2()
+
+	c := New(addr1, StaticDiscoverer(addr1, addr2), grpc.WithInsecure())
+	defer c.Close()
+	assert.NoError(t, c.Refresh())
+
+	now := time.Now()
+	assert.NoError(t, node1.Add("key1", now, time.Minute))
+	assert.NoError(t, node2.Add("key1", now, time.Minute))
+	assert.NoError(t, node2.Add("key1", now, time.Minute))
+
+	g := NewGossiper(node1, c, time.Second)
+	g.Gossip(context.Background())
+
+	for _, s := range []rate.SlideWindowStorage{node1, node2} {
+		hits, err := s.Count("key1", now)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, hits)
+	}
+
+	lag := g.Lag()
+	assert.Len(t, lag, 1)
+	assert.True(t, lag[addr2] < time.Second)
+}
+
+func TestGossiper_Lag(t *testing.T) {
+	node1 := rate.NewGCounterSlideWindowStorage("node1", time.Second)
+
+	c := New("127.0.0.1:1", StaticDiscoverer("127.0.0.1:2"), grpc.WithInsecure())
+	defer c.Close()
+	assert.NoError(t, c.Refresh())
+
+	g := NewGossiper(node1, c, time.Second)
+	time.Sleep(10 * time.Millisecond)
+	g.Gossip(context.Background())
+
+	assert.True(t, g.Lag()["127.0.0.1:2"] >= 10*time.Millisecond, "lag should grow while the peer is unreachable")
+}
+
+func serveGossip(t *testing.T, s rate.GCounterSlideWindowStorage) (string, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := grpc.NewServer()
+	ratio.RegisterGossipServiceServer(srv, NewGossipServer(s))
+	go func() { _ = srv.Serve(l) }()
+
+	return l.Addr().String(), srv.Stop
+}
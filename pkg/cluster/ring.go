@@ -0,0 +1,62 @@
+package cluster
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+)
+
+// DefaultReplicas is the default number of virtual nodes per peer in the Ring.
+const DefaultReplicas = 128
+
+// Ring is a consistent hash ring of peers. Each peer is placed several times (virtual nodes) in the ring so keys get
+// evenly distributed and only a small fraction of them move when a peer joins or leaves.
+// Ring is immutable; build a new one on each membership change.
+type Ring struct {
+	replicas int
+	hashes   []uint32
+	peers    map[uint32]string
+}
+
+// NewRing creates a Ring with the given virtual nodes per peer.
+func NewRing(replicas int, peers ...string) *Ring {
+	if replicas <= 0 {
+		replicas = DefaultReplicas
+	}
+
+	r := &Ring{
+		replicas: replicas,
+		peers:    make(map[uint32]string, len(peers)*replicas),
+	}
+
+	for _, p := range peers {
+		for i := 0; i < replicas; i++ {
+			h := hash(strconv.Itoa(i) + p)
+			r.hashes = append(r.hashes, h)
+			r.peers[h] = p
+		}
+	}
+
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+
+	return r
+}
+
+// Get returns the peer owning the given key. Returns an empty string if the ring has no peers.
+func (r *Ring) Get(key string) string {
+	if len(r.hashes) == 0 {
+		return ""
+	}
+
+	h := hash(key)
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	if i == len(r.hashes) {
+		i = 0
+	}
+
+	return r.peers[r.hashes[i]]
+}
+
+func hash(s string) uint32 {
+	return crc32.ChecksumIEEE([]byte(s))
+}
@@ -0,0 +1,42 @@
+package cluster
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRing_Get(t *testing.T) {
+	assert.Empty(t, NewRing(DefaultReplicas).Get("key"))
+
+	r := NewRing(DefaultReplicas, "a:1", "b:1", "c:1")
+	owners := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("owner-%d", i)
+		owner := r.Get(key)
+		assert.Equal(t, owner, r.Get(key), "the same key should be always owned by the same peer")
+		owners[owner]++
+	}
+
+	assert.Len(t, owners, 3)
+	for _, hits := range owners {
+		assert.True(t, hits > 200, "keys should be evenly distributed")
+	}
+}
+
+func TestRing_Rebalance(t *testing.T) {
+	before := NewRing(DefaultReplicas, "a:1", "b:1", "c:1")
+	after := NewRing(DefaultReplicas, "a:1", "b:1", "c:1", "d:1")
+
+	var moved int
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("owner-%d", i)
+		if before.Get(key) != after.Get(key) {
+			moved++
+			assert.Equal(t, "d:1", after.Get(key), "keys should only move to the new peer")
+		}
+	}
+
+	assert.True(t, moved > 0 && moved < 500)
+}
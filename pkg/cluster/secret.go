@@ -0,0 +1,51 @@
+package cluster
+
+import (
+	"context"
+	"crypto/subtle"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+// SecretHeader is the metadata key carrying the secret shared by the peers of a cluster.
+const SecretHeader = "x-ratio-cluster-secret"
+
+type secretCredentials string
+
+// SecretCredentials returns credentials sending the secret shared by the peers on every call, for being used as
+// grpc.WithPerRPCCredentials when dialing peers. The secret is sent in clear unless the connections use TLS.
+func SecretCredentials(secret string) credentials.PerRPCCredentials {
+	return secretCredentials(secret)
+}
+
+// GetRequestMetadata implements credentials.PerRPCCredentials.
+func (s secretCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{SecretHeader: string(s)}, nil
+}
+
+// RequireTransportSecurity implements credentials.PerRPCCredentials.
+func (s secretCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// FromPeer returns whether the incoming request carries the given secret, so it was sent by a peer. Always false for
+// an empty secret.
+func FromPeer(ctx context.Context, secret string) bool {
+	if secret == "" {
+		return false
+	}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+
+	for _, v := range md.Get(SecretHeader) {
+		if subtle.ConstantTimeCompare([]byte(v), []byte(secret)) == 1 {
+			return true
+		}
+	}
+
+	return false
+}
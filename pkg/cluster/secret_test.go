@@ -0,0 +1,22 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestFromPeer(t *testing.T) {
+	md, err := SecretCredentials("s3cr3t").GetRequestMetadata(context.Background())
+	assert.NoError(t, err)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.New(md))
+	assert.True(t, FromPeer(ctx, "s3cr3t"))
+	assert.False(t, FromPeer(ctx, "another"))
+	assert.False(t, FromPeer(context.Background(), "s3cr3t"))
+
+	empty := metadata.NewIncomingContext(context.Background(), metadata.Pairs(SecretHeader, ""))
+	assert.False(t, FromPeer(empty, ""), "an empty secret authenticates nobody")
+}
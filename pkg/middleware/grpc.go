@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	ratio "github.com/smoya/ratio/api/proto"
+)
+
+// GRPCExtractor extracts the owner or the resource from a GRPC call.
+type GRPCExtractor func(ctx context.Context, fullMethod string) string
+
+// GRPCStatic always extracts the given value.
+func GRPCStatic(value string) GRPCExtractor {
+	return func(context.Context, string) string {
+		return value
+	}
+}
+
+// GRPCMetadata extracts the first value of a metadata key.
+func GRPCMetadata(key string) GRPCExtractor {
+	return func(ctx context.Context, _ string) string {
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok {
+			return ""
+		}
+
+		values := md.Get(key)
+		if len(values) == 0 {
+			return ""
+		}
+
+		return values[0]
+	}
+}
+
+// GRPCMethod extracts the full method name, e.g. /package.Service/Method.
+func GRPCMethod() GRPCExtractor {
+	return func(_ context.Context, fullMethod string) string {
+		return fullMethod
+	}
+}
+
+// GRPCPeer extracts the IP of the peer.
+func GRPCPeer() GRPCExtractor {
+	return func(ctx context.Context, _ string) string {
+		p, ok := peer.FromContext(ctx)
+		if !ok {
+			return ""
+		}
+
+		host, _, err := net.SplitHostPort(p.Addr.String())
+		if err != nil {
+			return p.Addr.String()
+		}
+
+		return host
+	}
+}
+
+// GRPCJoin extracts the values of all the extractors joined by sep.
+func GRPCJoin(sep string, extractors ...GRPCExtractor) GRPCExtractor {
+	return func(ctx context.Context, fullMethod string) string {
+		values := make([]string, 0, len(extractors))
+		for _, e := range extractors {
+			values = append(values, e(ctx, fullMethod))
+		}
+
+		return strings.Join(values, sep)
+	}
+}
+
+// UnaryServerInterceptor returns a GRPC interceptor asking ratio whether each call is allowed. Rate limit headers are
+// sent as metadata, calls over the limit are rejected with ResourceExhausted, and denied ones with
+// PermissionDenied.
+func UnaryServerInterceptor(c ratio.RateLimitServiceClient, owner, resource GRPCExtractor, o Options) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, err := check(ctx, c, owner, resource, info.FullMethod, o)
+		if md != nil {
+			_ = grpc.SetHeader(ctx, md)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a GRPC interceptor asking ratio whether each stream is allowed. Rate limit headers
+// are sent as metadata, streams over the limit are rejected with ResourceExhausted, and denied ones with
+// PermissionDenied.
+func StreamServerInterceptor(c ratio.RateLimitServiceClient, owner, resource GRPCExtractor, o Options) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		md, err := check(ss.Context(), c, owner, resource, info.FullMethod, o)
+		if md != nil {
+			_ = ss.SetHeader(md)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+func check(ctx context.Context, c ratio.RateLimitServiceClient, owner, resource GRPCExtractor, method string, o Options) (metadata.MD, error) {
+	resp, err := c.RateLimit(ctx, &ratio.RateLimitRequest{
+		Owner:    owner(ctx, method),
+		Resource: resource(ctx, method),
+	})
+
+	if err != nil && resp.GetCode() != ratio.RateLimitResponse_OK && resp.GetCode() != ratio.RateLimitResponse_OVER_LIMIT {
+		if o.FailOpen {
+			return nil, nil
+		}
+
+		return nil, status.Error(codes.Unavailable, "rate limiter is unavailable")
+	}
+
+	md := metadata.MD{}
+	for k, v := range headers(resp) {
+		md.Set(strings.ToLower(k), v)
+	}
+
+	switch resp.Code {
+	case ratio.RateLimitResponse_OVER_LIMIT:
+		return md, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+	case ratio.RateLimitResponse_DENIED:
+		return md, status.Error(codes.PermissionDenied, "denied by the rate limiter")
+	}
+
+	return md, nil
+}
@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	ratio "github.com/smoya/ratio/api/proto"
+)
+
+type okServer struct{}
+
+func (okServer) RateLimit(context.Context, *ratio.RateLimitRequest) (*ratio.RateLimitResponse, error) {
+	return &ratio.RateLimitResponse{Code: ratio.RateLimitResponse_OK}, nil
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	cases := []struct {
+		desc      string
+		rpc       *fakeRPC
+		failOpen  bool
+		code      codes.Code
+		remaining string
+	}{
+		{desc: "OK", rpc: &fakeRPC{resp: response(ratio.RateLimitResponse_OK, 42)}, code: codes.OK, remaining: "42"},
+		{desc: "Over limit", rpc: &fakeRPC{resp: response(ratio.RateLimitResponse_OVER_LIMIT, 0)}, code: codes.ResourceExhausted, remaining: "0"},
+		{desc: "Denied", rpc: &fakeRPC{resp: &ratio.RateLimitResponse{Code: ratio.RateLimitResponse_DENIED}}, code: codes.PermissionDenied},
+		{desc: "Unavailable, fail closed", rpc: &fakeRPC{err: errors.New("whatever error")}, code: codes.Unavailable},
+		{desc: "Unavailable, fail open", rpc: &fakeRPC{err: errors.New("whatever error")}, failOpen: true, code: codes.OK},
+	}
+
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			srv := grpc.NewServer(grpc.UnaryInterceptor(UnaryServerInterceptor(
+				c.rpc,
+				GRPCMetadata("x-owner"),
+				GRPCJoin("@", GRPCMethod(), GRPCStatic("v1")),
+				Options{FailOpen: c.failOpen},
+			)))
+			ratio.RegisterRateLimitServiceServer(srv, okServer{})
+
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			assert.NoError(t, err)
+			go func() { _ = srv.Serve(l) }()
+			defer srv.Stop()
+
+			conn, err := grpc.Dial(l.Addr().String(), grpc.WithInsecure())
+			assert.NoError(t, err)
+			defer conn.Close()
+
+			var header metadata.MD
+			ctx := metadata.AppendToOutgoingContext(context.Background(), "x-owner", "my-awesome-service")
+			_, err = ratio.NewRateLimitServiceClient(conn).RateLimit(ctx, &ratio.RateLimitRequest{}, grpc.Header(&header))
+
+			assert.Equal(t, c.code, status.Code(err))
+			assert.Equal(t, &ratio.RateLimitRequest{Owner: "my-awesome-service", Resource: "/RateLimitService/RateLimit@v1"}, c.rpc.received)
+			if c.remaining != "" {
+				assert.Equal(t, []string{c.remaining}, header.Get("x-ratelimit-remaining"))
+				assert.Equal(t, []string{"100"}, header.Get("x-ratelimit-limit"))
+			}
+		})
+	}
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	header metadata.MD
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeServerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	rpc := &fakeRPC{resp: response(ratio.RateLimitResponse_OVER_LIMIT, 0)}
+	interceptor := StreamServerInterceptor(rpc, GRPCPeer(), GRPCMethod(), Options{})
+
+	ss := &fakeServerStream{ctx: context.Background()}
+	var called bool
+	err := interceptor(nil, ss, &grpc.StreamServerInfo{FullMethod: "/Service/Stream"}, func(interface{}, grpc.ServerStream) error {
+		called = true
+		return nil
+	})
+
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.False(t, called)
+	assert.Equal(t, []string{"60"}, ss.header.Get("retry-after"))
+	assert.Equal(t, "/Service/Stream", rpc.received.Resource)
+}
@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	ratio "github.com/smoya/ratio/api/proto"
+)
+
+// HTTPExtractor extracts the owner or the resource from an HTTP request.
+type HTTPExtractor func(r *http.Request) string
+
+// Static always extracts the given value.
+func Static(value string) HTTPExtractor {
+	return func(*http.Request) string {
+		return value
+	}
+}
+
+// Header extracts the value of an HTTP header.
+func Header(name string) HTTPExtractor {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// Method extracts the HTTP method.
+func Method() HTTPExtractor {
+	return func(r *http.Request) string {
+		return r.Method
+	}
+}
+
+// Path extracts the URL path.
+func Path() HTTPExtractor {
+	return func(r *http.Request) string {
+		return r.URL.Path
+	}
+}
+
+// PathTemplate extracts the first template matching the URL path, so all the paths of a route share the same resource.
+// Templates are paths where segments like {id} match any value, e.g. /v1/users/{id}. The path is extracted if no
+// template matches.
+func PathTemplate(templates ...string) HTTPExtractor {
+	split := make([][]string, 0, len(templates))
+	for _, t := range templates {
+		split = append(split, strings.Split(strings.Trim(t, "/"), "/"))
+	}
+
+	return func(r *http.Request) string {
+		segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		for i, template := range split {
+			if matchTemplate(template, segments) {
+				return templates[i]
+			}
+		}
+
+		return r.URL.Path
+	}
+}
+
+func matchTemplate(template, segments []string) bool {
+	if len(template) != len(segments) {
+		return false
+	}
+
+	for i, s := range template {
+		if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+			continue
+		}
+
+		if s != segments[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// RemoteIP extracts the IP of the peer.
+func RemoteIP() HTTPExtractor {
+	return func(r *http.Request) string {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			return r.RemoteAddr
+		}
+
+		return host
+	}
+}
+
+// Join extracts the values of all the extractors joined by sep. e.g. Join(" ", Method(), Path()) -> "GET /v1/users"
+func Join(sep string, extractors ...HTTPExtractor) HTTPExtractor {
+	return func(r *http.Request) string {
+		values := make([]string, 0, len(extractors))
+		for _, e := range extractors {
+			values = append(values, e(r))
+		}
+
+		return strings.Join(values, sep)
+	}
+}
+
+// HTTP returns a net/http middleware asking ratio whether each request is allowed. Rate limit headers are set on the
+// responses, requests over the limit are rejected with 429 Too Many Requests, and denied ones with 403 Forbidden.
+func HTTP(c ratio.RateLimitServiceClient, owner, resource HTTPExtractor, o Options) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			resp, err := c.RateLimit(r.Context(), &ratio.RateLimitRequest{
+				Owner:    owner(r),
+				Resource: resource(r),
+			})
+
+			if err != nil && resp.GetCode() != ratio.RateLimitResponse_OK && resp.GetCode() != ratio.RateLimitResponse_OVER_LIMIT {
+				if o.FailOpen {
+					next.ServeHTTP(w, r)
+					return
+				}
+
+				http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+				return
+			}
+
+			for k, v := range headers(resp) {
+				w.Header().Set(k, v)
+			}
+
+			switch resp.Code {
+			case ratio.RateLimitResponse_OVER_LIMIT:
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			case ratio.RateLimitResponse_DENIED:
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
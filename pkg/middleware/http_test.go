@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+
+	ratio "github.com/smoya/ratio/api/proto"
+)
+
+type fakeRPC struct {
+	resp     *ratio.RateLimitResponse
+	err      error
+	received *ratio.RateLimitRequest
+}
+
+func (f *fakeRPC) RateLimit(_ context.Context, r *ratio.RateLimitRequest, _ ...grpc.CallOption) (*ratio.RateLimitResponse, error) {
+	f.received = r
+	return f.resp, f.err
+}
+
+func response(code ratio.RateLimitResponse_Code, remaining uint32) *ratio.RateLimitResponse {
+	return &ratio.RateLimitResponse{
+		Code:      code,
+		Limit:     &ratio.Limit{Quantity: 100, WindowMs: 60000},
+		Remaining: remaining,
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	cases := []struct {
+		desc     string
+		rpc      *fakeRPC
+		failOpen bool
+		status   int
+		headers  map[string]string
+	}{
+		{
+			desc:    "OK",
+			rpc:     &fakeRPC{resp: response(ratio.RateLimitResponse_OK, 42)},
+			status:  http.StatusOK,
+			headers: map[string]string{HeaderLimit: "100", HeaderRemaining: "42", HeaderWindow: "60", HeaderRetry: ""},
+		},
+		{
+			desc:    "Over limit",
+			rpc:     &fakeRPC{resp: response(ratio.RateLimitResponse_OVER_LIMIT, 0)},
+			status:  http.StatusTooManyRequests,
+			headers: map[string]string{HeaderLimit: "100", HeaderRemaining: "0", HeaderWindow: "60", HeaderRetry: "60"},
+		},
+		{
+			desc: "Banned",
+			rpc: &fakeRPC{resp: &ratio.RateLimitResponse{
+				Code:          ratio.RateLimitResponse_OVER_LIMIT,
+				BannedUntilMs: time.Now().Add(90*time.Second).UnixNano() / int64(time.Millisecond),
+			}},
+			status:  http.StatusTooManyRequests,
+			headers: map[string]string{HeaderLimit: "", HeaderRetry: "90"},
+		},
+		{
+			desc:    "Denied",
+			rpc:     &fakeRPC{resp: &ratio.RateLimitResponse{Code: ratio.RateLimitResponse_DENIED}},
+			status:  http.StatusForbidden,
+			headers: map[string]string{HeaderLimit: "", HeaderRetry: ""},
+		},
+		{
+			desc:   "Unavailable, fail closed",
+			rpc:    &fakeRPC{err: errors.New("whatever error")},
+			status: http.StatusServiceUnavailable,
+		},
+		{
+			desc:     "Unavailable, fail open",
+			rpc:      &fakeRPC{err: errors.New("whatever error")},
+			failOpen: true,
+			status:   http.StatusOK,
+		},
+		{
+			desc:   "Decision already taken by the client",
+			rpc:    &fakeRPC{resp: &ratio.RateLimitResponse{Code: ratio.RateLimitResponse_OK}, err: errors.New("whatever error")},
+			status: http.StatusOK,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			mw := HTTP(c.rpc, Header("X-Owner"), Join(" ", Method(), PathTemplate("/v1/users/{id}")), Options{FailOpen: c.failOpen})
+			h := mw(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/users/123", nil)
+			req.Header.Set("X-Owner", "my-awesome-service")
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			assert.Equal(t, c.status, rec.Code)
+			assert.Equal(t, &ratio.RateLimitRequest{Owner: "my-awesome-service", Resource: "GET /v1/users/{id}"}, c.rpc.received)
+			for k, v := range c.headers {
+				assert.Equal(t, v, rec.Header().Get(k), k)
+			}
+		})
+	}
+}
+
+func TestHTTPExtractors(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/v1/orders/123/pay", nil)
+	req.RemoteAddr = "10.0.0.1:4321"
+
+	assert.Equal(t, "10.0.0.1", RemoteIP()(req))
+	assert.Equal(t, "static", Static("static")(req))
+	assert.Equal(t, "/v1/orders/123/pay", Path()(req))
+	assert.Equal(t, "/v1/orders/{id}/pay", PathTemplate("/v1/orders/{id}", "/v1/orders/{id}/pay")(req))
+	assert.Equal(t, "/v1/orders/123/pay", PathTemplate("/v1/users/{id}")(req))
+	assert.Equal(t, "POST|10.0.0.1", Join("|", Method(), RemoteIP())(req))
+}
@@ -0,0 +1,49 @@
+// Package middleware enforces ratio limits in net/http and GRPC servers.
+package middleware
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	ratio "github.com/smoya/ratio/api/proto"
+)
+
+// Rate limit headers set on every response.
+const (
+	HeaderLimit     = "X-RateLimit-Limit"
+	HeaderRemaining = "X-RateLimit-Remaining"
+	HeaderWindow    = "X-RateLimit-Window"
+	HeaderRetry     = "Retry-After"
+)
+
+// Options configures the middlewares.
+type Options struct {
+	// FailOpen lets the requests through when ratio answers with an error and no decision. Otherwise they are rejected
+	// as unavailable. When using client.Client, its own FailOpen option applies first.
+	FailOpen bool
+}
+
+// headers returns the rate limit headers for a response.
+func headers(resp *ratio.RateLimitResponse) map[string]string {
+	h := make(map[string]string)
+	if resp.GetBannedUntilMs() > 0 {
+		// Banned keys are rejected until the ban ends, whatever their limits.
+		until := time.Unix(0, resp.BannedUntilMs*int64(time.Millisecond))
+		h[HeaderRetry] = strconv.FormatInt(int64(math.Ceil(time.Until(until).Seconds())), 10)
+	}
+
+	if resp.GetLimit() == nil {
+		return h
+	}
+
+	h[HeaderLimit] = strconv.Itoa(int(resp.Limit.Quantity))
+	h[HeaderRemaining] = strconv.Itoa(int(resp.Remaining))
+	h[HeaderWindow] = strconv.FormatInt(resp.Limit.WindowMs/int64(time.Second/time.Millisecond), 10)
+	if resp.Code == ratio.RateLimitResponse_OVER_LIMIT && h[HeaderRetry] == "" {
+		// The window slides, so the worst case is waiting a whole window.
+		h[HeaderRetry] = h[HeaderWindow]
+	}
+
+	return h
+}
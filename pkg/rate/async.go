@@ -0,0 +1,238 @@
+package rate
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrStorageClosed is returned when adding hits to a closed storage.
+var ErrStorageClosed = errors.New("rate: storage is closed")
+
+// OverflowPolicy decides what happens to a hit when the queue of pending writes is full.
+type OverflowPolicy int
+
+// Overflow policies.
+const (
+	// Block makes the caller wait until there is room in the queue (back-pressure).
+	Block OverflowPolicy = iota
+	// Drop discards the hit.
+	Drop
+)
+
+// ParseOverflowPolicy returns an OverflowPolicy from a string representation.
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+	switch s {
+	case "block", "BLOCK":
+		return Block, nil
+	case "drop", "DROP":
+		return Drop, nil
+	}
+
+	return 0, errors.New(s + " is not a valid overflow policy")
+}
+
+// Default AsyncOptions.
+const (
+	DefaultAsyncQueueSize      = 10000
+	DefaultAsyncWorkers        = 4
+	DefaultAsyncBatchSize      = 100
+	DefaultAsyncReportInterval = 10 * time.Second
+)
+
+// AsyncOptions configures an async SlideWindowStorage.
+type AsyncOptions struct {
+	// QueueSize is the max number of hits pending to be written.
+	QueueSize int
+	// Workers is the number of goroutines writing hits.
+	Workers int
+	// BatchSize is the max number of hits written at once by a worker.
+	BatchSize int
+	// Overflow is the policy applied when the queue is full.
+	Overflow OverflowPolicy
+	// ReportInterval is the interval dropped hits are reported (logged) at.
+	ReportInterval time.Duration
+}
+
+// Hit is a hit to be added to a SlideWindowStorage.
+type Hit struct {
+	Key      string
+	Time     time.Time
+	ExpireIn time.Duration
+}
+
+// BatchAdder is implemented by the storages able to add several hits at once, like Redis through pipelines.
+type BatchAdder interface {
+	AddBatch(hits []Hit) error
+}
+
+// AsyncSlideWindowStorage is a SlideWindowStorage writing hits asynchronously.
+type AsyncSlideWindowStorage interface {
+	SlideWindowStorage
+	// Dropped returns the number of hits that have been lost, either discarded by the overflow policy or because of
+	// write errors.
+	Dropped() uint64
+}
+
+type asyncSlideWindowStorage struct {
+	SlideWindowStorage
+	batchSize int
+	overflow  OverflowPolicy
+
+	queue    chan Hit
+	dropped  uint64
+	reported uint64
+
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+	done   chan struct{}
+}
+
+// NewAsyncSlideWindowStorage wraps a SlideWindowStorage so hits are added asynchronously by a bounded pool of workers,
+// in batches when the storage is a BatchAdder. Callers do not wait for writes, as ratio is eventually consistent.
+// Close drains the pending hits before closing the wrapped storage.
+func NewAsyncSlideWindowStorage(s SlideWindowStorage, o AsyncOptions) AsyncSlideWindowStorage {
+	if o.QueueSize <= 0 {
+		o.QueueSize = DefaultAsyncQueueSize
+	}
+
+	if o.Workers <= 0 {
+		o.Workers = DefaultAsyncWorkers
+	}
+
+	if o.BatchSize <= 0 {
+		o.BatchSize = DefaultAsyncBatchSize
+	}
+
+	if o.ReportInterval <= 0 {
+		o.ReportInterval = DefaultAsyncReportInterval
+	}
+
+	a := &asyncSlideWindowStorage{
+		SlideWindowStorage: s,
+		batchSize:          o.BatchSize,
+		overflow:           o.Overflow,
+		queue:              make(chan Hit, o.QueueSize),
+		done:               make(chan struct{}),
+	}
+
+	a.wg.Add(o.Workers)
+	for i := 0; i < o.Workers; i++ {
+		go a.work()
+	}
+
+	go a.report(o.ReportInterval)
+
+	return a
+}
+
+func (s *asyncSlideWindowStorage) Add(key string, now time.Time, expireIn time.Duration) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return ErrStorageClosed
+	}
+
+	hit := Hit{Key: key, Time: now, ExpireIn: expireIn}
+	if s.overflow == Block {
+		s.queue <- hit
+		return nil
+	}
+
+	select {
+	case s.queue <- hit:
+	default:
+		atomic.AddUint64(&s.dropped, 1)
+	}
+
+	return nil
+}
+
+func (s *asyncSlideWindowStorage) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Close stops accepting hits, waits until all the pending ones are written and closes the wrapped storage.
+func (s *asyncSlideWindowStorage) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.queue)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	close(s.done)
+
+	if dropped := s.Dropped(); dropped > 0 {
+		log.Printf("%d hits were dropped in total\n", dropped)
+	}
+
+	return s.SlideWindowStorage.Close()
+}
+
+func (s *asyncSlideWindowStorage) work() {
+	defer s.wg.Done()
+
+	batch := make([]Hit, 0, s.batchSize)
+	for hit := range s.queue {
+		batch = append(batch[:0], hit)
+
+		// Take whatever is already queued, without waiting, up to the batch size.
+	fill:
+		for len(batch) < s.batchSize {
+			select {
+			case hit, ok := <-s.queue:
+				if !ok {
+					break fill
+				}
+				batch = append(batch, hit)
+			default:
+				break fill
+			}
+		}
+
+		s.write(batch)
+	}
+}
+
+func (s *asyncSlideWindowStorage) write(batch []Hit) {
+	if b, ok := s.SlideWindowStorage.(BatchAdder); ok {
+		if err := b.AddBatch(batch); err != nil {
+			atomic.AddUint64(&s.dropped, uint64(len(batch)))
+			log.Printf("error adding %d hits: %s\n", len(batch), err.Error())
+		}
+		return
+	}
+
+	for _, hit := range batch {
+		if err := s.SlideWindowStorage.Add(hit.Key, hit.Time, hit.ExpireIn); err != nil {
+			atomic.AddUint64(&s.dropped, 1)
+			log.Printf("error adding hit: %s\n", err.Error())
+		}
+	}
+}
+
+func (s *asyncSlideWindowStorage) report(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-t.C:
+			dropped := s.Dropped()
+			if dropped > s.reported {
+				log.Printf("%d hits dropped in the last %s\n", dropped-s.reported, interval)
+				s.reported = dropped
+			}
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package rate
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type batchRecorderStorage struct {
+	SlideWindowStorage
+	mu      sync.Mutex
+	batches [][]Hit
+}
+
+func (s *batchRecorderStorage) AddBatch(hits []Hit) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.batches = append(s.batches, append([]Hit(nil), hits...))
+	for _, hit := range hits {
+		if err := s.SlideWindowStorage.Add(hit.Key, hit.Time, hit.ExpireIn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func TestParseOverflowPolicy(t *testing.T) {
+	p, err := ParseOverflowPolicy("block")
+	assert.NoError(t, err)
+	assert.Equal(t, Block, p)
+
+	p, err = ParseOverflowPolicy("DROP")
+	assert.NoError(t, err)
+	assert.Equal(t, Drop, p)
+
+	_, err = ParseOverflowPolicy("whatever")
+	assert.Error(t, err)
+}
+
+func TestAsyncSlideWindowStorage_Add(t *testing.T) {
+	inner := NewInMemorySlideWindowStorage(make(map[string][]time.Time))
+	store := NewAsyncSlideWindowStorage(inner, AsyncOptions{})
+
+	now := time.Now()
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, store.Add("key1", now, time.Minute))
+	}
+
+	assert.NoError(t, store.Close(), "closing should drain the pending hits")
+
+	c, err := inner.Count("key1", now)
+	assert.NoError(t, err)
+	assert.Equal(t, 100, c)
+	assert.Zero(t, store.Dropped())
+
+	assert.Equal(t, ErrStorageClosed, store.Add("key1", now, time.Minute))
+	assert.NoError(t, store.Close())
+}
+
+func TestAsyncSlideWindowStorage_Batches(t *testing.T) {
+	unblock := make(chan struct{})
+	inner := &batchRecorderStorage{SlideWindowStorage: blockingSlideWindowStorage{
+		SlideWindowStorage: NewInMemorySlideWindowStorage(make(map[string][]time.Time)),
+		unblock:            unblock,
+	}}
+	store := NewAsyncSlideWindowStorage(inner, AsyncOptions{Workers: 1, BatchSize: 10})
+
+	now := time.Now()
+	for i := 0; i < 21; i++ {
+		assert.NoError(t, store.Add("key1", now, time.Minute))
+	}
+
+	close(unblock)
+	assert.NoError(t, store.Close())
+
+	var total int
+	for _, batch := range inner.batches {
+		assert.True(t, len(batch) <= 10)
+		total += len(batch)
+	}
+	assert.Equal(t, 21, total)
+	assert.True(t, len(inner.batches) < 21, "hits should be written in batches")
+}
+
+func TestAsyncSlideWindowStorage_DropPolicy(t *testing.T) {
+	unblock := make(chan struct{})
+	inner := blockingSlideWindowStorage{
+		SlideWindowStorage: NewInMemorySlideWindowStorage(make(map[string][]time.Time)),
+		unblock:            unblock,
+	}
+	store := NewAsyncSlideWindowStorage(inner, AsyncOptions{QueueSize: 1, Workers: 1, BatchSize: 1, Overflow: Drop})
+
+	now := time.Now()
+	assert.NoError(t, store.Add("key1", now, time.Minute))
+	// Let the worker take the first hit, so the queue gets empty.
+	time.Sleep(10 * time.Millisecond)
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, store.Add("key1", now, time.Minute))
+	}
+
+	close(unblock)
+	assert.NoError(t, store.Close())
+	assert.Equal(t, uint64(4), store.Dropped())
+
+	c, err := inner.Count("key1", now)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, c)
+}
+
+func TestAsyncSlideWindowStorage_BlockPolicy(t *testing.T) {
+	unblock := make(chan struct{})
+	inner := blockingSlideWindowStorage{
+		SlideWindowStorage: NewInMemorySlideWindowStorage(make(map[string][]time.Time)),
+		unblock:            unblock,
+	}
+	store := NewAsyncSlideWindowStorage(inner, AsyncOptions{QueueSize: 1, Workers: 1, BatchSize: 1, Overflow: Block})
+
+	now := time.Now()
+	added := make(chan struct{})
+	go func() {
+		for i := 0; i < 5; i++ {
+			_ = store.Add("key1", now, time.Minute)
+		}
+		close(added)
+	}()
+
+	select {
+	case <-added:
+		t.Fatal("adding should block while the queue is full")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(unblock)
+	<-added
+	assert.NoError(t, store.Close())
+	assert.Zero(t, store.Dropped())
+
+	c, err := inner.Count("key1", now)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, c)
+}
@@ -0,0 +1,102 @@
+package rate
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// ConcurrencyStorage stores the leases of the ConcurrencyLimiter. Implemented by the Redis and in memory storages.
+type ConcurrencyStorage interface {
+	// Acquire drops the leases expired at now and, if less than max remain, adds the lease expiring at expireAt.
+	// Returns whether the lease was added and the number of leases held before.
+	Acquire(key, lease string, max int, now, expireAt time.Time) (bool, int, error)
+	// Release drops the lease. Returns false if it was not found.
+	Release(key, lease string) (bool, error)
+}
+
+// ConcurrencyDescriptor is the descriptor telling the keys of the ConcurrencyLimiter apart from the other ones.
+const ConcurrencyDescriptor = ReservedDescriptorPrefix + "concurrency"
+
+// Lease is the result of acquiring a slot of a ConcurrencyLimiter.
+type Lease struct {
+	// ID identifies the lease for releasing it. Empty if not acquired.
+	ID string
+	// Acquired tells whether the slot was acquired.
+	Acquired bool
+	// Max is the max number of slots held at once.
+	Max int
+	// Held is the number of slots held, the current one excluded.
+	Held int
+	// ExpireAt is when the lease expires if not released.
+	ExpireAt time.Time
+}
+
+// Remaining returns the number of slots still available, after the current one.
+func (l Lease) Remaining() int {
+	if remaining := l.Max - l.Held - 1; remaining > 0 {
+		return remaining
+	}
+
+	return 0
+}
+
+// ConcurrencyLimiter limits the number of hits of an owner on a resource running at once. Every hit holds a slot
+// with a lease until it is released or it expires, so slots of crashed callers are not leaked.
+type ConcurrencyLimiter interface {
+	Acquire(max int, ttl time.Duration, owner, resource string, descriptors ...Descriptor) (Lease, error)
+	Release(lease, owner, resource string, descriptors ...Descriptor) (bool, error)
+}
+
+type concurrencyLimiter struct {
+	s ConcurrencyStorage
+}
+
+// NewConcurrencyLimiter creates a ConcurrencyLimiter on top of a ConcurrencyStorage.
+func NewConcurrencyLimiter(s ConcurrencyStorage) ConcurrencyLimiter {
+	return concurrencyLimiter{s: s}
+}
+
+func (c concurrencyLimiter) Acquire(max int, ttl time.Duration, owner, resource string, descriptors ...Descriptor) (Lease, error) {
+	if ttl <= 0 {
+		return Lease{Max: max}, errors.New("the ttl of a lease should be greater than 0")
+	}
+
+	id, err := leaseID()
+	if err != nil {
+		return Lease{Max: max}, err
+	}
+
+	now := time.Now()
+	l := Lease{Max: max, ExpireAt: now.Add(ttl)}
+	l.Acquired, l.Held, err = c.s.Acquire(concurrencyKey(owner, resource, descriptors), id, max, now, l.ExpireAt)
+	if err != nil {
+		return Lease{Max: max}, err
+	}
+
+	if l.Acquired {
+		l.ID = id
+	}
+
+	return l, nil
+}
+
+func (c concurrencyLimiter) Release(lease, owner, resource string, descriptors ...Descriptor) (bool, error) {
+	return c.s.Release(concurrencyKey(owner, resource, descriptors), lease)
+}
+
+func concurrencyKey(owner, resource string, descriptors []Descriptor) string {
+	d := make([]Descriptor, 0, len(descriptors)+1)
+	d = append(d, Descriptor{Key: ConcurrencyDescriptor})
+	return Key(owner, resource, append(d, descriptors...)...)
+}
+
+func leaseID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
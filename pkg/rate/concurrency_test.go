@@ -0,0 +1,107 @@
+package rate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrencyLimiter(t *testing.T) {
+	r, m := createRedis()
+	defer m.Close()
+
+	storages := map[string]ConcurrencyStorage{
+		"In memory": NewInMemorySlideWindowStorage(make(map[string][]time.Time)).(ConcurrencyStorage),
+		"Redis":     NewRedisSlideWindowStorage(r, DefaultRedisNamespace).(ConcurrencyStorage),
+	}
+
+	for desc, s := range storages {
+		t.Run(desc, func(t *testing.T) {
+			c := NewConcurrencyLimiter(s)
+
+			first, err := c.Acquire(2, time.Minute, "svc", "/export")
+			require.NoError(t, err)
+			assert.True(t, first.Acquired)
+			assert.NotEmpty(t, first.ID)
+			assert.Equal(t, 1, first.Remaining())
+
+			second, err := c.Acquire(2, time.Minute, "svc", "/export")
+			require.NoError(t, err)
+			assert.True(t, second.Acquired)
+			assert.NotEqual(t, first.ID, second.ID)
+			assert.Equal(t, 0, second.Remaining())
+
+			third, err := c.Acquire(2, time.Minute, "svc", "/export")
+			require.NoError(t, err)
+			assert.False(t, third.Acquired)
+			assert.Empty(t, third.ID)
+			assert.Equal(t, 2, third.Held)
+
+			// Other descriptors have their own slots.
+			other, err := c.Acquire(2, time.Minute, "svc", "/export", Descriptor{Key: "customer", Value: "c1"})
+			require.NoError(t, err)
+			assert.True(t, other.Acquired)
+
+			released, err := c.Release(first.ID, "svc", "/export")
+			require.NoError(t, err)
+			assert.True(t, released)
+
+			released, err = c.Release(first.ID, "svc", "/export")
+			require.NoError(t, err)
+			assert.False(t, released, "already released")
+
+			third, err = c.Acquire(2, time.Minute, "svc", "/export")
+			require.NoError(t, err)
+			assert.True(t, third.Acquired)
+		})
+	}
+}
+
+func TestConcurrencyLimiter_Expiration(t *testing.T) {
+	r, m := createRedis()
+	defer m.Close()
+
+	storages := map[string]ConcurrencyStorage{
+		"In memory": NewInMemorySlideWindowStorage(make(map[string][]time.Time)).(ConcurrencyStorage),
+		"Redis":     NewRedisSlideWindowStorage(r, DefaultRedisNamespace).(ConcurrencyStorage),
+	}
+
+	for desc, s := range storages {
+		t.Run(desc, func(t *testing.T) {
+			c := NewConcurrencyLimiter(s)
+
+			l, err := c.Acquire(1, 20*time.Millisecond, "svc", "/export")
+			require.NoError(t, err)
+			assert.True(t, l.Acquired)
+
+			l, err = c.Acquire(1, time.Minute, "svc", "/export")
+			require.NoError(t, err)
+			assert.False(t, l.Acquired)
+
+			// The first lease was never released, but its slot is not leaked.
+			time.Sleep(30 * time.Millisecond)
+			l, err = c.Acquire(1, time.Minute, "svc", "/export")
+			require.NoError(t, err)
+			assert.True(t, l.Acquired)
+			assert.Equal(t, 0, l.Held)
+		})
+	}
+
+	_, err := NewConcurrencyLimiter(storages["In memory"]).Acquire(1, 0, "svc", "/export")
+	assert.Error(t, err)
+}
+
+func TestConcurrencyLimiter_KeysApartFromHits(t *testing.T) {
+	store := make(map[string][]time.Time)
+	s := NewInMemorySlideWindowStorage(store)
+
+	_, err := NewConcurrencyLimiter(s.(ConcurrencyStorage)).Acquire(1, time.Minute, "svc", "/export")
+	require.NoError(t, err)
+
+	d, err := SlideWindowRateLimiter(s)(Limits{NewLimit(PerMinute, 1)}, "svc", "/export")
+	require.NoError(t, err)
+	assert.True(t, d.Allowed)
+	assert.Len(t, store, 1)
+}
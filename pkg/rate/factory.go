@@ -1,15 +1,53 @@
 package rate
 
 import (
-	"errors"
+	"fmt"
 	"net/url"
-	"strconv"
-	"time"
+	"sort"
+	"sync"
+)
+
+// StorageConstructor creates a SlideWindowStorage from a parsed DSN.
+type StorageConstructor func(dsn *url.URL) (SlideWindowStorage, error)
 
-	"github.com/go-redis/redis"
+var (
+	storagesMu sync.RWMutex
+	storages   = make(map[string]StorageConstructor)
 )
 
+// RegisterStorage makes a SlideWindowStorage available by the provided DSN scheme.
+// If RegisterStorage is called twice with the same scheme or if constructor is nil, it panics.
+func RegisterStorage(scheme string, constructor StorageConstructor) {
+	storagesMu.Lock()
+	defer storagesMu.Unlock()
+
+	if constructor == nil {
+		panic("rate: RegisterStorage constructor is nil")
+	}
+
+	if _, dup := storages[scheme]; dup {
+		panic("rate: RegisterStorage called twice for scheme " + scheme)
+	}
+
+	storages[scheme] = constructor
+}
+
+// Storages returns a sorted list of the schemes of the registered storages.
+func Storages() []string {
+	storagesMu.RLock()
+	defer storagesMu.RUnlock()
+
+	list := make([]string, 0, len(storages))
+	for scheme := range storages {
+		list = append(list, scheme)
+	}
+	sort.Strings(list)
+
+	return list
+}
+
 // NewSlideWindowStorageFromDSN creates a SlideWindowStorage based on a DSN.
+// The DSN scheme should match one of the storages registered via RegisterStorage.
 // Example: redis://localhost:6379/0
 func NewSlideWindowStorageFromDSN(raw string) (SlideWindowStorage, error) {
 	dsn, err := url.Parse(raw)
@@ -17,27 +55,13 @@ func NewSlideWindowStorageFromDSN(raw string) (SlideWindowStorage, error) {
 		return nil, err
 	}
 
-	var storage SlideWindowStorage
-	switch dsn.Scheme {
-	case "redis":
-		ops := &redis.Options{
-			Addr: dsn.Host,
-			DB:   0,
-		}
-
-		db, err := strconv.Atoi(dsn.Path)
-		if err == nil {
-			ops.DB = db
-		}
-
-		storage = NewRedisSlideWindowStorage(
-			redis.NewClient(ops),
-		)
-	case "inmemory":
-		storage = NewInMemorySlideWindowStorage(make(map[string][]time.Time))
-	default:
-		return nil, errors.New("invalid slide window storage")
+	storagesMu.RLock()
+	constructor, ok := storages[dsn.Scheme]
+	storagesMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("invalid slide window storage %q (forgotten import?)", dsn.Scheme)
 	}
 
-	return storage, nil
+	return constructor(dsn)
 }
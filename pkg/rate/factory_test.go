@@ -1,7 +1,9 @@
 package rate
 
 import (
+	"net/url"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -10,6 +12,15 @@ func TestNewSlideWindowStorageFromDSN_Redis(t *testing.T) {
 	s, err := NewSlideWindowStorageFromDSN("redis://localhost:6379/0")
 	assert.NoError(t, err)
 	assert.IsType(t, &redisSlideWindowStorage{}, s)
+	assert.Equal(t, DefaultRedisNamespace, s.(*redisSlideWindowStorage).namespace)
+
+	s, err = NewSlideWindowStorageFromDSN("redis://localhost:6379/0?namespace=shared")
+	assert.NoError(t, err)
+	assert.Equal(t, "shared", s.(*redisSlideWindowStorage).namespace)
+
+	s, err = NewSlideWindowStorageFromDSN("redis://localhost:6379/0?namespace=")
+	assert.NoError(t, err)
+	assert.Equal(t, "", s.(*redisSlideWindowStorage).namespace)
 }
 
 func TestNewSlideWindowStorageFromDSN_InMemory(t *testing.T) {
@@ -17,3 +28,33 @@ func TestNewSlideWindowStorageFromDSN_InMemory(t *testing.T) {
 	assert.NoError(t, err)
 	assert.IsType(t, &inMemorySlideWindowStorage{}, s)
 }
+
+func TestNewSlideWindowStorageFromDSN_Unknown(t *testing.T) {
+	_, err := NewSlideWindowStorageFromDSN("mongodb://localhost:27017/ratio")
+	assert.Error(t, err)
+}
+
+func TestRegisterStorage(t *testing.T) {
+	var received *url.URL
+	RegisterStorage("custom", func(dsn *url.URL) (SlideWindowStorage, error) {
+		received = dsn
+		return NewInMemorySlideWindowStorage(make(map[string][]time.Time)), nil
+	})
+	defer func() {
+		storagesMu.Lock()
+		delete(storages, "custom")
+		storagesMu.Unlock()
+	}()
+
+	assert.Contains(t, Storages(), "custom")
+
+	s, err := NewSlideWindowStorageFromDSN("custom://host:1234/db")
+	assert.NoError(t, err)
+	assert.IsType(t, &inMemorySlideWindowStorage{}, s)
+	assert.Equal(t, "host:1234", received.Host)
+}
+
+func TestRegisterStorage_Panics(t *testing.T) {
+	assert.Panics(t, func() { RegisterStorage("redis", newRedisSlideWindowStorageFromDSN) })
+	assert.Panics(t, func() { RegisterStorage("nil", nil) })
+}
@@ -0,0 +1,259 @@
+package rate
+
+import (
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultGCounterResolution is the default size of the buckets of time the hits are counted in.
+const DefaultGCounterResolution = time.Second
+
+func init() {
+	RegisterStorage("gcounter", newGCounterSlideWindowStorageFromDSN)
+}
+
+// newGCounterSlideWindowStorageFromDSN creates a G-Counter SlideWindowStorage from a DSN like
+// gcounter://?node=ratio-0&resolution=1s. The node defaults to the hostname.
+func newGCounterSlideWindowStorageFromDSN(dsn *url.URL) (SlideWindowStorage, error) {
+	q := dsn.Query()
+
+	node := q.Get("node")
+	if node == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, err
+		}
+		node = hostname
+	}
+
+	resolution := DefaultGCounterResolution
+	if raw := q.Get("resolution"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return nil, err
+		}
+		resolution = d
+	}
+
+	return NewGCounterSlideWindowStorage(node, resolution), nil
+}
+
+// GCounter is a grow-only counter (CRDT) of the hits of a key during a bucket of time, with one entry per node.
+// Two GCounters of the same key and bucket are merged by keeping the max count of each node.
+type GCounter struct {
+	Key      string
+	Bucket   time.Time
+	ExpireAt time.Time
+	Counts   map[string]int64
+}
+
+// GCounterSlideWindowStorage is a SlideWindowStorage that counts hits locally and converges with other nodes by
+// exchanging (gossiping) its counters. Counts are eventually consistent.
+type GCounterSlideWindowStorage interface {
+	SlideWindowStorage
+	// Node returns the ID of the local node.
+	Node() string
+	// State returns a snapshot of all the non expired counters.
+	State() []GCounter
+	// Merge merges the counters received from other nodes.
+	Merge(counters []GCounter)
+}
+
+type gcounterKey struct {
+	counts   map[int64]map[string]int64 // bucket (unix ms) -> node -> hits
+	expireAt time.Time
+}
+
+type gcounterSlideWindowStorage struct {
+	node       string
+	resolution time.Duration
+
+	mu   sync.Mutex
+	keys map[string]*gcounterKey
+}
+
+// NewGCounterSlideWindowStorage creates a new G-Counter SlideWindowStorage. Hits are counted in buckets of
+// the given resolution, so windows are approximated to it.
+func NewGCounterSlideWindowStorage(node string, resolution time.Duration) GCounterSlideWindowStorage {
+	if resolution <= 0 {
+		resolution = DefaultGCounterResolution
+	}
+
+	return &gcounterSlideWindowStorage{
+		node:       node,
+		resolution: resolution,
+		keys:       make(map[string]*gcounterKey),
+	}
+}
+
+func (s *gcounterSlideWindowStorage) Node() string {
+	return s.node
+}
+
+func (s *gcounterSlideWindowStorage) Add(key string, now time.Time, expireIn time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := s.key(key)
+	bucket := s.bucket(now)
+	if k.counts[bucket] == nil {
+		k.counts[bucket] = make(map[string]int64)
+	}
+	k.counts[bucket][s.node]++
+
+	if expireAt := now.Add(expireIn); expireAt.After(k.expireAt) {
+		k.expireAt = expireAt
+	}
+
+	return nil
+}
+
+func (s *gcounterSlideWindowStorage) Drop(key string, until time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.keys[key]
+	if !ok {
+		return 0, nil
+	}
+
+	// Only the buckets that ended before until are dropped.
+	var dropped int
+	last := s.bucket(until.Add(-s.resolution))
+	for bucket, counts := range k.counts {
+		if bucket <= last {
+			dropped += int(sum(counts))
+			delete(k.counts, bucket)
+		}
+	}
+
+	if len(k.counts) == 0 {
+		delete(s.keys, key)
+	}
+
+	return dropped, nil
+}
+
+func (s *gcounterSlideWindowStorage) Count(key string, until time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.keys[key]
+	if !ok {
+		return 0, nil
+	}
+
+	var hits int64
+	last := s.bucket(until)
+	for bucket, counts := range k.counts {
+		if bucket <= last {
+			hits += sum(counts)
+		}
+	}
+
+	return int(hits), nil
+}
+
+func (s *gcounterSlideWindowStorage) State() []GCounter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	state := make([]GCounter, 0, len(s.keys))
+	for key, k := range s.keys {
+		if now.After(k.expireAt) {
+			delete(s.keys, key)
+			continue
+		}
+
+		for bucket, counts := range k.counts {
+			c := GCounter{
+				Key:      key,
+				Bucket:   fromMilliseconds(bucket),
+				ExpireAt: k.expireAt,
+				Counts:   make(map[string]int64, len(counts)),
+			}
+			for node, hits := range counts {
+				c.Counts[node] = hits
+			}
+			state = append(state, c)
+		}
+	}
+
+	return state
+}
+
+func (s *gcounterSlideWindowStorage) Merge(counters []GCounter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, c := range counters {
+		if now.After(c.ExpireAt) {
+			continue
+		}
+
+		k := s.key(c.Key)
+		bucket := s.bucket(c.Bucket)
+		if k.counts[bucket] == nil {
+			k.counts[bucket] = make(map[string]int64, len(c.Counts))
+		}
+
+		for node, hits := range c.Counts {
+			if hits > k.counts[bucket][node] {
+				k.counts[bucket][node] = hits
+			}
+		}
+
+		if c.ExpireAt.After(k.expireAt) {
+			k.expireAt = c.ExpireAt
+		}
+	}
+}
+
+func (s *gcounterSlideWindowStorage) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys = make(map[string]*gcounterKey)
+	return nil
+}
+
+func (s *gcounterSlideWindowStorage) Ping() error {
+	return nil
+}
+
+func (s *gcounterSlideWindowStorage) Close() error {
+	// no-op
+	return nil
+}
+
+// key returns the counters of a key, creating them if needed. The caller must hold the lock.
+func (s *gcounterSlideWindowStorage) key(key string) *gcounterKey {
+	k, ok := s.keys[key]
+	if !ok {
+		k = &gcounterKey{counts: make(map[int64]map[string]int64)}
+		s.keys[key] = k
+	}
+
+	return k
+}
+
+func (s *gcounterSlideWindowStorage) bucket(t time.Time) int64 {
+	return t.Truncate(s.resolution).UnixNano() / int64(time.Millisecond)
+}
+
+func fromMilliseconds(ms int64) time.Time {
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
+
+func sum(counts map[string]int64) int64 {
+	var total int64
+	for _, hits := range counts {
+		total += hits
+	}
+
+	return total
+}
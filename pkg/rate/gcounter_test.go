@@ -0,0 +1,110 @@
+package rate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSlideWindowStorageFromDSN_GCounter(t *testing.T) {
+	s, err := NewSlideWindowStorageFromDSN("gcounter://?node=node1&resolution=100ms")
+	assert.NoError(t, err)
+	assert.IsType(t, &gcounterSlideWindowStorage{}, s)
+	assert.Equal(t, "node1", s.(GCounterSlideWindowStorage).Node())
+	assert.Equal(t, 100*time.Millisecond, s.(*gcounterSlideWindowStorage).resolution)
+
+	_, err = NewSlideWindowStorageFromDSN("gcounter://?resolution=whatever")
+	assert.Error(t, err)
+}
+
+func TestGCounterSlideWindowStorage_Count(t *testing.T) {
+	store := NewGCounterSlideWindowStorage("node1", time.Second)
+
+	now := time.Now()
+	assert.NoError(t, store.Add("key1", now, time.Minute))
+	assert.NoError(t, store.Add("key1", now, time.Minute))
+	assert.NoError(t, store.Add("key1", now.Add(time.Hour), time.Minute))
+
+	c, err := store.Count("key1", now)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, c)
+
+	c, err = store.Count("key2", now)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, c)
+}
+
+func TestGCounterSlideWindowStorage_Drop(t *testing.T) {
+	store := NewGCounterSlideWindowStorage("node1", time.Second)
+
+	now := time.Now()
+	assert.NoError(t, store.Add("key1", now, time.Hour))
+	assert.NoError(t, store.Add("key1", now.Add(-time.Minute), time.Hour))
+	assert.NoError(t, store.Add("key1", now.Add(-time.Minute*2), time.Hour))
+
+	c, err := store.Drop("key1", now.Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, c)
+
+	c, err = store.Count("key1", now)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, c)
+}
+
+func TestGCounterSlideWindowStorage_Merge(t *testing.T) {
+	node1 := NewGCounterSlideWindowStorage("node1", time.Second)
+	node2 := NewGCounterSlideWindowStorage("node2", time.Second)
+
+	now := time.Now()
+	assert.NoError(t, node1.Add("key1", now, time.Minute))
+	assert.NoError(t, node2.Add("key1", now, time.Minute))
+	assert.NoError(t, node2.Add("key1", now, time.Minute))
+
+	node1.Merge(node2.State())
+	node2.Merge(node1.State())
+
+	// Merging is idempotent.
+	node1.Merge(node2.State())
+
+	for _, s := range []SlideWindowStorage{node1, node2} {
+		c, err := s.Count("key1", now)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, c)
+	}
+}
+
+func TestGCounterSlideWindowStorage_StateSkipsExpired(t *testing.T) {
+	store := NewGCounterSlideWindowStorage("node1", time.Second)
+
+	assert.NoError(t, store.Add("key1", time.Now().Add(-time.Hour), time.Minute))
+	assert.NoError(t, store.Add("key2", time.Now(), time.Minute))
+
+	state := store.State()
+	assert.Len(t, state, 1)
+	assert.Equal(t, "key2", state[0].Key)
+	assert.Equal(t, map[string]int64{"node1": 1}, state[0].Counts)
+
+	store.Merge([]GCounter{{Key: "key3", ExpireAt: time.Now().Add(-time.Second), Counts: map[string]int64{"node2": 1}}})
+	assert.Len(t, store.State(), 1)
+}
+
+func TestSlideWindowLimiter_GCounterStorage(t *testing.T) {
+	node1 := NewGCounterSlideWindowStorage("node1", time.Second)
+	node2 := NewGCounterSlideWindowStorage("node2", time.Second)
+	limit := NewLimit(PerMinute, 2)
+
+	d, err := SlideWindowRateLimiter(node1)(Limits{limit}, "myservice", "resource1")
+	assert.NoError(t, err)
+	assert.True(t, d.Allowed)
+
+	d, err = SlideWindowRateLimiter(node2)(Limits{limit}, "myservice", "resource1")
+	assert.NoError(t, err)
+	assert.True(t, d.Allowed)
+
+	node1.Merge(node2.State())
+
+	d, err = SlideWindowRateLimiter(node1)(Limits{limit}, "myservice", "resource1")
+	assert.NoError(t, err)
+	assert.False(t, d.Allowed, "hits from other nodes should count once merged")
+}
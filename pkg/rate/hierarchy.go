@@ -0,0 +1,135 @@
+package rate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Level is the level of a hierarchy of limits where a decision was made.
+type Level int
+
+// Levels of a Hierarchy.
+const (
+	// LevelKey are the limits of each owner and resource.
+	LevelKey Level = iota
+	// LevelGlobal are the limits of all the hits.
+	LevelGlobal
+	// LevelOwner are the limits of each owner.
+	LevelOwner
+	// LevelResource are the limits of each owner and resource without its sub-part. e.g. /v1/order/pay for
+	// /v1/order/pay#customer123.
+	LevelResource
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelGlobal:
+		return "global"
+	case LevelOwner:
+		return "owner"
+	case LevelResource:
+		return "resource"
+	}
+
+	return "key"
+}
+
+// SubResourceSeparator separates a resource from its sub-part. e.g. /v1/order/pay#customer123.
+const SubResourceSeparator = "#"
+
+// Hierarchy are the limits enforced on top of the ones of each owner and resource. Empty levels are not enforced.
+type Hierarchy struct {
+	Global   Limits
+	Owner    Limits
+	Resource Limits
+}
+
+// IsZero returns whether the hierarchy has no limits at any level.
+func (h Hierarchy) IsZero() bool {
+	return len(h.Global) == 0 && len(h.Owner) == 0 && len(h.Resource) == 0
+}
+
+// ParseHierarchy parses a Hierarchy from the string representation of the limits of each level. Empty strings are
+// empty levels.
+func ParseHierarchy(global, owner, resource string) (Hierarchy, error) {
+	var h Hierarchy
+	for _, level := range []struct {
+		s      string
+		limits *Limits
+		level  Level
+	}{
+		{s: global, limits: &h.Global, level: LevelGlobal},
+		{s: owner, limits: &h.Owner, level: LevelOwner},
+		{s: resource, limits: &h.Resource, level: LevelResource},
+	} {
+		if level.s == "" {
+			continue
+		}
+
+		limits, err := ParseLimits(level.s)
+		if err != nil {
+			return h, fmt.Errorf("invalid %s limits: %s", level.level, err.Error())
+		}
+		*level.limits = limits
+	}
+
+	return h, nil
+}
+
+// LevelDescriptor is the descriptor identifying the keys of the levels of a hierarchy above the key one.
+const LevelDescriptor = ReservedDescriptorPrefix + "level"
+
+// HierarchicalRateLimiter enforces the limits of the hierarchy besides the ones of each owner and resource. Every
+// hit counts against every level, even if it is not allowed. The decision reported is the first level exceeded, from
+// the global to the key one, or, if the hit is allowed, the one with less remaining hits.
+// Only the key level takes the descriptors into account.
+func HierarchicalRateLimiter(limiter Limiter, h Hierarchy) Limiter {
+	return func(l Limits, owner, resource string, descriptors ...Descriptor) (Decision, error) {
+		base := strings.SplitN(resource, SubResourceSeparator, 2)[0]
+
+		levels := []struct {
+			level           Level
+			limits          Limits
+			owner, resource string
+			descriptors     []Descriptor
+		}{
+			{level: LevelGlobal, limits: h.Global},
+			{level: LevelOwner, limits: h.Owner, owner: owner},
+			{level: LevelResource, limits: h.Resource, owner: owner, resource: base},
+			{level: LevelKey, limits: l, owner: owner, resource: resource, descriptors: descriptors},
+		}
+
+		var d Decision
+		var decided bool
+		for _, level := range levels {
+			if len(level.limits) == 0 {
+				continue
+			}
+
+			if level.level != LevelKey {
+				// Each level is counted on its own key.
+				level.descriptors = []Descriptor{{Key: LevelDescriptor, Value: level.level.String()}}
+			}
+
+			current, err := limiter(level.limits, level.owner, level.resource, level.descriptors...)
+			if err != nil {
+				return Decision{Limit: level.limits[0], Level: level.level}, err
+			}
+			current.Level = level.level
+
+			switch {
+			case !decided:
+				d, decided = current, true
+			case !d.Allowed:
+			case !current.Allowed || current.Remaining() < d.Remaining():
+				d = current
+			}
+		}
+
+		if !decided {
+			return d, fmt.Errorf("no limits for %s -> %s", owner, resource)
+		}
+
+		return d, nil
+	}
+}
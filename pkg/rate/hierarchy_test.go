@@ -0,0 +1,108 @@
+package rate
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHierarchy(t *testing.T) {
+	h, err := ParseHierarchy("1000/s", "", "10/s;100/m")
+	assert.NoError(t, err)
+	assert.Equal(t, Hierarchy{
+		Global:   Limits{NewLimit(PerSecond, 1000)},
+		Resource: Limits{NewLimit(PerSecond, 10), NewLimit(PerMinute, 100)},
+	}, h)
+
+	_, err = ParseHierarchy("", "10/x", "")
+	assert.EqualError(t, err, "invalid owner limits: 10/x is not a valid limit: x is not a valid frequency for a rate: unknown unit x")
+}
+
+func TestHierarchicalRateLimiter(t *testing.T) {
+	store := make(map[string][]time.Time)
+	h := Hierarchy{
+		Global:   Limits{NewLimit(PerMinute, 5)},
+		Owner:    Limits{NewLimit(PerMinute, 4)},
+		Resource: Limits{NewLimit(PerMinute, 3)},
+	}
+	limiter := HierarchicalRateLimiter(SlideWindowRateLimiter(NewInMemorySlideWindowStorage(store)), h)
+	key := Limits{NewLimit(PerMinute, 2)}
+
+	cases := []struct {
+		desc     string
+		owner    string
+		resource string
+		ok       bool
+		level    Level
+	}{
+		{desc: "First hit. The key level has less remaining hits", owner: "svc1", resource: "/pay#c1", ok: true, level: LevelKey},
+		{desc: "Second hit on the key", owner: "svc1", resource: "/pay#c1", ok: true, level: LevelKey},
+		{desc: "The key level trips", owner: "svc1", resource: "/pay#c1", ok: false, level: LevelKey},
+		{desc: "Another sub-part. The resource level trips", owner: "svc1", resource: "/pay#c2", ok: false, level: LevelResource},
+		{desc: "Another resource. The owner level trips", owner: "svc1", resource: "/orders", ok: false, level: LevelOwner},
+		{desc: "Another owner. The global level trips", owner: "svc2", resource: "/orders", ok: false, level: LevelGlobal},
+	}
+
+	for _, c := range cases {
+		d, err := limiter(key, c.owner, c.resource)
+		assert.NoError(t, err, c.desc)
+		assert.Equal(t, c.ok, d.Allowed, c.desc)
+		assert.Equal(t, c.level, d.Level, c.desc)
+	}
+
+	// Every hit counted at every level.
+	level := func(l Level) Descriptor { return Descriptor{Key: LevelDescriptor, Value: l.String()} }
+	assert.Len(t, store[Key("", "", level(LevelGlobal))], 6)
+	assert.Len(t, store[Key("svc1", "", level(LevelOwner))], 5)
+	assert.Len(t, store[Key("svc1", "/pay", level(LevelResource))], 4)
+	assert.Len(t, store[Key("svc1", "/pay#c1")], 3)
+	assert.Len(t, store[Key("svc2", "/orders")], 1)
+}
+
+func TestHierarchicalRateLimiter_Descriptors(t *testing.T) {
+	store := make(map[string][]time.Time)
+	limiter := HierarchicalRateLimiter(
+		SlideWindowRateLimiter(NewInMemorySlideWindowStorage(store)),
+		Hierarchy{Resource: Limits{NewLimit(PerMinute, 2)}},
+	)
+	key := Limits{NewLimit(PerMinute, 1)}
+
+	d, err := limiter(key, "svc1", "/pay", Descriptor{Key: "customer", Value: "c1"})
+	assert.NoError(t, err)
+	assert.True(t, d.Allowed)
+
+	// Another customer has its own key, but shares the resource level.
+	d, err = limiter(key, "svc1", "/pay", Descriptor{Key: "customer", Value: "c2"})
+	assert.NoError(t, err)
+	assert.True(t, d.Allowed)
+
+	d, err = limiter(key, "svc1", "/pay", Descriptor{Key: "customer", Value: "c3"})
+	assert.NoError(t, err)
+	assert.False(t, d.Allowed)
+	assert.Equal(t, LevelResource, d.Level)
+}
+
+func TestHierarchicalRateLimiter_EmptyLevels(t *testing.T) {
+	store := make(map[string][]time.Time)
+	limiter := HierarchicalRateLimiter(SlideWindowRateLimiter(NewInMemorySlideWindowStorage(store)), Hierarchy{})
+
+	d, err := limiter(Limits{NewLimit(PerMinute, 2)}, "svc1", "/pay")
+	assert.NoError(t, err)
+	assert.True(t, d.Allowed)
+	assert.Equal(t, LevelKey, d.Level)
+	assert.Len(t, store, 1)
+
+	_, err = limiter(nil, "svc1", "/pay")
+	assert.Error(t, err)
+}
+
+func TestHierarchicalRateLimiter_Error(t *testing.T) {
+	failing := func(l Limits, _, _ string, _ ...Descriptor) (Decision, error) {
+		return Decision{Limit: l[0]}, errors.New("storage down")
+	}
+
+	_, err := HierarchicalRateLimiter(failing, Hierarchy{Global: Limits{NewLimit(PerMinute, 5)}})(Limits{NewLimit(PerMinute, 2)}, "svc1", "/pay")
+	assert.EqualError(t, err, "storage down")
+}
@@ -0,0 +1,59 @@
+package rate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// KeyVersion prefixes all the keys, so their encoding can change without reading keys of the old one, which expire
+// as usual.
+const KeyVersion = "v1"
+
+// MaxKeyPartLength is the max length of a part of a key. Longer ones are hashed.
+const MaxKeyPartLength = 128
+
+// ReservedDescriptorPrefix is the prefix of the descriptor keys used by ratio itself.
+const ReservedDescriptorPrefix = "ratio."
+
+// Descriptor is a dimension of a hit. e.g. {Key: "customer", Value: "customer123"}.
+type Descriptor struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Key builds the storage key of the hits of an owner on a resource with the given descriptors. Every part is
+// prefixed by its length, so different parts never build the same key, and parts longer than MaxKeyPartLength are
+// replaced by their SHA-256, so keys are bounded. Storages shared with others may prefix them with a namespace.
+// Example: "v1:3:svc4:/pay8:customer3:c12".
+func Key(owner, resource string, descriptors ...Descriptor) string {
+	var b strings.Builder
+	b.WriteString(KeyVersion)
+	b.WriteByte(':')
+
+	write := func(s string) {
+		if len(s) > MaxKeyPartLength {
+			// The separator tells hashed parts apart from the ones that look like a hash.
+			sum := sha256.Sum256([]byte(s))
+			s = hex.EncodeToString(sum[:])
+			b.WriteString(strconv.Itoa(len(s)))
+			b.WriteByte('#')
+			b.WriteString(s)
+			return
+		}
+
+		b.WriteString(strconv.Itoa(len(s)))
+		b.WriteByte(':')
+		b.WriteString(s)
+	}
+
+	write(owner)
+	write(resource)
+	for _, d := range descriptors {
+		write(d.Key)
+		write(d.Value)
+	}
+
+	return b.String()
+}
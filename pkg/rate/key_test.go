@@ -0,0 +1,33 @@
+package rate
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKey(t *testing.T) {
+	assert.Equal(t, "v1:3:svc4:/pay", Key("svc", "/pay"))
+	assert.Equal(t, "v1:3:svc4:/pay8:customer3:c12", Key("svc", "/pay", Descriptor{Key: "customer", Value: "c12"}))
+	assert.Equal(t, "v1:0:0:", Key("", ""))
+
+	// Parts that used to collide.
+	assert.NotEqual(t, Key("a-b", "c"), Key("a", "b-c"))
+	assert.NotEqual(t, Key("a", "b", Descriptor{Key: "c", Value: "d"}), Key("a", "b", Descriptor{Key: "c:d"}))
+	assert.NotEqual(t, Key("1:a", ""), Key("", "a"))
+}
+
+func TestKey_LongParts(t *testing.T) {
+	long := strings.Repeat("/v1/order/pay", 20)
+	hashed := "64#" + fmt.Sprintf("%x", sha256.Sum256([]byte(long)))
+
+	assert.Equal(t, "v1:3:svc"+hashed, Key("svc", long))
+	assert.True(t, len(Key(long, long, Descriptor{Key: "customer", Value: long})) < 4*MaxKeyPartLength)
+
+	// A part that looks like a hash is not one.
+	assert.NotEqual(t, Key("svc", long), Key("svc", hashed[3:]))
+	assert.NotEqual(t, Key("svc", long), Key("svc", long+"x"))
+}
@@ -0,0 +1,163 @@
+package rate
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Default MultiRegionOptions.
+const (
+	DefaultReplicationQueueSize    = 10000
+	DefaultReplicationMaxStaleness = 10 * time.Second
+)
+
+// MultiRegionOptions configures the replication of a multi region SlideWindowStorage.
+type MultiRegionOptions struct {
+	// QueueSize is the max number of hits pending to be replicated per remote region. Hits are dropped when it is full.
+	QueueSize int
+	// MaxStaleness is the max time a hit can wait to be replicated. Older hits are dropped, so remote regions never
+	// receive hits older than that.
+	MaxStaleness time.Duration
+}
+
+type replicatedHit struct {
+	key        string
+	now        time.Time
+	expireIn   time.Duration
+	enqueuedAt time.Time
+}
+
+type replica struct {
+	storage SlideWindowStorage
+	queue   chan replicatedHit
+	dropped uint64
+}
+
+type multiRegionSlideWindowStorage struct {
+	local        SlideWindowStorage
+	replicas     []*replica
+	maxStaleness time.Duration
+
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewMultiRegionSlideWindowStorage creates a SlideWindowStorage spanning several regions, each one with its own storage.
+// Hits are written synchronously to the local region and replicated asynchronously to the remote ones, so each region
+// contains the hits of all of them. Reads are local only: Count is the sum of the hits across all regions, with a
+// staleness bounded by the replication lag of the other regions (at most MaxStaleness).
+func NewMultiRegionSlideWindowStorage(local SlideWindowStorage, remotes []SlideWindowStorage, o MultiRegionOptions) SlideWindowStorage {
+	if o.QueueSize <= 0 {
+		o.QueueSize = DefaultReplicationQueueSize
+	}
+
+	if o.MaxStaleness <= 0 {
+		o.MaxStaleness = DefaultReplicationMaxStaleness
+	}
+
+	s := &multiRegionSlideWindowStorage{
+		local:        local,
+		maxStaleness: o.MaxStaleness,
+	}
+
+	for _, remote := range remotes {
+		r := &replica{
+			storage: remote,
+			queue:   make(chan replicatedHit, o.QueueSize),
+		}
+		s.replicas = append(s.replicas, r)
+
+		s.wg.Add(1)
+		go s.replicate(r)
+	}
+
+	return s
+}
+
+func (s *multiRegionSlideWindowStorage) replicate(r *replica) {
+	defer s.wg.Done()
+
+	for hit := range r.queue {
+		if time.Since(hit.enqueuedAt) > s.maxStaleness {
+			atomic.AddUint64(&r.dropped, 1)
+			continue
+		}
+
+		if err := r.storage.Add(hit.key, hit.now, hit.expireIn); err != nil {
+			atomic.AddUint64(&r.dropped, 1)
+			log.Printf("error replicating hit: %s\n", err.Error())
+		}
+	}
+}
+
+func (s *multiRegionSlideWindowStorage) Add(key string, now time.Time, expireIn time.Duration) error {
+	if err := s.local.Add(key, now, expireIn); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil
+	}
+
+	hit := replicatedHit{key: key, now: now, expireIn: expireIn, enqueuedAt: time.Now()}
+	for _, r := range s.replicas {
+		select {
+		case r.queue <- hit:
+		default:
+			atomic.AddUint64(&r.dropped, 1)
+		}
+	}
+
+	return nil
+}
+
+func (s *multiRegionSlideWindowStorage) Drop(key string, until time.Time) (int, error) {
+	return s.local.Drop(key, until)
+}
+
+func (s *multiRegionSlideWindowStorage) Count(key string, until time.Time) (int, error) {
+	return s.local.Count(key, until)
+}
+
+func (s *multiRegionSlideWindowStorage) Flush() error {
+	return s.local.Flush()
+}
+
+// Ping only checks the local region, as the remote ones are not needed for serving.
+func (s *multiRegionSlideWindowStorage) Ping() error {
+	return s.local.Ping()
+}
+
+// Close stops the replication, waiting for the pending hits to be replicated, and closes all the region storages.
+func (s *multiRegionSlideWindowStorage) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	for _, r := range s.replicas {
+		close(r.queue)
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+
+	for i, r := range s.replicas {
+		if dropped := atomic.LoadUint64(&r.dropped); dropped > 0 {
+			log.Printf("%d hits were not replicated to remote region #%d\n", dropped, i)
+		}
+
+		if err := r.storage.Close(); err != nil {
+			log.Printf("error closing remote region #%d storage: %s\n", i, err.Error())
+		}
+	}
+
+	return s.local.Close()
+}
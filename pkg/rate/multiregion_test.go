@@ -0,0 +1,89 @@
+package rate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingSlideWindowStorage blocks every Add until unblock is closed.
+type blockingSlideWindowStorage struct {
+	SlideWindowStorage
+	unblock chan struct{}
+}
+
+func (s blockingSlideWindowStorage) Add(key string, now time.Time, expireIn time.Duration) error {
+	<-s.unblock
+	return s.SlideWindowStorage.Add(key, now, expireIn)
+}
+
+func TestMultiRegionSlideWindowStorage_Add(t *testing.T) {
+	local := NewInMemorySlideWindowStorage(make(map[string][]time.Time))
+	remote1 := NewInMemorySlideWindowStorage(make(map[string][]time.Time))
+	remote2 := NewInMemorySlideWindowStorage(make(map[string][]time.Time))
+
+	store := NewMultiRegionSlideWindowStorage(local, []SlideWindowStorage{remote1, remote2}, MultiRegionOptions{})
+
+	now := time.Now()
+	assert.NoError(t, store.Add("key1", now, time.Minute))
+
+	c, err := store.Count("key1", now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, c, "local writes should be synchronous")
+
+	assert.NoError(t, store.Close())
+
+	for _, remote := range []SlideWindowStorage{remote1, remote2} {
+		c, err := remote.Count("key1", now)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, c, "pending hits should be replicated on close")
+	}
+
+	assert.NoError(t, store.Add("key1", now, time.Minute), "adding after close should only write locally")
+}
+
+func TestMultiRegionSlideWindowStorage_Count(t *testing.T) {
+	local := NewInMemorySlideWindowStorage(make(map[string][]time.Time))
+	store := NewMultiRegionSlideWindowStorage(local, nil, MultiRegionOptions{})
+	defer store.Close()
+
+	now := time.Now()
+	// Hits replicated from other regions.
+	assert.NoError(t, local.Add("key1", now.Add(-time.Second), time.Minute))
+	assert.NoError(t, local.Add("key1", now.Add(-time.Second), time.Minute))
+	assert.NoError(t, store.Add("key1", now, time.Minute))
+
+	c, err := store.Count("key1", now)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, c)
+}
+
+func TestMultiRegionSlideWindowStorage_DropsWhenBehind(t *testing.T) {
+	local := NewInMemorySlideWindowStorage(make(map[string][]time.Time))
+	blocked := blockingSlideWindowStorage{
+		SlideWindowStorage: NewInMemorySlideWindowStorage(make(map[string][]time.Time)),
+		unblock:            make(chan struct{}),
+	}
+
+	store := NewMultiRegionSlideWindowStorage(local, []SlideWindowStorage{blocked}, MultiRegionOptions{
+		QueueSize:    1,
+		MaxStaleness: 10 * time.Millisecond,
+	})
+
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, store.Add("key1", now, time.Minute))
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	close(blocked.unblock)
+	assert.NoError(t, store.Close())
+
+	c, err := blocked.Count("key1", now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, c, "only the hit being replicated when the region got blocked should arrive")
+
+	r := store.(*multiRegionSlideWindowStorage).replicas[0]
+	assert.Equal(t, uint64(4), r.dropped)
+}
@@ -0,0 +1,145 @@
+package rate
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// PenaltyStorage stores the violations and the bans of a PenaltyBox. Implemented by the Redis and in memory storages.
+type PenaltyStorage interface {
+	CounterStorage
+	// Ban bans key until the given time, replacing any previous ban.
+	Ban(key string, until time.Time) error
+	// BannedUntil returns when the ban of key ends, the zero time if it is not banned.
+	BannedUntil(key string) (time.Time, error)
+	// Unban lifts the ban of key, returning false if it was not banned.
+	Unban(key string) (bool, error)
+}
+
+// PenaltyPolicy bans the keys exceeding their limits too often.
+type PenaltyPolicy struct {
+	// Violations is the number of OVER_LIMIT decisions of the limits of a key in a Window that bans it.
+	Violations int
+	// Window is the fixed window of time violations are counted in.
+	Window time.Duration
+	// Ban is how long a key stays banned.
+	Ban time.Duration
+}
+
+// Validate checks the policy is usable.
+func (p PenaltyPolicy) Validate() error {
+	if p.Violations < 1 {
+		return errors.New("invalid penalty policy: violations should be at least 1")
+	}
+
+	if p.Window <= 0 || p.Ban <= 0 {
+		return errors.New("invalid penalty policy: window and ban should be positive")
+	}
+
+	return nil
+}
+
+// PenaltyDescriptor is the descriptor identifying the violations and the bans of each key.
+const PenaltyDescriptor = ReservedDescriptorPrefix + "penalty"
+
+// PenaltyBox bans the keys of the hits violating the PenaltyPolicy.
+type PenaltyBox struct {
+	s PenaltyStorage
+	p PenaltyPolicy
+}
+
+// NewPenaltyBox creates a PenaltyBox storing its violations and bans in s.
+func NewPenaltyBox(s PenaltyStorage, p PenaltyPolicy) (*PenaltyBox, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &PenaltyBox{s: s, p: p}, nil
+}
+
+// BannedUntil returns when the ban of the hits of the owner on the resource with the given descriptors ends, the zero
+// time if they are not banned.
+func (b *PenaltyBox) BannedUntil(owner, resource string, descriptors ...Descriptor) (time.Time, error) {
+	until, err := b.s.BannedUntil(penaltyKey("ban", owner, resource, descriptors))
+	if err != nil || !until.After(time.Now()) {
+		return time.Time{}, err
+	}
+
+	return until, nil
+}
+
+// Unban lifts the ban of the hits of the owner on the resource with the given descriptors, and forgets their
+// violations. Returns false if they were not banned.
+func (b *PenaltyBox) Unban(owner, resource string, descriptors ...Descriptor) (bool, error) {
+	start := time.Now().Truncate(b.p.Window)
+	key := violationsKey(start, owner, resource, descriptors)
+	if n, err := b.s.Get(key); err != nil {
+		return false, err
+	} else if n > 0 {
+		if _, err := b.s.Incr(key, -n, start.Add(b.p.Window)); err != nil {
+			return false, err
+		}
+	}
+
+	return b.s.Unban(penaltyKey("ban", owner, resource, descriptors))
+}
+
+// violation counts a violation at now, banning the hits once the policy is violated. Returns when the ban ends, the
+// zero time if they are not banned.
+func (b *PenaltyBox) violation(now time.Time, owner, resource string, descriptors []Descriptor) (time.Time, error) {
+	start := now.Truncate(b.p.Window)
+	key := violationsKey(start, owner, resource, descriptors)
+	n, err := b.s.Incr(key, 1, start.Add(b.p.Window))
+	if err != nil || n < b.p.Violations {
+		return time.Time{}, err
+	}
+
+	until := now.Add(b.p.Ban)
+	if err := b.s.Ban(penaltyKey("ban", owner, resource, descriptors), until); err != nil {
+		return time.Time{}, err
+	}
+
+	return until, nil
+}
+
+// PenaltyRateLimiter rejects the hits of the keys banned by the PenaltyBox straight away, without checking nor
+// counting them against the limits of limiter. The hits rejected by limiter at LevelKey, other than by a period quota,
+// count as violations, so keys exceeding their own limits too often are banned. Banned hits are reported with
+// Decision.BannedUntil.
+func PenaltyRateLimiter(limiter Limiter, b *PenaltyBox) Limiter {
+	return func(l Limits, owner, resource string, descriptors ...Descriptor) (Decision, error) {
+		until, err := b.BannedUntil(owner, resource, descriptors...)
+		if err != nil {
+			return Decision{}, fmt.Errorf("checking the ban: %s", err.Error())
+		}
+
+		if !until.IsZero() {
+			return Decision{BannedUntil: until}, nil
+		}
+
+		d, err := limiter(l, owner, resource, descriptors...)
+		// Only the key exceeding its own window limits is to blame, not the ones sharing a hierarchy level nor its
+		// exceeded periods, reported along the window decisions as well.
+		if err != nil || d.Allowed || d.Level != LevelKey || (d.Period != nil && d.Period.Exceeded()) {
+			return d, err
+		}
+
+		if d.BannedUntil, err = b.violation(time.Now(), owner, resource, descriptors); err != nil {
+			log.Printf("error counting a violation of %s -> %s: %s\n", owner, resource, err.Error())
+		}
+
+		return d, nil
+	}
+}
+
+// violationsKey is the key of the violations counted in the window starting at start.
+func violationsKey(start time.Time, owner, resource string, descriptors []Descriptor) string {
+	return penaltyKey("violations:"+start.Format(time.RFC3339), owner, resource, descriptors)
+}
+
+func penaltyKey(kind, owner, resource string, descriptors []Descriptor) string {
+	d := Descriptor{Key: PenaltyDescriptor, Value: kind}
+	return Key(owner, resource, append(append([]Descriptor{}, descriptors...), d)...)
+}
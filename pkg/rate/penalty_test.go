@@ -0,0 +1,164 @@
+package rate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPenaltyPolicy_Validate(t *testing.T) {
+	assert.NoError(t, PenaltyPolicy{Violations: 1, Window: time.Minute, Ban: time.Hour}.Validate())
+	assert.Error(t, PenaltyPolicy{Window: time.Minute, Ban: time.Hour}.Validate())
+	assert.Error(t, PenaltyPolicy{Violations: 1, Ban: time.Hour}.Validate())
+	assert.Error(t, PenaltyPolicy{Violations: 1, Window: time.Minute}.Validate())
+}
+
+func TestPenaltyRateLimiter(t *testing.T) {
+	s := NewInMemorySlideWindowStorage(make(map[string][]time.Time))
+	box, err := NewPenaltyBox(s.(PenaltyStorage), PenaltyPolicy{Violations: 3, Window: time.Hour, Ban: time.Minute})
+	require.NoError(t, err)
+
+	calls := 0
+	slideWindow := SlideWindowRateLimiter(s)
+	limiter := PenaltyRateLimiter(func(l Limits, owner, resource string, descriptors ...Descriptor) (Decision, error) {
+		calls++
+		return slideWindow(l, owner, resource, descriptors...)
+	}, box)
+	limits := Limits{NewLimit(PerMinute, 1)}
+
+	d, err := limiter(limits, "svc", "/pay")
+	require.NoError(t, err)
+	assert.True(t, d.Allowed, "allowed hits are not violations")
+
+	for i := 0; i < 2; i++ {
+		d, err = limiter(limits, "svc", "/pay")
+		require.NoError(t, err)
+		assert.False(t, d.Allowed)
+		assert.True(t, d.BannedUntil.IsZero())
+	}
+
+	d, err = limiter(limits, "svc", "/pay")
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), d.BannedUntil, time.Second, "the third violation bans the key")
+	assert.Equal(t, 4, calls)
+
+	d, err = limiter(limits, "svc", "/pay")
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+	assert.False(t, d.BannedUntil.IsZero())
+	assert.Equal(t, 4, calls, "banned hits should not reach the limiter")
+
+	d, err = limiter(limits, "svc", "/orders")
+	require.NoError(t, err)
+	assert.True(t, d.Allowed, "every key has its own ban")
+
+	unbanned, err := box.Unban("svc", "/pay")
+	require.NoError(t, err)
+	assert.True(t, unbanned)
+
+	unbanned, err = box.Unban("svc", "/pay")
+	require.NoError(t, err)
+	assert.False(t, unbanned)
+
+	d, err = limiter(limits, "svc", "/pay")
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+	assert.True(t, d.BannedUntil.IsZero(), "violations are forgotten on unban")
+	assert.Equal(t, 6, calls)
+}
+
+func TestPenaltyRateLimiter_OtherLevels(t *testing.T) {
+	s := NewInMemorySlideWindowStorage(make(map[string][]time.Time))
+	box, err := NewPenaltyBox(s.(PenaltyStorage), PenaltyPolicy{Violations: 1, Window: time.Hour, Ban: time.Minute})
+	require.NoError(t, err)
+
+	limits := Limits{NewLimit(PerMinute, 1)}
+	cases := map[string]Decision{
+		"Global level":   {Limit: limits[0], Level: LevelGlobal},
+		"Owner level":    {Limit: limits[0], Level: LevelOwner},
+		"Resource level": {Limit: limits[0], Level: LevelResource},
+	}
+
+	for name, rejection := range cases {
+		t.Run(name, func(t *testing.T) {
+			limiter := PenaltyRateLimiter(func(Limits, string, string, ...Descriptor) (Decision, error) {
+				return rejection, nil
+			}, box)
+
+			d, err := limiter(limits, "svc", "/pay")
+			require.NoError(t, err)
+			assert.False(t, d.Allowed)
+			assert.True(t, d.BannedUntil.IsZero(), "rejections not caused by the key itself are not violations")
+
+			until, err := box.BannedUntil("svc", "/pay")
+			require.NoError(t, err)
+			assert.True(t, until.IsZero())
+		})
+	}
+}
+
+func TestPenaltyRateLimiter_Periods(t *testing.T) {
+	s := NewInMemorySlideWindowStorage(make(map[string][]time.Time))
+	box, err := NewPenaltyBox(s.(PenaltyStorage), PenaltyPolicy{Violations: 1, Window: time.Hour, Ban: time.Minute})
+	require.NoError(t, err)
+
+	limiter := PenaltyRateLimiter(PeriodRateLimiter(
+		SlideWindowRateLimiter(s),
+		s.(CounterStorage),
+		PeriodLimits{{Period: Period{Unit: Daily}, Quantity: 2}},
+		nil,
+	), box)
+
+	for i := 0; i < 2; i++ {
+		d, err := limiter(Limits{NewLimit(PerMinute, 100)}, "svc", "/pay")
+		require.NoError(t, err)
+		assert.True(t, d.Allowed)
+	}
+
+	d, err := limiter(Limits{NewLimit(PerMinute, 100)}, "svc", "/pay")
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+	require.NotNil(t, d.Period)
+	assert.True(t, d.BannedUntil.IsZero(), "exceeded periods are not violations")
+
+	d, err = limiter(Limits{NewLimit(PerMinute, 1)}, "svc", "/orders")
+	require.NoError(t, err)
+	assert.True(t, d.Allowed)
+
+	d, err = limiter(Limits{NewLimit(PerMinute, 1)}, "svc", "/orders")
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+	require.NotNil(t, d.Period, "the period is reported along the window decision")
+	assert.False(t, d.BannedUntil.IsZero(), "window rejections are violations whatever the periods")
+}
+
+func TestInMemorySlideWindowStorage_Ban(t *testing.T) {
+	s := NewInMemorySlideWindowStorage(make(map[string][]time.Time)).(PenaltyStorage)
+	until := time.Now().Add(time.Hour)
+
+	banned, err := s.BannedUntil("key1")
+	require.NoError(t, err)
+	assert.True(t, banned.IsZero())
+
+	require.NoError(t, s.Ban("key1", until))
+	require.NoError(t, s.Ban("key2", time.Now().Add(-time.Second)))
+
+	banned, err = s.BannedUntil("key1")
+	require.NoError(t, err)
+	assert.Equal(t, until, banned)
+
+	banned, err = s.BannedUntil("key2")
+	require.NoError(t, err)
+	assert.True(t, banned.IsZero(), "expired bans are lifted")
+
+	unbanned, err := s.Unban("key2")
+	require.NoError(t, err)
+	assert.False(t, unbanned)
+
+	unbanned, err = s.Unban("key1")
+	require.NoError(t, err)
+	assert.True(t, unbanned)
+}
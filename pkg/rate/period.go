@@ -0,0 +1,268 @@
+package rate
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CounterStorage stores plain counters, used for the limits of long periods where keeping every hit is too expensive.
+// Implemented by the Redis and in memory storages.
+type CounterStorage interface {
+	// Incr adds n, which may be negative, to the counter of key and returns its new value. The counter expires at
+	// expireAt.
+	Incr(key string, n int, expireAt time.Time) (int, error)
+	// Get returns the counter of key, 0 if it does not exist.
+	Get(key string) (int, error)
+}
+
+// PeriodUnit is the unit of a calendar Period.
+type PeriodUnit int
+
+// Period units.
+const (
+	// Daily periods start at midnight.
+	Daily PeriodUnit = iota + 1
+	// Monthly periods start at midnight of the billing day of the month.
+	Monthly
+)
+
+// Period is a calendar period, aligned to the days of a time zone instead of sliding.
+type Period struct {
+	Unit PeriodUnit
+	// BillingDay is the day of the month monthly periods start at. Months with less days start on their last day.
+	// Zero means 1.
+	BillingDay int
+	// Location is the time zone of the period. Nil means UTC.
+	Location *time.Location
+}
+
+// Bounds returns the start and the end of the period t belongs to.
+func (p Period) Bounds(t time.Time) (time.Time, time.Time) {
+	loc := p.location()
+	t = t.In(loc)
+	if p.Unit == Daily {
+		start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 0, 1)
+	}
+
+	start := p.monthStart(t.Year(), t.Month())
+	if t.Before(start) {
+		start = p.monthStart(t.Year(), t.Month()-1)
+	}
+
+	return start, p.monthStart(start.Year(), start.Month()+1)
+}
+
+// monthStart returns the start of the monthly period of the given month, normalizing out of range months.
+func (p Period) monthStart(year int, month time.Month) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, p.location())
+
+	day := p.BillingDay
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	if day < 1 {
+		day = 1
+	}
+
+	return first.AddDate(0, 0, day-1)
+}
+
+func (p Period) location() *time.Location {
+	if p.Location == nil {
+		return time.UTC
+	}
+
+	return p.Location
+}
+
+func (p Period) String() string {
+	if p.Unit == Daily {
+		return "day"
+	}
+
+	if p.BillingDay > 1 {
+		return "month@" + strconv.Itoa(p.BillingDay)
+	}
+
+	return "month"
+}
+
+// ParsePeriod returns a Period from a string representation: "day", "month", or "month@N" for monthly periods
+// starting on the day N. Case insensitive.
+func ParsePeriod(s string, loc *time.Location) (Period, error) {
+	unit, day := strings.ToLower(s), ""
+	if i := strings.Index(unit, "@"); i != -1 {
+		unit, day = unit[:i], unit[i+1:]
+	}
+
+	p := Period{Location: loc}
+	switch unit {
+	case "day":
+		p.Unit = Daily
+		if day != "" {
+			return Period{}, fmt.Errorf("%s is not a valid period: only monthly periods have a billing day", s)
+		}
+	case "month":
+		p.Unit = Monthly
+		if day != "" {
+			d, err := strconv.Atoi(day)
+			if err != nil || d < 1 || d > 31 {
+				return Period{}, fmt.Errorf("%s is not a valid period: invalid billing day %s", s, day)
+			}
+			p.BillingDay = d
+		}
+	default:
+		return Period{}, fmt.Errorf("%s is not a valid period: unknown unit %s", s, unit)
+	}
+
+	return p, nil
+}
+
+// PeriodLimit is the max number of hits allowed during a calendar Period.
+type PeriodLimit struct {
+	Period   Period
+	Quantity int
+}
+
+// ParsePeriodLimit returns a PeriodLimit from a string representation in the time zone loc.
+// Example: "50000/day", "1000000/month@15".
+func ParsePeriodLimit(s string, loc *time.Location) (PeriodLimit, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 {
+		return PeriodLimit{}, fmt.Errorf("%s is not a valid period limit", s)
+	}
+
+	q, err := strconv.Atoi(parts[0])
+	if err != nil || q < 0 {
+		return PeriodLimit{}, fmt.Errorf("%s is not a valid period limit: %s is not a valid quantity", s, parts[0])
+	}
+
+	p, err := ParsePeriod(parts[1], loc)
+	if err != nil {
+		return PeriodLimit{}, fmt.Errorf("%s is not a valid period limit: %s", s, err.Error())
+	}
+
+	return PeriodLimit{Period: p, Quantity: q}, nil
+}
+
+// PeriodLimits are several PeriodLimit enforced at once.
+type PeriodLimits []PeriodLimit
+
+// ParsePeriodLimits returns PeriodLimits from a string representation of several PeriodLimit separated by ";".
+// Example: "50000/day;1000000/month@15"
+func ParsePeriodLimits(s string, loc *time.Location) (PeriodLimits, error) {
+	var limits PeriodLimits
+	for _, part := range strings.Split(s, ";") {
+		l, err := ParsePeriodLimit(strings.TrimSpace(part), loc)
+		if err != nil {
+			return nil, err
+		}
+
+		limits = append(limits, l)
+	}
+
+	return limits, nil
+}
+
+// PeriodUsage are the hits of a key during the current period of a PeriodLimit.
+type PeriodUsage struct {
+	Limit PeriodLimit
+	// Hits is the number of hits counted in the period, the current one excluded.
+	Hits  int
+	Start time.Time
+	End   time.Time
+
+	key string
+}
+
+// Exceeded tells whether no hits are left in the period, so the current one is rejected.
+func (u PeriodUsage) Exceeded() bool {
+	return u.Hits >= u.Limit.Quantity
+}
+
+// Remaining returns the number of hits still allowed in the period, after the current one.
+func (u PeriodUsage) Remaining() int {
+	if remaining := u.Limit.Quantity - u.Hits - 1; remaining > 0 {
+		return remaining
+	}
+
+	return 0
+}
+
+// PeriodDescriptor is the descriptor identifying the counters of each period.
+const PeriodDescriptor = ReservedDescriptorPrefix + "period"
+
+// PeriodRateLimiter enforces calendar period limits besides the ones of limiter. The period limits are the ones of
+// the first rule matching the hit with period limits, or limits if none does.
+// The periods are checked first, so the hits rejected by a period count against neither the periods nor the limits of
+// limiter, which is not called; such decisions only carry Decision.Period. The hits rejected by limiter do not count
+// against the periods either. The period with less remaining hits, or the one exceeded, is reported in
+// Decision.Period.
+func PeriodRateLimiter(limiter Limiter, s CounterStorage, limits PeriodLimits, rules Rules) Limiter {
+	return func(l Limits, owner, resource string, descriptors ...Descriptor) (Decision, error) {
+		pls := rules.PeriodLimits(limits, owner, resource, descriptors...)
+		if len(pls) == 0 {
+			return limiter(l, owner, resource, descriptors...)
+		}
+
+		now := time.Now()
+		var (
+			counted  []PeriodUsage
+			reported *PeriodUsage
+		)
+		for _, pl := range pls {
+			u := PeriodUsage{Limit: pl}
+			u.Start, u.End = pl.Period.Bounds(now)
+			u.key = periodKey(pl.Period, u.Start, owner, resource, descriptors)
+
+			hits, err := s.Incr(u.key, 1, u.End)
+			if err != nil {
+				undo(s, counted)
+				return Decision{}, fmt.Errorf("counting period hits: %s", err.Error())
+			}
+			counted = append(counted, u)
+			u.Hits = hits - 1
+
+			switch {
+			case reported == nil:
+			case reported.Exceeded():
+				continue
+			case !u.Exceeded() && u.Limit.Quantity-u.Hits >= reported.Limit.Quantity-reported.Hits:
+				continue
+			}
+			usage := u
+			reported = &usage
+		}
+
+		if reported.Exceeded() {
+			undo(s, counted)
+			return Decision{Period: reported}, nil
+		}
+
+		d, err := limiter(l, owner, resource, descriptors...)
+		if err != nil || !d.Allowed {
+			undo(s, counted)
+		}
+		d.Period = reported
+
+		return d, err
+	}
+}
+
+// undo takes back the hits counted in the periods.
+func undo(s CounterStorage, counted []PeriodUsage) {
+	for _, u := range counted {
+		if _, err := s.Incr(u.key, -1, u.End); err != nil {
+			log.Printf("error taking a period hit back: %s\n", err.Error())
+		}
+	}
+}
+
+func periodKey(p Period, start time.Time, owner, resource string, descriptors []Descriptor) string {
+	d := Descriptor{Key: PeriodDescriptor, Value: p.String() + ":" + start.Format(time.RFC3339)}
+	return Key(owner, resource, append(append([]Descriptor{}, descriptors...), d)...)
+}
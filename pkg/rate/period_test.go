@@ -0,0 +1,209 @@
+package rate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePeriodLimit(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	require.NoError(t, err)
+
+	cases := []struct {
+		string      string
+		limit       PeriodLimit
+		shouldError bool
+	}{
+		{string: "50000/day", limit: PeriodLimit{Period: Period{Unit: Daily, Location: madrid}, Quantity: 50000}},
+		{string: "1000/MONTH", limit: PeriodLimit{Period: Period{Unit: Monthly, Location: madrid}, Quantity: 1000}},
+		{string: "1000/month@15", limit: PeriodLimit{Period: Period{Unit: Monthly, BillingDay: 15, Location: madrid}, Quantity: 1000}},
+		{string: "1000/month@31", limit: PeriodLimit{Period: Period{Unit: Monthly, BillingDay: 31, Location: madrid}, Quantity: 1000}},
+		{string: "1000/month@0", shouldError: true},
+		{string: "1000/month@32", shouldError: true},
+		{string: "1000/day@2", shouldError: true},
+		{string: "1000/year", shouldError: true},
+		{string: "1000/m", shouldError: true},
+		{string: "lots/month", shouldError: true},
+		{string: "1000", shouldError: true},
+	}
+
+	for _, c := range cases {
+		l, err := ParsePeriodLimit(c.string, madrid)
+		if c.shouldError {
+			assert.Error(t, err, c.string)
+		} else {
+			assert.NoError(t, err, c.string)
+		}
+
+		assert.Equal(t, c.limit, l)
+	}
+
+	ls, err := ParsePeriodLimits("50000/day; 1000000/month@15", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, PeriodLimits{
+		{Period: Period{Unit: Daily}, Quantity: 50000},
+		{Period: Period{Unit: Monthly, BillingDay: 15}, Quantity: 1000000},
+	}, ls)
+}
+
+func TestPeriod_Bounds(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	require.NoError(t, err)
+
+	utc := func(s string) time.Time {
+		parsed, err := time.Parse(time.RFC3339, s)
+		require.NoError(t, err)
+		return parsed
+	}
+
+	cases := []struct {
+		desc   string
+		period Period
+		t      string
+		start  string
+		end    string
+	}{
+		{
+			desc:   "Day in UTC",
+			period: Period{Unit: Daily},
+			t:      "2026-10-19T23:30:00Z",
+			start:  "2026-10-19T00:00:00Z",
+			end:    "2026-10-20T00:00:00Z",
+		},
+		{
+			desc:   "Day in another time zone",
+			period: Period{Unit: Daily, Location: madrid},
+			t:      "2026-10-19T23:30:00Z",
+			start:  "2026-10-20T00:00:00+02:00",
+			end:    "2026-10-21T00:00:00+02:00",
+		},
+		{
+			desc:   "Calendar month",
+			period: Period{Unit: Monthly},
+			t:      "2026-10-19T10:00:00Z",
+			start:  "2026-10-01T00:00:00Z",
+			end:    "2026-11-01T00:00:00Z",
+		},
+		{
+			desc:   "Before the billing day",
+			period: Period{Unit: Monthly, BillingDay: 20},
+			t:      "2026-10-19T10:00:00Z",
+			start:  "2026-09-20T00:00:00Z",
+			end:    "2026-10-20T00:00:00Z",
+		},
+		{
+			desc:   "On the billing day",
+			period: Period{Unit: Monthly, BillingDay: 19},
+			t:      "2026-10-19T10:00:00Z",
+			start:  "2026-10-19T00:00:00Z",
+			end:    "2026-11-19T00:00:00Z",
+		},
+		{
+			desc:   "Billing day after the end of February",
+			period: Period{Unit: Monthly, BillingDay: 31},
+			t:      "2027-03-15T10:00:00Z",
+			start:  "2027-02-28T00:00:00Z",
+			end:    "2027-03-31T00:00:00Z",
+		},
+		{
+			desc:   "Across a year, in another time zone",
+			period: Period{Unit: Monthly, Location: madrid},
+			t:      "2026-12-31T23:30:00Z",
+			start:  "2027-01-01T00:00:00+01:00",
+			end:    "2027-02-01T00:00:00+01:00",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			start, end := c.period.Bounds(utc(c.t))
+			assert.True(t, utc(c.start).Equal(start), "start %s", start)
+			assert.True(t, utc(c.end).Equal(end), "end %s", end)
+		})
+	}
+}
+
+func TestPeriodRateLimiter(t *testing.T) {
+	s := NewInMemorySlideWindowStorage(make(map[string][]time.Time))
+	limiter := PeriodRateLimiter(
+		SlideWindowRateLimiter(s),
+		s.(CounterStorage),
+		PeriodLimits{{Period: Period{Unit: Daily}, Quantity: 3}, {Period: Period{Unit: Monthly}, Quantity: 5}},
+		nil,
+	)
+	limits := Limits{NewLimit(PerMinute, 100)}
+
+	for i := 0; i < 3; i++ {
+		d, err := limiter(limits, "svc", "/pay")
+		require.NoError(t, err)
+		assert.True(t, d.Allowed)
+		require.NotNil(t, d.Period)
+		assert.Equal(t, Daily, d.Period.Limit.Period.Unit, "the day has less remaining hits")
+		assert.Equal(t, i, d.Period.Hits)
+		assert.Equal(t, 2-i, d.Period.Remaining())
+	}
+
+	d, err := limiter(limits, "svc", "/pay")
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+	assert.Equal(t, Daily, d.Period.Limit.Period.Unit)
+	assert.Equal(t, 0, d.Period.Remaining())
+
+	hits, err := s.Count(Key("svc", "/pay"), time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, hits, "hits rejected by a period should not count in the window")
+
+	counters := s.(CounterStorage)
+	start, _ := Period{Unit: Monthly}.Bounds(time.Now())
+	month, err := counters.Get(periodKey(Period{Unit: Monthly}, start, "svc", "/pay", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, month, "hits rejected by a period should not count against the others")
+
+	d, err = limiter(limits, "svc", "/orders")
+	require.NoError(t, err)
+	assert.True(t, d.Allowed, "every key has its own periods")
+}
+
+func TestPeriodRateLimiter_RejectedHitsNotCounted(t *testing.T) {
+	s := NewInMemorySlideWindowStorage(make(map[string][]time.Time))
+	limiter := PeriodRateLimiter(
+		SlideWindowRateLimiter(s),
+		s.(CounterStorage),
+		PeriodLimits{{Period: Period{Unit: Monthly}, Quantity: 5}},
+		nil,
+	)
+
+	for i := 0; i < 3; i++ {
+		_, err := limiter(Limits{NewLimit(PerMinute, 1)}, "svc", "/pay")
+		require.NoError(t, err)
+	}
+
+	d, err := limiter(Limits{NewLimit(PerMinute, 1)}, "svc", "/pay")
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+	assert.Equal(t, 1, d.Period.Hits, "only the allowed hit should count against the month")
+}
+
+func TestPeriodRateLimiter_Rules(t *testing.T) {
+	rules, err := CompileRules([]Rule{{Owner: "acme", Period: "1/month@15", Timezone: "Europe/Madrid"}})
+	require.NoError(t, err)
+
+	s := NewInMemorySlideWindowStorage(make(map[string][]time.Time))
+	limiter := PeriodRateLimiter(SlideWindowRateLimiter(s), s.(CounterStorage), nil, rules)
+	limits := Limits{NewLimit(PerMinute, 100)}
+
+	for i, ok := range []bool{true, false} {
+		d, err := limiter(limits, "acme", "/pay")
+		require.NoError(t, err)
+		assert.Equal(t, ok, d.Allowed, "hit %d", i)
+		assert.Equal(t, "month@15", d.Period.Limit.Period.String())
+	}
+
+	d, err := limiter(limits, "other", "/pay")
+	require.NoError(t, err)
+	assert.True(t, d.Allowed)
+	assert.Nil(t, d.Period, "owners without period limits should not report any")
+}
@@ -0,0 +1,107 @@
+package rate
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// WeightedSlideWindowStorage is a SlideWindowStorage that stores entries weighing several hits, like the permits of
+// a quota lease. Count includes their weight. Implemented by the Redis and in memory storages.
+type WeightedSlideWindowStorage interface {
+	SlideWindowStorage
+	// AddWeighted adds a single entry identified by id weighing n hits.
+	AddWeighted(key, id string, n int, now time.Time, expireIn time.Duration) error
+	// Unweight subtracts up to n hits from the weight of the entry. Returns the hits subtracted and when the entry
+	// was added, 0 and the zero time if the entry is not found, e.g. because it is out of the window already.
+	Unweight(key, id string, n int) (int, time.Time, error)
+}
+
+// Reservation is a quota lease: a batch of permits reserved at once and consumed by the caller on its own.
+type Reservation struct {
+	// ID identifies the reservation for returning the unused permits. Empty if no permit is reserved.
+	ID string
+	// Permits is the number of permits reserved, which may be less than the requested.
+	Permits int
+	// Limit is the limit with less remaining hits.
+	Limit Limit
+	// Hits is the number of hits found in the window of Limit, the reserved ones excluded.
+	Hits int
+}
+
+// Remaining returns the number of hits still allowed in the window, after the reserved ones.
+func (r Reservation) Remaining() int {
+	if remaining := r.Limit.Quantity - r.Hits - r.Permits; remaining > 0 {
+		return remaining
+	}
+
+	return 0
+}
+
+// QuotaLeaser reserves batches of permits, counted as hits of the same key the Limiter uses, so callers do not need
+// to call ratio on every hit.
+type QuotaLeaser interface {
+	// Reserve reserves up to permits hits, as many as all the limits still allow.
+	Reserve(l Limits, permits int, owner, resource string, descriptors ...Descriptor) (Reservation, error)
+	// Return gives the unused permits of a reservation back. Returns the permits given back and when they were
+	// reserved.
+	Return(id string, unused int, owner, resource string, descriptors ...Descriptor) (int, time.Time, error)
+}
+
+type slideWindowQuotaLeaser struct {
+	s WeightedSlideWindowStorage
+}
+
+// NewSlideWindowQuotaLeaser creates a QuotaLeaser storing every reservation as a single weighted entry.
+func NewSlideWindowQuotaLeaser(s WeightedSlideWindowStorage) QuotaLeaser {
+	return slideWindowQuotaLeaser{s: s}
+}
+
+func (q slideWindowQuotaLeaser) Reserve(ls Limits, permits int, owner, resource string, descriptors ...Descriptor) (Reservation, error) {
+	if len(ls) == 0 {
+		return Reservation{}, fmt.Errorf("no limits for %s -> %s", owner, resource)
+	}
+
+	if permits <= 0 {
+		return Reservation{Limit: ls[0]}, errors.New("the permits to reserve should be greater than 0")
+	}
+
+	now := time.Now()
+	key := Key(owner, resource, descriptors...)
+
+	d, err := slideWindowDecision(q.s, ls, key, now)
+	if err != nil {
+		return Reservation{Limit: d.Limit}, err
+	}
+
+	r := Reservation{Limit: d.Limit, Hits: d.Hits}
+	if available := d.Limit.Quantity - d.Hits; available < permits {
+		permits = available
+	}
+
+	if permits <= 0 {
+		return r, nil
+	}
+
+	id, err := leaseID()
+	if err != nil {
+		return r, err
+	}
+
+	if err := q.s.AddWeighted(key, id, permits, now, ls.window()); err != nil {
+		return r, fmt.Errorf("reserving permits: %s", err.Error())
+	}
+
+	r.ID = id
+	r.Permits = permits
+
+	return r, nil
+}
+
+func (q slideWindowQuotaLeaser) Return(id string, unused int, owner, resource string, descriptors ...Descriptor) (int, time.Time, error) {
+	if unused <= 0 {
+		return 0, time.Time{}, nil
+	}
+
+	return q.s.Unweight(Key(owner, resource, descriptors...), id, unused)
+}
@@ -0,0 +1,96 @@
+package rate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlideWindowQuotaLeaser(t *testing.T) {
+	r, m := createRedis()
+	defer m.Close()
+
+	storages := map[string]WeightedSlideWindowStorage{
+		"In memory": NewInMemorySlideWindowStorage(make(map[string][]time.Time)).(WeightedSlideWindowStorage),
+		"Redis":     NewRedisSlideWindowStorage(r, DefaultRedisNamespace).(WeightedSlideWindowStorage),
+	}
+
+	for desc, s := range storages {
+		t.Run(desc, func(t *testing.T) {
+			q := NewSlideWindowQuotaLeaser(s)
+			limiter := SlideWindowRateLimiter(s)
+			limits := Limits{NewLimit(PerMinute, 10), NewLimit(PerHour, 100)}
+
+			d, err := limiter(limits, "svc", "/search")
+			require.NoError(t, err)
+			assert.True(t, d.Allowed)
+
+			res, err := q.Reserve(limits, 6, "svc", "/search")
+			require.NoError(t, err)
+			assert.NotEmpty(t, res.ID)
+			assert.Equal(t, 6, res.Permits)
+			assert.Equal(t, NewLimit(PerMinute, 10), res.Limit)
+			assert.Equal(t, 1, res.Hits)
+			assert.Equal(t, 3, res.Remaining())
+
+			// Reserved permits count as hits of the same key.
+			hits, err := s.Count(Key("svc", "/search"), time.Now())
+			require.NoError(t, err)
+			assert.Equal(t, 7, hits)
+
+			// Only the permits still allowed are reserved.
+			partial, err := q.Reserve(limits, 6, "svc", "/search")
+			require.NoError(t, err)
+			assert.Equal(t, 3, partial.Permits)
+			assert.Equal(t, 0, partial.Remaining())
+
+			none, err := q.Reserve(limits, 1, "svc", "/search")
+			require.NoError(t, err)
+			assert.Empty(t, none.ID)
+			assert.Equal(t, 0, none.Permits)
+
+			d, err = limiter(limits, "svc", "/search")
+			require.NoError(t, err)
+			assert.False(t, d.Allowed)
+
+			// Unused permits are given back.
+			returned, reservedAt, err := q.Return(res.ID, 4, "svc", "/search")
+			require.NoError(t, err)
+			assert.Equal(t, 4, returned)
+			assert.WithinDuration(t, time.Now(), reservedAt, time.Second)
+
+			returned, again, err := q.Return(res.ID, 4, "svc", "/search")
+			require.NoError(t, err)
+			assert.Equal(t, 2, returned, "only the permits left are given back")
+			assert.True(t, again.Equal(reservedAt), "the reservation keeps its time")
+
+			returned, reservedAt, err = q.Return(res.ID, 1, "svc", "/search")
+			require.NoError(t, err)
+			assert.Equal(t, 0, returned)
+			assert.True(t, reservedAt.IsZero())
+
+			hits, err = s.Count(Key("svc", "/search"), time.Now())
+			require.NoError(t, err)
+			assert.Equal(t, 5, hits)
+
+			// Reservations are dropped out of the window as any hit.
+			_, err = s.Drop(Key("svc", "/search"), time.Now().Add(time.Second))
+			require.NoError(t, err)
+			hits, err = s.Count(Key("svc", "/search"), time.Now())
+			require.NoError(t, err)
+			assert.Equal(t, 0, hits)
+		})
+	}
+}
+
+func TestSlideWindowQuotaLeaser_InvalidReservations(t *testing.T) {
+	q := NewSlideWindowQuotaLeaser(NewInMemorySlideWindowStorage(make(map[string][]time.Time)).(WeightedSlideWindowStorage))
+
+	_, err := q.Reserve(nil, 1, "svc", "/search")
+	assert.Error(t, err)
+
+	_, err = q.Reserve(Limits{NewLimit(PerMinute, 10)}, 0, "svc", "/search")
+	assert.Error(t, err)
+}
@@ -20,23 +20,80 @@ func (f Frequency) Duration() time.Duration {
 
 // Default Frequencies
 const (
-	PerDay    = Frequency(time.Hour * 60)
-	PerHour   = Frequency(time.Hour)
+	PerSecond = Frequency(time.Second)
 	PerMinute = Frequency(time.Minute)
+	PerHour   = Frequency(time.Hour)
+	PerDay    = Frequency(time.Hour * 24)
+	PerWeek   = Frequency(time.Hour * 24 * 7)
 )
 
-// ParseFrequency returns a Frequency from a string representation.
+var frequencyNames = map[string]Frequency{
+	"second": PerSecond,
+	"minute": PerMinute,
+	"hour":   PerHour,
+	"day":    PerDay,
+	"week":   PerWeek,
+}
+
+var durationUnits = map[string]time.Duration{
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  time.Hour * 24,
+	"w":  time.Hour * 24 * 7,
+}
+
+// ParseFrequency returns a Frequency from a string representation. It accepts unit names ("second", "minute",
+// "hour", "day", "week"), case insensitive, and durations made of one or more quantity and unit (ms, s, m, h, d, w)
+// pairs. A missing quantity means 1.
+// Example: "day", "m", "10s", "1h30m", "2w".
 func ParseFrequency(s string) (Frequency, error) {
-	switch s {
-	case "day", "DAY", "d":
-		return PerDay, nil
-	case "hour", "HOUR", "h":
-		return PerHour, nil
-	case "minute", "MINUTE", "m":
-		return PerMinute, nil
+	lower := strings.ToLower(s)
+	if f, ok := frequencyNames[lower]; ok {
+		return f, nil
+	}
+
+	if lower == "" {
+		return 0, fmt.Errorf("empty frequency for a rate")
 	}
 
-	return 0, fmt.Errorf("%s is not a valid frequency for a rate", s)
+	var d time.Duration
+	rest := lower
+	for rest != "" {
+		i := strings.IndexFunc(rest, func(r rune) bool { return r < '0' || r > '9' })
+		if i == -1 {
+			return 0, fmt.Errorf("%s is not a valid frequency for a rate: missing unit after %s", s, rest)
+		}
+
+		q := 1
+		if i > 0 {
+			var err error
+			if q, err = strconv.Atoi(rest[:i]); err != nil {
+				return 0, fmt.Errorf("%s is not a valid frequency for a rate: invalid quantity %s", s, rest[:i])
+			}
+		}
+		rest = rest[i:]
+
+		j := strings.IndexFunc(rest, func(r rune) bool { return r >= '0' && r <= '9' })
+		if j == -1 {
+			j = len(rest)
+		}
+
+		unit, ok := durationUnits[rest[:j]]
+		if !ok {
+			return 0, fmt.Errorf("%s is not a valid frequency for a rate: unknown unit %s", s, rest[:j])
+		}
+		rest = rest[j:]
+
+		d += time.Duration(q) * unit
+	}
+
+	if d <= 0 {
+		return 0, fmt.Errorf("%s is not a valid frequency for a rate: it should be greater than 0", s)
+	}
+
+	return Frequency(d), nil
 }
 
 // Limit represents the rate limit of a repeating event (hits) per unit of time.
@@ -51,7 +108,7 @@ func NewLimit(unit Frequency, quantity int) Limit {
 }
 
 // ParseLimit parses a Limit from a string representation.
-// Example: "100/day", "50/minute", "1/m"
+// Example: "100/day", "50/minute", "1/m", "5/30s", "1000/1h30m"
 func ParseLimit(s string) (Limit, error) {
 	var l Limit
 	parts := strings.Split(s, "/")
@@ -61,12 +118,12 @@ func ParseLimit(s string) (Limit, error) {
 
 	f, err := ParseFrequency(parts[1])
 	if err != nil {
-		return l, err
+		return l, fmt.Errorf("%s is not a valid limit: %s", s, err.Error())
 	}
 
 	q, err := strconv.Atoi(parts[0])
-	if err != nil {
-		return l, fmt.Errorf("%s is not a valid quantity", parts[0])
+	if err != nil || q < 0 {
+		return l, fmt.Errorf("%s is not a valid limit: %s is not a valid quantity", s, parts[0])
 	}
 
 	l.Unit = f
@@ -75,39 +132,127 @@ func ParseLimit(s string) (Limit, error) {
 	return l, nil
 }
 
-// Limiter rate limits a resource for a given owner based on a Rate.
-type Limiter func(l Limit, owner, resource string) (bool, error)
+// Limits is a set of limits on different windows (e.g. a burst and a sustained limit) evaluated together against the
+// same hits.
+type Limits []Limit
 
-// SlideWindowRateLimiter limits based on a time window that is always in movement (sliding).
-func SlideWindowRateLimiter(s SlideWindowStorage, async bool) Limiter {
-	return func(l Limit, owner, resource string) (bool, error) {
-		now := time.Now()
-		windowStartedAt := now.Add(-l.Unit.Duration())
+// ParseLimits parses Limits from a string representation of limits separated by ";".
+// Example: "10/s;1000/h;10000/d"
+func ParseLimits(s string) (Limits, error) {
+	var limits Limits
+	for _, part := range strings.Split(s, ";") {
+		l, err := ParseLimit(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
 
-		key := fmt.Sprintf("%s-%s", owner, resource) // TODO COMPRESS?
+		limits = append(limits, l)
+	}
+
+	return limits, nil
+}
 
-		_, err := s.Drop(key, windowStartedAt)
-		if err != nil && err != redis.Nil {
-			log.Printf("error dropping out of window hits: %s\n", err.Error())
+// window returns the largest window of the limits.
+func (ls Limits) window() time.Duration {
+	var w time.Duration
+	for _, l := range ls {
+		if d := l.Unit.Duration(); d > w {
+			w = d
+		}
+	}
+
+	return w
+}
+
+// Decision is the result of rate limiting a hit.
+type Decision struct {
+	// Allowed tells whether the hit is allowed, this is, no limit is exceeded.
+	Allowed bool
+	// Limit is the limit reported: the first one exceeded or, if the hit is allowed, the one with less remaining hits.
+	Limit Limit
+	// Hits is the number of hits found in the window of Limit, the current one excluded.
+	Hits int
+	// Level is the level of the hierarchy Limit belongs to. See HierarchicalRateLimiter.
+	Level Level
+	// Period is the usage of the calendar period limits, if any. See PeriodRateLimiter.
+	Period *PeriodUsage
+	// BannedUntil is when the ban of the key ends, if it is banned. See PenaltyRateLimiter.
+	BannedUntil time.Time
+}
+
+// Remaining returns the number of hits still allowed in the window, after the current one.
+func (d Decision) Remaining() int {
+	if remaining := d.Limit.Quantity - d.Hits - 1; remaining > 0 {
+		return remaining
+	}
+
+	return 0
+}
+
+// Limiter rate limits a resource for a given owner, and the given descriptors, based on a set of Limits.
+type Limiter func(l Limits, owner, resource string, descriptors ...Descriptor) (Decision, error)
+
+// SlideWindowRateLimiter limits based on time windows that are always in movement (sliding). All the windows share the
+// same hits, which are kept for the largest window.
+// Wrap the storage with NewAsyncSlideWindowStorage in case the caller should not wait for the hit to be added.
+func SlideWindowRateLimiter(s SlideWindowStorage) Limiter {
+	return func(ls Limits, owner, resource string, descriptors ...Descriptor) (Decision, error) {
+		if len(ls) == 0 {
+			return Decision{}, fmt.Errorf("no limits for %s -> %s", owner, resource)
+		}
+
+		now := time.Now()
+		key := Key(owner, resource, descriptors...)
+
+		d, err := slideWindowDecision(s, ls, key, now)
+		if err != nil {
+			return d, err
 		}
 
-		hits, err := s.Count(key, now)
-		if err != nil && err != redis.Nil {
-			return false, fmt.Errorf("getting hits count: %s", err.Error())
+		err = s.Add(key, now, ls.window())
+		if err != nil {
+			log.Printf("error adding hit: %s\n", err.Error())
 		}
 
-		if async {
-			// Asynchronously, we do not want the caller to wait as ratio is eventually consistent.
-			go func() {
-				_ = s.Add(key, now, l.Unit.Duration())
-			}()
-		} else {
-			err = s.Add(key, now, l.Unit.Duration())
-			if err != nil {
-				log.Printf("error adding hit: %s\n", err.Error())
+		return d, nil
+	}
+}
+
+// slideWindowDecision drops the hits out of the largest window and decides on a new hit at now, without adding it.
+func slideWindowDecision(s SlideWindowStorage, ls Limits, key string, now time.Time) (Decision, error) {
+	window := ls.window()
+
+	_, err := s.Drop(key, now.Add(-window))
+	if err != nil && err != redis.Nil {
+		log.Printf("error dropping out of window hits: %s\n", err.Error())
+	}
+
+	total, err := s.Count(key, now)
+	if err != nil && err != redis.Nil {
+		return Decision{Limit: ls[0]}, fmt.Errorf("getting hits count: %s", err.Error())
+	}
+
+	var d Decision
+	for i, l := range ls {
+		hits := total
+		if l.Unit.Duration() < window {
+			// Hits in the window are the ones not counted before it started.
+			before, err := s.Count(key, now.Add(-l.Unit.Duration()).Add(-time.Nanosecond))
+			if err != nil && err != redis.Nil {
+				return Decision{Limit: l}, fmt.Errorf("getting hits count: %s", err.Error())
 			}
+			hits -= before
 		}
 
-		return int(hits) < l.Quantity, nil
+		current := Decision{Allowed: hits < l.Quantity, Limit: l, Hits: hits}
+		switch {
+		case i == 0:
+			d = current
+		case !d.Allowed:
+		case !current.Allowed || current.Limit.Quantity-current.Hits < d.Limit.Quantity-d.Hits:
+			d = current
+		}
 	}
+
+	return d, nil
 }
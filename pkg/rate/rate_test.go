@@ -22,9 +22,22 @@ func TestParseFrequency(t *testing.T) {
 		{string: "hour", frequency: PerHour},
 		{string: "h", frequency: PerHour},
 		{string: "HOUR", frequency: PerHour},
+		{string: "second", frequency: PerSecond},
+		{string: "s", frequency: PerSecond},
+		{string: "week", frequency: PerWeek},
+		{string: "w", frequency: PerWeek},
+		{string: "WEEK", frequency: PerWeek},
+		{string: "10s", frequency: Frequency(10 * time.Second)},
+		{string: "15m", frequency: Frequency(15 * time.Minute)},
+		{string: "1h30m", frequency: Frequency(90 * time.Minute)},
+		{string: "2d12h", frequency: Frequency(60 * time.Hour)},
+		{string: "500ms", frequency: Frequency(500 * time.Millisecond)},
 
-		{string: "WEEK", shouldError: true},
 		{string: "YEAR", shouldError: true},
+		{string: "10", shouldError: true},
+		{string: "10x", shouldError: true},
+		{string: "0s", shouldError: true},
+		{string: "1.5h", shouldError: true},
 		{string: "", shouldError: true},
 	}
 
@@ -49,7 +62,14 @@ func TestParseLimit(t *testing.T) {
 		{string: "100/day", limit: NewLimit(PerDay, 100)},
 		{string: "50/h", limit: NewLimit(PerHour, 50)},
 		{string: "2/MINUTE", limit: NewLimit(PerMinute, 2)},
-		{string: "1/WEEK", shouldError: true},
+		{string: "1/WEEK", limit: NewLimit(PerWeek, 1)},
+		{string: "10/s", limit: NewLimit(PerSecond, 10)},
+		{string: "500/15m", limit: NewLimit(Frequency(15*time.Minute), 500)},
+		{string: "1000/1h30m", limit: NewLimit(Frequency(90*time.Minute), 1000)},
+		{string: "1/YEAR", shouldError: true},
+		{string: "-1/m", shouldError: true},
+		{string: "ten/m", shouldError: true},
+		{string: "10/m/s", shouldError: true},
 	}
 
 	for _, c := range cases {
@@ -64,6 +84,34 @@ func TestParseLimit(t *testing.T) {
 	}
 }
 
+func TestParseLimit_ErrorPointsAtBadToken(t *testing.T) {
+	_, err := ParseLimit("100/1h30x")
+	assert.EqualError(t, err, "100/1h30x is not a valid limit: 1h30x is not a valid frequency for a rate: unknown unit x")
+
+	_, err = ParseLimit("1o0/m")
+	assert.EqualError(t, err, "1o0/m is not a valid limit: 1o0 is not a valid quantity")
+}
+
+func TestParseLimits(t *testing.T) {
+	l, err := ParseLimits("10/s; 1000/h;10000/d")
+	assert.NoError(t, err)
+	assert.Equal(t, Limits{NewLimit(PerSecond, 10), NewLimit(PerHour, 1000), NewLimit(PerDay, 10000)}, l)
+
+	l, err = ParseLimits("100/m")
+	assert.NoError(t, err)
+	assert.Equal(t, Limits{NewLimit(PerMinute, 100)}, l)
+
+	_, err = ParseLimits("10/s;1000/x")
+	assert.EqualError(t, err, "1000/x is not a valid limit: x is not a valid frequency for a rate: unknown unit x")
+
+	_, err = ParseLimits("10/s;")
+	assert.Error(t, err)
+}
+
+func TestPerDay(t *testing.T) {
+	assert.Equal(t, 24*time.Hour, PerDay.Duration())
+}
+
 func TestInMemorySlideWindowStorage_Add(t *testing.T) {
 	s := make(map[string][]time.Time)
 	store := NewInMemorySlideWindowStorage(s)
@@ -112,7 +160,7 @@ func TestInMemorySlideWindowStorage_Flush(t *testing.T) {
 
 func TestSlideWindowLimiter_InMemoryStorage(t *testing.T) {
 	store := NewInMemorySlideWindowStorage(inMemoryStore())
-	limiter := SlideWindowRateLimiter(store, false)
+	limiter := SlideWindowRateLimiter(store)
 
 	cases := []struct {
 		desc     string
@@ -153,18 +201,74 @@ func TestSlideWindowLimiter_InMemoryStorage(t *testing.T) {
 
 	for _, c := range cases {
 		t.Run(c.desc, func(t *testing.T) {
-			ok, err := limiter(c.limit, c.owner, c.resource)
+			d, err := limiter(Limits{c.limit}, c.owner, c.resource)
 			assert.NoError(t, err)
-			assert.Equal(t, c.ok, ok)
+			assert.Equal(t, c.ok, d.Allowed)
 			assert.NoError(t, store.Flush())
 			store.(*inMemorySlideWindowStorage).store = inMemoryStore()
 		})
 	}
 }
 
+func TestSlideWindowLimiter_MultipleWindows(t *testing.T) {
+	cases := []struct {
+		desc   string
+		limits Limits
+		ok     bool
+		limit  Limit
+		hits   int
+	}{
+		{
+			desc:   "Limits 4/m and 5/h. 1 and 3 hits found. The hour window has less remaining hits.",
+			limits: Limits{NewLimit(PerMinute, 4), NewLimit(PerHour, 5)},
+			ok:     true,
+			limit:  NewLimit(PerHour, 5),
+			hits:   3,
+		},
+		{
+			desc:   "Limits 1/m and 5/h. 1 and 3 hits found. The minute window trips.",
+			limits: Limits{NewLimit(PerMinute, 1), NewLimit(PerHour, 5)},
+			ok:     false,
+			limit:  NewLimit(PerMinute, 1),
+			hits:   1,
+		},
+		{
+			desc:   "Limits 10/m and 3/h. 1 and 3 hits found. The hour window trips.",
+			limits: Limits{NewLimit(PerMinute, 10), NewLimit(PerHour, 3)},
+			ok:     false,
+			limit:  NewLimit(PerHour, 3),
+			hits:   3,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			now := time.Now()
+			limiter := SlideWindowRateLimiter(NewInMemorySlideWindowStorage(map[string][]time.Time{
+				Key("myservice", "resource1"): {
+					now.Add(-time.Hour * 2),
+					now.Add(-time.Minute * 30),
+					now.Add(-time.Minute * 20),
+					now.Add(-time.Second * 30),
+				},
+			}))
+
+			d, err := limiter(c.limits, "myservice", "resource1")
+			assert.NoError(t, err)
+			assert.Equal(t, c.ok, d.Allowed)
+			assert.Equal(t, c.limit, d.Limit)
+			assert.Equal(t, c.hits, d.Hits)
+		})
+	}
+
+	limiter := SlideWindowRateLimiter(NewInMemorySlideWindowStorage(inMemoryStore()))
+	_, err := limiter(Limits{}, "myservice", "resource1")
+	assert.Error(t, err)
+}
+
 func inMemoryStore() map[string][]time.Time {
 	return map[string][]time.Time{
-		"myservice-resource1": {
+		Key("myservice", "resource1"): {
 			time.Now().Add(-time.Hour * 2),
 			time.Now().Add(-time.Minute * 45),
 			time.Now().Add(-time.Minute * 30),
@@ -172,3 +276,11 @@ func inMemoryStore() map[string][]time.Time {
 		},
 	}
 }
+
+func TestDecision_Remaining(t *testing.T) {
+	l := NewLimit(PerMinute, 3)
+
+	assert.Equal(t, 2, Decision{Allowed: true, Limit: l, Hits: 0}.Remaining())
+	assert.Equal(t, 0, Decision{Allowed: true, Limit: l, Hits: 2}.Remaining())
+	assert.Equal(t, 0, Decision{Allowed: false, Limit: l, Hits: 5}.Remaining())
+}
@@ -1,9 +1,14 @@
 package rate
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"net/url"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-redis/redis"
@@ -16,21 +21,67 @@ type Rediser interface {
 	ZCount(key, min, max string) *redis.IntCmd
 	ZAdd(key string, members ...redis.Z) *redis.IntCmd
 	Expire(key string, expiration time.Duration) *redis.BoolCmd
-	FlushAll() *redis.StatusCmd
+	Scan(cursor uint64, match string, count int64) *redis.ScanCmd
+	Del(keys ...string) *redis.IntCmd
+	ZRem(key string, members ...interface{}) *redis.IntCmd
+	Eval(script string, keys []string, args ...interface{}) *redis.Cmd
+	Get(key string) *redis.StringCmd
+	HGetAll(key string) *redis.StringStringMapCmd
+	Pipeline() redis.Pipeliner
+	Ping() *redis.StatusCmd
+}
+
+func init() {
+	RegisterStorage("redis", newRedisSlideWindowStorageFromDSN)
+}
+
+// DefaultRedisNamespace prefixes the keys stored in Redis when no namespace is given.
+const DefaultRedisNamespace = "ratio"
+
+// newRedisSlideWindowStorageFromDSN creates a Redis SlideWindowStorage from a DSN like
+// redis://localhost:6379/0?namespace=ratio.
+func newRedisSlideWindowStorageFromDSN(dsn *url.URL) (SlideWindowStorage, error) {
+	ops := &redis.Options{
+		Addr: dsn.Host,
+		DB:   0,
+	}
+
+	db, err := strconv.Atoi(strings.TrimPrefix(dsn.Path, "/"))
+	if err == nil {
+		ops.DB = db
+	}
+
+	namespace := DefaultRedisNamespace
+	if ns, ok := dsn.Query()["namespace"]; ok {
+		namespace = ns[0]
+	}
+
+	return NewRedisSlideWindowStorage(redis.NewClient(ops), namespace), nil
 }
 
 type redisSlideWindowStorage struct {
-	r Rediser
+	r         Rediser
+	namespace string
 }
 
-// NewRedisSlideWindowStorage creates a new Redis SlideWindowStorage.
-func NewRedisSlideWindowStorage(r Rediser) SlideWindowStorage {
-	return &redisSlideWindowStorage{r: r}
+// NewRedisSlideWindowStorage creates a new Redis SlideWindowStorage. Keys are prefixed by the namespace, so the Redis
+// can be shared with others. An empty namespace means no prefix.
+func NewRedisSlideWindowStorage(r Rediser, namespace string) SlideWindowStorage {
+	return &redisSlideWindowStorage{r: r, namespace: namespace}
+}
+
+func (s redisSlideWindowStorage) key(key string) string {
+	if s.namespace == "" {
+		return key
+	}
+
+	return s.namespace + ":" + key
 }
 
 func (s redisSlideWindowStorage) Add(key string, now time.Time, expireIn time.Duration) error {
+	key = s.key(key)
 	nowMs := s.toMilliseconds(now)
-	err := s.r.ZAdd(key, redis.Z{Score: float64(nowMs), Member: nowMs}).Err()
+	err := s.r.ZAdd(key, redis.Z{Score: float64(nowMs), Member: hitMember(nowMs)}).Err()
 	if err != nil {
 		return err
 	}
@@ -45,26 +96,362 @@ func (s redisSlideWindowStorage) Add(key string, now time.Time, expireIn time.Du
 	return nil
 }
 
+// AddBatch adds several hits in a single round trip by using a pipeline.
+func (s redisSlideWindowStorage) AddBatch(hits []Hit) error {
+	pipe := s.r.Pipeline()
+	defer pipe.Close()
+
+	for _, hit := range hits {
+		key := s.key(hit.Key)
+		nowMs := s.toMilliseconds(hit.Time)
+		pipe.ZAdd(key, redis.Z{Score: float64(nowMs), Member: hitMember(nowMs)})
+
+		if hit.ExpireIn > 0 {
+			pipe.Expire(key, hit.ExpireIn)
+		}
+	}
+
+	_, err := pipe.Exec()
+	return err
+}
+
+// hitSeq and hitPrefix make the members of the hits unique, as the hits of the same millisecond would be a single
+// member of the Sorted Set, counted once, otherwise. hitPrefix tells apart the instances sharing the Redis.
+var (
+	hitSeq    uint64
+	hitPrefix = func() string {
+		b := make([]byte, 4)
+		_, _ = rand.Read(b)
+		return hex.EncodeToString(b)
+	}()
+)
+
+// hitMember is a unique member of a Sorted Set for a hit at nowMs, like "1571234567890:9f86d081:42".
+func hitMember(nowMs int) string {
+	return fmt.Sprintf("%d:%s:%d", nowMs, hitPrefix, atomic.AddUint64(&hitSeq, 1))
+}
+
 func (s redisSlideWindowStorage) Drop(key string, until time.Time) (int, error) {
-	hits, err := s.r.ZRemRangeByScore(key, "-inf", fmt.Sprintf("(%s", strconv.Itoa(s.toMilliseconds(until)))).Result()
-	if err != nil && err != redis.Nil {
+	max := fmt.Sprintf("(%s", strconv.Itoa(s.toMilliseconds(until)))
+
+	pipe := s.r.Pipeline()
+	defer pipe.Close()
+
+	hits := pipe.ZRemRangeByScore(s.key(key), "-inf", max)
+	weighted := pipe.ZRemRangeByScore(s.weightedKey(key), "-inf", max)
+	if _, err := pipe.Exec(); err != nil && err != redis.Nil {
 		return 0, err
 	}
 
-	return int(hits), nil
+	return int(hits.Val() + weighted.Val()), nil
 }
 
+// Count counts the hits and the weight of the weighted entries in a single round trip.
 func (s redisSlideWindowStorage) Count(key string, until time.Time) (int, error) {
-	hits, err := s.r.ZCount(key, "-inf", fmt.Sprintf("%d", s.toMilliseconds(until))).Result()
-	if err != nil && err != redis.Nil {
+	max := fmt.Sprintf("%d", s.toMilliseconds(until))
+
+	pipe := s.r.Pipeline()
+	defer pipe.Close()
+
+	hits := pipe.ZCount(s.key(key), "-inf", max)
+	weighted := pipe.ZRangeByScore(s.weightedKey(key), redis.ZRangeBy{Min: "-inf", Max: max})
+	if _, err := pipe.Exec(); err != nil && err != redis.Nil {
 		return 0, err
 	}
 
-	return int(hits), nil
+	count := int(hits.Val())
+	for _, member := range weighted.Val() {
+		count += weight(member)
+	}
+
+	return count, nil
+}
+
+// weightedKey is the sorted set of the weighted entries of a key, scored by time. Members are "id:weight".
+// Keys built by Key never end like this, so they never collide.
+func (s redisSlideWindowStorage) weightedKey(key string) string {
+	return s.key(key) + ":w"
 }
 
+func weight(member string) int {
+	i := strings.LastIndex(member, ":")
+	if i == -1 {
+		return 0
+	}
+
+	n, _ := strconv.Atoi(member[i+1:])
+	return n
+}
+
+func (s redisSlideWindowStorage) AddWeighted(key, id string, n int, now time.Time, expireIn time.Duration) error {
+	pipe := s.r.Pipeline()
+	defer pipe.Close()
+
+	k := s.weightedKey(key)
+	pipe.ZAdd(k, redis.Z{Score: float64(s.toMilliseconds(now)), Member: fmt.Sprintf("%s:%d", id, n)})
+	if expireIn > 0 {
+		pipe.Expire(k, expireIn)
+	}
+
+	_, err := pipe.Exec()
+	return err
+}
+
+// unweightScript subtracts up to ARGV[2] from the weight of the entry ARGV[1] of KEYS[1], atomically. Returns the
+// weight subtracted and the score of the entry.
+const unweightScript = `
+local prefix = ARGV[1] .. ":"
+for _, member in ipairs(redis.call("ZRANGE", KEYS[1], 0, -1)) do
+	if string.sub(member, 1, string.len(prefix)) == prefix then
+		local n = tonumber(string.sub(member, string.len(prefix) + 1))
+		local subtracted = math.min(n, tonumber(ARGV[2]))
+		local score = redis.call("ZSCORE", KEYS[1], member)
+		redis.call("ZREM", KEYS[1], member)
+		if n > subtracted then
+			redis.call("ZADD", KEYS[1], score, prefix .. (n - subtracted))
+		end
+		return {subtracted, score}
+	end
+end
+return {0, "0"}
+`
+
+func (s redisSlideWindowStorage) Unweight(key, id string, n int) (int, time.Time, error) {
+	res, err := s.r.Eval(unweightScript, []string{s.weightedKey(key)}, id, n).Result()
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	values, ok := res.([]interface{})
+	if !ok || len(values) != 2 {
+		return 0, time.Time{}, fmt.Errorf("unexpected unweight result %v", res)
+	}
+
+	subtracted, _ := values[0].(int64)
+	if subtracted == 0 {
+		return 0, time.Time{}, nil
+	}
+
+	score, _ := values[1].(string)
+	ms, err := strconv.ParseFloat(score, 64)
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("invalid weighted entry score %s: %s", score, err.Error())
+	}
+
+	return int(subtracted), fromMilliseconds(int64(ms)), nil
+}
+
+// Flush deletes the keys of the namespace, or all the keys of the database if there is no namespace.
 func (s redisSlideWindowStorage) Flush() error {
-	return s.r.FlushAll().Err()
+	var cursor uint64
+	for {
+		keys, next, err := s.r.Scan(cursor, s.key("*"), 1000).Result()
+		if err != nil {
+			return err
+		}
+
+		if len(keys) > 0 {
+			if err := s.r.Del(keys...).Err(); err != nil {
+				return err
+			}
+		}
+
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// acquireScript drops the expired leases and adds the new one if there are less than max, atomically.
+// KEYS[1]: key. ARGV: now, expire at (ms), max, lease.
+const acquireScript = `
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", ARGV[1])
+local held = redis.call("ZCARD", KEYS[1])
+if held >= tonumber(ARGV[3]) then
+	return {0, held}
+end
+redis.call("ZADD", KEYS[1], ARGV[2], ARGV[4])
+redis.call("PEXPIREAT", KEYS[1], redis.call("ZRANGE", KEYS[1], -1, -1, "WITHSCORES")[2])
+return {1, held}
+`
+
+// Acquire stores the leases of a key as a sorted set of lease IDs scored by their expiration.
+func (s redisSlideWindowStorage) Acquire(key, lease string, max int, now, expireAt time.Time) (bool, int, error) {
+	res, err := s.r.Eval(acquireScript, []string{s.key(key)}, s.toMilliseconds(now), s.toMilliseconds(expireAt), max, lease).Result()
+	if err != nil {
+		return false, 0, err
+	}
+
+	values, ok := res.([]interface{})
+	if !ok || len(values) != 2 {
+		return false, 0, fmt.Errorf("unexpected acquire result: %v", res)
+	}
+
+	acquired, _ := values[0].(int64)
+	held, _ := values[1].(int64)
+
+	return acquired == 1, int(held), nil
+}
+
+func (s redisSlideWindowStorage) Release(key, lease string) (bool, error) {
+	removed, err := s.r.ZRem(s.key(key), lease).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return removed > 0, nil
+}
+
+// Incr stores the counter as a plain Redis integer.
+func (s redisSlideWindowStorage) Incr(key string, n int, expireAt time.Time) (int, error) {
+	pipe := s.r.Pipeline()
+	defer pipe.Close()
+
+	k := s.key(key)
+	incr := pipe.IncrBy(k, int64(n))
+	pipe.PExpireAt(k, expireAt)
+
+	if _, err := pipe.Exec(); err != nil {
+		return 0, err
+	}
+
+	return int(incr.Val()), nil
+}
+
+func (s redisSlideWindowStorage) Get(key string) (int, error) {
+	n, err := s.r.Get(s.key(key)).Int()
+	if err == redis.Nil {
+		return 0, nil
+	}
+
+	return n, err
+}
+
+// Ban stores the end of the ban, in unix milliseconds, as a plain Redis value expiring at the same time.
+func (s redisSlideWindowStorage) Ban(key string, until time.Time) error {
+	pipe := s.r.Pipeline()
+	defer pipe.Close()
+
+	k := s.key(key)
+	pipe.Set(k, s.toMilliseconds(until), 0)
+	pipe.PExpireAt(k, until)
+
+	_, err := pipe.Exec()
+	return err
+}
+
+func (s redisSlideWindowStorage) BannedUntil(key string) (time.Time, error) {
+	ms, err := s.r.Get(s.key(key)).Int64()
+	if err == redis.Nil {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(0, ms*int64(time.Millisecond)), nil
+}
+
+func (s redisSlideWindowStorage) Unban(key string) (bool, error) {
+	removed, err := s.r.Del(s.key(key)).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return removed > 0, nil
+}
+
+// usageKey is the hash of the usage of a bucket. Keys built by Key never start like this, so they never collide.
+func (s redisSlideWindowStorage) usageKey(bucket time.Time) string {
+	return s.key(fmt.Sprintf("usage:%d", s.toMilliseconds(bucket)))
+}
+
+// usageField is the field of the hash of a bucket holding the allowed (a) or rejected (r) hits of an owner and
+// resource. The owner is prefixed by its length, so the field can be split back.
+func usageField(owner, resource, kind string) string {
+	return fmt.Sprintf("%d:%s%s:%s", len(owner), owner, resource, kind)
+}
+
+func parseUsageField(field string) (owner, resource, kind string, ok bool) {
+	i := strings.Index(field, ":")
+	if i == -1 {
+		return "", "", "", false
+	}
+
+	n, err := strconv.Atoi(field[:i])
+	rest := field[i+1:]
+	if err != nil || n < 0 || len(rest) < n+2 || rest[len(rest)-2] != ':' {
+		return "", "", "", false
+	}
+
+	return rest[:n], rest[n : len(rest)-2], rest[len(rest)-1:], true
+}
+
+// AddUsage stores the usage of each bucket as a hash of counters, in a single round trip.
+func (s redisSlideWindowStorage) AddUsage(usages []Usage, expireIn time.Duration) error {
+	pipe := s.r.Pipeline()
+	defer pipe.Close()
+
+	buckets := make(map[string]struct{})
+	for _, u := range usages {
+		k := s.usageKey(u.Bucket)
+		if u.Allowed > 0 {
+			pipe.HIncrBy(k, usageField(u.Owner, u.Resource, "a"), int64(u.Allowed))
+		}
+		if u.Rejected > 0 {
+			pipe.HIncrBy(k, usageField(u.Owner, u.Resource, "r"), int64(u.Rejected))
+		}
+		buckets[k] = struct{}{}
+	}
+
+	for k := range buckets {
+		pipe.Expire(k, expireIn)
+	}
+
+	_, err := pipe.Exec()
+	return err
+}
+
+func (s redisSlideWindowStorage) Usage(bucket time.Time) ([]Usage, error) {
+	fields, err := s.r.HGetAll(s.usageKey(bucket)).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	byKey := make(map[[2]string]*Usage)
+	var usages []*Usage
+	for field, value := range fields {
+		owner, resource, kind, ok := parseUsageField(field)
+		if !ok {
+			continue
+		}
+
+		n, _ := strconv.Atoi(value)
+		u, ok := byKey[[2]string{owner, resource}]
+		if !ok {
+			u = &Usage{Owner: owner, Resource: resource, Bucket: bucket}
+			byKey[[2]string{owner, resource}] = u
+			usages = append(usages, u)
+		}
+
+		if kind == "a" {
+			u.Allowed += n
+		} else {
+			u.Rejected += n
+		}
+	}
+
+	result := make([]Usage, 0, len(usages))
+	for _, u := range usages {
+		result = append(result, *u)
+	}
+
+	return result, nil
+}
+
+func (s redisSlideWindowStorage) Ping() error {
+	return s.r.Ping().Err()
 }
 
 func (s redisSlideWindowStorage) Close() error {
@@ -2,7 +2,6 @@ package rate
 
 import (
 	"fmt"
-	"strconv"
 	"testing"
 	"time"
 
@@ -16,15 +15,19 @@ func TestRedisSlideWindowStorage_Add(t *testing.T) {
 	defer m.Close()
 	defer m.FlushAll()
 
-	store := NewRedisSlideWindowStorage(r)
+	store := NewRedisSlideWindowStorage(r, "")
 	now := time.Now()
 
 	assert.NoError(t, store.Add("key1", now, 0))
-	hits, err := r.ZRange("key1", 0, -1).Result()
+	assert.NoError(t, store.Add("key1", now, 0))
+	hits, err := r.ZRangeWithScores("key1", 0, -1).Result()
 
 	assert.NoError(t, err)
-	assert.Len(t, hits, 1)
-	assert.Equal(t, fmt.Sprintf("%d", now.UnixNano()/1000000), hits[0])
+	assert.Len(t, hits, 2, "hits of the same millisecond are stored apart")
+	for _, hit := range hits {
+		assert.Equal(t, float64(now.UnixNano()/1000000), hit.Score)
+		assert.Contains(t, hit.Member, fmt.Sprintf("%d:", now.UnixNano()/1000000))
+	}
 }
 
 func TestRedisSlideWindowStorage_Count(t *testing.T) {
@@ -32,7 +35,7 @@ func TestRedisSlideWindowStorage_Count(t *testing.T) {
 	defer m.Close()
 	defer m.FlushAll()
 
-	store := NewRedisSlideWindowStorage(r)
+	store := NewRedisSlideWindowStorage(r, "")
 	now := time.Now()
 
 	assert.NoError(t, store.Add("key1", now, 0))
@@ -49,7 +52,7 @@ func TestRedisSlideWindowStorage_Drop(t *testing.T) {
 	defer m.Close()
 	defer m.FlushAll()
 
-	store := NewRedisSlideWindowStorage(r)
+	store := NewRedisSlideWindowStorage(r, "")
 	now := time.Now()
 
 	assert.NoError(t, store.Add("key1", now, 0))
@@ -60,13 +63,12 @@ func TestRedisSlideWindowStorage_Drop(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, 1, c)
 
-	hits, err := r.ZRevRange("key1", 0, -1).Result()
+	hits, err := r.ZRevRangeWithScores("key1", 0, -1).Result()
 	assert.NoError(t, err)
 
-	assert.Equal(t, []string{
-		strconv.Itoa(int(now.UnixNano() / 1000000)),
-		strconv.Itoa(int(now.Add(-time.Minute).UnixNano() / 1000000)),
-	}, hits)
+	assert.Len(t, hits, 2)
+	assert.Equal(t, float64(now.UnixNano()/1000000), hits[0].Score)
+	assert.Equal(t, float64(now.Add(-time.Minute).UnixNano()/1000000), hits[1].Score)
 }
 
 func TestRedisSlideWindowStorage_Flush(t *testing.T) {
@@ -74,7 +76,7 @@ func TestRedisSlideWindowStorage_Flush(t *testing.T) {
 	defer m.Close()
 	defer m.FlushAll()
 
-	store := NewRedisSlideWindowStorage(r)
+	store := NewRedisSlideWindowStorage(r, "")
 	now := time.Now()
 
 	assert.NoError(t, store.Add("key1", now, 0))
@@ -85,12 +87,39 @@ func TestRedisSlideWindowStorage_Flush(t *testing.T) {
 	assert.Equal(t, int64(0), hits)
 }
 
+func TestRedisSlideWindowStorage_Namespace(t *testing.T) {
+	r, m := createRedis()
+	defer m.Close()
+	defer m.FlushAll()
+
+	store := NewRedisSlideWindowStorage(r, "ratio")
+	now := time.Now()
+
+	assert.NoError(t, store.Add("key1", now, 0))
+	assert.NoError(t, store.(BatchAdder).AddBatch([]Hit{{Key: "key2", Time: now}}))
+	assert.NoError(t, r.Set("other", "value", 0).Err())
+
+	assert.True(t, m.Exists("ratio:key1"))
+	assert.True(t, m.Exists("ratio:key2"))
+
+	hits, err := store.Count("key1", now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, hits)
+
+	// Only the keys of the namespace are flushed.
+	for i := 0; i < 2000; i++ {
+		assert.NoError(t, r.ZAdd(fmt.Sprintf("ratio:many%d", i), redis.Z{Score: 1, Member: 1}).Err())
+	}
+	assert.NoError(t, store.Flush())
+	assert.Equal(t, []string{"other"}, m.Keys())
+}
+
 func TestSlideWindowLimiter_RedisStorage(t *testing.T) {
 	r, m := createRedis()
 	defer m.Close()
 
-	s := redisSlideWindowStorage{r}
-	limiter := SlideWindowRateLimiter(s, false)
+	s := redisSlideWindowStorage{r: r}
+	limiter := SlideWindowRateLimiter(s)
 
 	cases := []struct {
 		desc         string
@@ -147,8 +176,8 @@ func TestSlideWindowLimiter_RedisStorage(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.desc, func(t *testing.T) {
 			for i := 0; i < c.previousHits; i++ {
-				ok, err := limiter(c.limit, c.owner, c.resource)
-				assert.True(t, ok, "error populating previous hits")
+				d, err := limiter(Limits{c.limit}, c.owner, c.resource)
+				assert.True(t, d.Allowed, "error populating previous hits")
 				assert.NoError(t, err, "error populating previous hits")
 
 				// Needed because races can happen in miniredis.
@@ -159,15 +188,39 @@ func TestSlideWindowLimiter_RedisStorage(t *testing.T) {
 				m.FastForward(c.fastForward)
 			}
 
-			ok, err := limiter(c.limit, c.owner, c.resource)
+			d, err := limiter(Limits{c.limit}, c.owner, c.resource)
 			assert.NoError(t, err)
-			assert.Equal(t, c.ok, ok)
+			assert.Equal(t, c.ok, d.Allowed)
 
 			r.FlushAll()
 		})
 	}
 }
 
+func TestSlideWindowLimiter_RedisStorage_MultipleWindows(t *testing.T) {
+	r, m := createRedis()
+	defer m.Close()
+
+	s := redisSlideWindowStorage{r: r}
+	now := time.Now()
+	assert.NoError(t, s.Add(Key("myservice", "resource1"), now.Add(-time.Minute*30), 0))
+	assert.NoError(t, s.Add(Key("myservice", "resource1"), now.Add(-time.Second*30), 0))
+
+	limiter := SlideWindowRateLimiter(s)
+	limits := Limits{NewLimit(PerMinute, 2), NewLimit(PerHour, 3)}
+
+	d, err := limiter(limits, "myservice", "resource1")
+	assert.NoError(t, err)
+	assert.True(t, d.Allowed)
+	assert.Equal(t, 0, d.Remaining())
+
+	d, err = limiter(limits, "myservice", "resource1")
+	assert.NoError(t, err)
+	assert.False(t, d.Allowed)
+	assert.Equal(t, NewLimit(PerMinute, 2), d.Limit)
+	assert.Equal(t, 2, d.Hits)
+}
+
 func createRedis() (*redis.Client, *miniredis.Miniredis) {
 	mini, err := miniredis.Run()
 	if err != nil {
@@ -179,3 +232,94 @@ func createRedis() (*redis.Client, *miniredis.Miniredis) {
 
 	return r, mini
 }
+
+func TestRedisSlideWindowStorage_AddBatch(t *testing.T) {
+	r, m := createRedis()
+	defer m.Close()
+	defer m.FlushAll()
+
+	store := NewRedisSlideWindowStorage(r, "").(BatchAdder)
+	now := time.Now()
+
+	assert.NoError(t, store.AddBatch([]Hit{
+		{Key: "key1", Time: now, ExpireIn: time.Minute},
+		{Key: "key1", Time: now, ExpireIn: time.Minute},
+		{Key: "key1", Time: now.Add(-time.Second), ExpireIn: time.Minute},
+		{Key: "key2", Time: now},
+	}))
+
+	hits, err := r.ZCount("key1", "-inf", "inf").Result()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), hits)
+	assert.Equal(t, time.Minute, m.TTL("key1"))
+
+	hits, err = r.ZCount("key2", "-inf", "inf").Result()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), hits)
+}
+
+func TestRedisSlideWindowStorage_Ping(t *testing.T) {
+	r, m := createRedis()
+	store := NewRedisSlideWindowStorage(r, "")
+
+	assert.NoError(t, store.Ping())
+
+	m.Close()
+	assert.Error(t, store.Ping())
+}
+
+func TestRedisSlideWindowStorage_Counter(t *testing.T) {
+	r, m := createRedis()
+	defer m.Close()
+	defer m.FlushAll()
+
+	store := NewRedisSlideWindowStorage(r, "ratio").(CounterStorage)
+	expireAt := time.Now().Add(time.Hour)
+
+	n, err := store.Get("key1")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	n, err = store.Incr("key1", 3, expireAt)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	n, err = store.Incr("key1", -1, expireAt)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	n, err = store.Get("key1")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.True(t, m.Exists("ratio:key1"))
+	assert.True(t, m.TTL("ratio:key1") > 0)
+}
+
+func TestRedisSlideWindowStorage_Ban(t *testing.T) {
+	r, m := createRedis()
+	defer m.Close()
+	defer m.FlushAll()
+
+	store := NewRedisSlideWindowStorage(r, "ratio").(PenaltyStorage)
+	until := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+
+	banned, err := store.BannedUntil("key1")
+	assert.NoError(t, err)
+	assert.True(t, banned.IsZero())
+
+	assert.NoError(t, store.Ban("key1", until))
+	assert.True(t, m.TTL("ratio:key1") > 0)
+
+	banned, err = store.BannedUntil("key1")
+	assert.NoError(t, err)
+	assert.True(t, until.Equal(banned))
+
+	unbanned, err := store.Unban("key1")
+	assert.NoError(t, err)
+	assert.True(t, unbanned)
+	assert.False(t, m.Exists("ratio:key1"))
+
+	unbanned, err = store.Unban("key1")
+	assert.NoError(t, err)
+	assert.False(t, unbanned)
+}
@@ -0,0 +1,244 @@
+package rate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"time"
+)
+
+// Rule assigns limits to the hits matching it. Owner, Resource and the descriptor values are patterns with the
+// syntax of path.Match. Empty ones match anything.
+// Every descriptor of the rule should match a descriptor of the hit with the same key.
+type Rule struct {
+	Owner       string       `json:"owner"`
+	Resource    string       `json:"resource"`
+	Descriptors []Descriptor `json:"descriptors"`
+	// Limit is the string representation of the limits. See ParseLimits.
+	Limit string `json:"limit,omitempty"`
+	// Period is the string representation of the calendar period limits, in Timezone (UTC by default). See
+	// ParsePeriodLimits. A rule needs a Limit, a Period or Alerts.
+	Period   string `json:"period,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+	// Alerts are the thresholds notified for the hits matching the rule.
+	Alerts []AlertThreshold `json:"alerts,omitempty"`
+}
+
+// AlertThreshold is a condition on the hits of a key worth notifying. Set either Percent or ConsecutiveRejections.
+type AlertThreshold struct {
+	// Percent of the limit reached by the hits of the window.
+	Percent int `json:"percent,omitempty"`
+	// ConsecutiveRejections is the number of hits rejected in a row.
+	ConsecutiveRejections int `json:"consecutive_rejections,omitempty"`
+	// Webhook is the URL notified. Empty for the default one.
+	Webhook string `json:"webhook,omitempty"`
+}
+
+// Validate returns an error if the threshold does not set exactly one condition or it is out of range.
+func (a AlertThreshold) Validate() error {
+	switch {
+	case a.Percent != 0 && a.ConsecutiveRejections != 0:
+		return errors.New("an alert should set either percent or consecutive_rejections, not both")
+	case a.Percent < 0 || a.Percent > 100:
+		return fmt.Errorf("invalid alert percent %d", a.Percent)
+	case a.ConsecutiveRejections < 0:
+		return fmt.Errorf("invalid alert consecutive_rejections %d", a.ConsecutiveRejections)
+	case a.Percent == 0 && a.ConsecutiveRejections == 0:
+		return errors.New("an alert should set percent or consecutive_rejections")
+	}
+
+	return nil
+}
+
+// LoadRules loads a list of rules from a JSON file.
+//
+// Example:
+//
+//	[
+//	  {"owner": "checkout", "resource": "/v1/order/*", "descriptors": [{"key": "plan", "value": "free"}], "limit": "10/s"},
+//	  {"descriptors": [{"key": "plan", "value": "enterprise"}], "limit": "1000/s;100000/d"},
+//	  {"owner": "acme", "period": "1000000/month@15", "timezone": "Europe/Madrid", "alerts": [{"percent": 80}]}
+//	]
+func LoadRules(file string) ([]Rule, error) {
+	var rules []Rule
+
+	raw, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(raw, &rules); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+func (r Rule) matches(owner, resource string, descriptors []Descriptor) bool {
+	if !match(r.Owner, owner) || !match(r.Resource, resource) {
+		return false
+	}
+
+	for _, rd := range r.Descriptors {
+		var found bool
+		for _, d := range descriptors {
+			if d.Key == rd.Key && match(rd.Value, d.Value) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+func match(pattern, s string) bool {
+	if pattern == "" {
+		return true
+	}
+
+	ok, _ := path.Match(pattern, s)
+	return ok
+}
+
+type compiledRule struct {
+	Rule
+	limits  Limits
+	periods PeriodLimits
+}
+
+// Rules are rules ready to be matched. See CompileRules.
+type Rules []compiledRule
+
+// CompileRules validates the patterns of the rules and parses their limits.
+func CompileRules(rules []Rule) (Rules, error) {
+	compiled := make(Rules, 0, len(rules))
+	for i, r := range rules {
+		if _, err := path.Match(r.Owner, ""); err != nil {
+			return nil, fmt.Errorf("invalid owner pattern %s on rule %d", r.Owner, i)
+		}
+		if _, err := path.Match(r.Resource, ""); err != nil {
+			return nil, fmt.Errorf("invalid resource pattern %s on rule %d", r.Resource, i)
+		}
+		for _, d := range r.Descriptors {
+			if _, err := path.Match(d.Value, ""); err != nil {
+				return nil, fmt.Errorf("invalid descriptor %s pattern %s on rule %d", d.Key, d.Value, i)
+			}
+		}
+
+		if r.Limit == "" && r.Period == "" && len(r.Alerts) == 0 {
+			return nil, fmt.Errorf("missing limit, period or alerts on rule %d", i)
+		}
+
+		for _, a := range r.Alerts {
+			if err := a.Validate(); err != nil {
+				return nil, fmt.Errorf("invalid alert on rule %d: %s", i, err.Error())
+			}
+		}
+
+		c := compiledRule{Rule: r}
+		if r.Limit != "" {
+			limits, err := ParseLimits(r.Limit)
+			if err != nil {
+				return nil, fmt.Errorf("invalid limit on rule %d: %s", i, err.Error())
+			}
+			c.limits = limits
+		}
+
+		if r.Period != "" {
+			loc, err := time.LoadLocation(r.Timezone)
+			if err != nil {
+				return nil, fmt.Errorf("invalid timezone on rule %d: %s", i, err.Error())
+			}
+
+			periods, err := ParsePeriodLimits(r.Period, loc)
+			if err != nil {
+				return nil, fmt.Errorf("invalid period on rule %d: %s", i, err.Error())
+			}
+			c.periods = periods
+		}
+
+		compiled = append(compiled, c)
+	}
+
+	return compiled, nil
+}
+
+// Limits returns the limits of the first rule with limits matching the hit, or l if none does.
+func (rs Rules) Limits(l Limits, owner, resource string, descriptors ...Descriptor) Limits {
+	for _, r := range rs {
+		if r.limits != nil && r.matches(owner, resource, descriptors) {
+			return r.limits
+		}
+	}
+
+	return l
+}
+
+// PeriodLimits returns the period limits of the first rule with period limits matching the hit, or l if none does.
+func (rs Rules) PeriodLimits(l PeriodLimits, owner, resource string, descriptors ...Descriptor) PeriodLimits {
+	for _, r := range rs {
+		if r.periods != nil && r.matches(owner, resource, descriptors) {
+			return r.periods
+		}
+	}
+
+	return l
+}
+
+// Alerts returns the alert thresholds of the first rule with alerts matching the hit, or a if none does.
+func (rs Rules) Alerts(a []AlertThreshold, owner, resource string, descriptors ...Descriptor) []AlertThreshold {
+	for _, r := range rs {
+		if len(r.Alerts) > 0 && r.matches(owner, resource, descriptors) {
+			return r.Alerts
+		}
+	}
+
+	return a
+}
+
+// AllAlerts returns the alert thresholds of every rule.
+func (rs Rules) AllAlerts() []AlertThreshold {
+	var a []AlertThreshold
+	for _, r := range rs {
+		a = append(a, r.Alerts...)
+	}
+
+	return a
+}
+
+// HasAlerts returns whether any rule has alert thresholds.
+func (rs Rules) HasAlerts() bool {
+	for _, r := range rs {
+		if len(r.Alerts) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasPeriodLimits returns whether any rule has period limits.
+func (rs Rules) HasPeriodLimits() bool {
+	for _, r := range rs {
+		if r.periods != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RulesRateLimiter replaces the limits of every hit by the ones of the first rule matching it. Hits not matching
+// any rule keep their limits.
+func RulesRateLimiter(limiter Limiter, rules Rules) Limiter {
+	return func(l Limits, owner, resource string, descriptors ...Descriptor) (Decision, error) {
+		return limiter(rules.Limits(l, owner, resource, descriptors...), owner, resource, descriptors...)
+	}
+}
@@ -0,0 +1,152 @@
+package rate
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRulesRateLimiter(t *testing.T) {
+	rules := []Rule{
+		{Owner: "checkout", Resource: "/v1/order/*", Descriptors: []Descriptor{{Key: "plan", Value: "free"}}, Limit: "1/m"},
+		{Descriptors: []Descriptor{{Key: "plan", Value: "enterprise"}}, Limit: "3/m"},
+		{Descriptors: []Descriptor{{Key: "customer"}}, Limit: "4/m"},
+	}
+
+	compiled, err := CompileRules(rules)
+	require.NoError(t, err)
+
+	var applied Limits
+	limiter := RulesRateLimiter(func(l Limits, _, _ string, _ ...Descriptor) (Decision, error) {
+		applied = l
+		return Decision{Allowed: true, Limit: l[0]}, nil
+	}, compiled)
+
+	def := Limits{NewLimit(PerMinute, 2)}
+	cases := []struct {
+		desc        string
+		owner       string
+		resource    string
+		descriptors []Descriptor
+		limits      Limits
+	}{
+		{
+			desc:        "First rule",
+			owner:       "checkout",
+			resource:    "/v1/order/pay",
+			descriptors: []Descriptor{{Key: "customer", Value: "c1"}, {Key: "plan", Value: "free"}},
+			limits:      Limits{NewLimit(PerMinute, 1)},
+		},
+		{
+			desc:        "Resource pattern not matching. Third rule",
+			owner:       "checkout",
+			resource:    "/v1/order/pay/now",
+			descriptors: []Descriptor{{Key: "customer", Value: "c1"}, {Key: "plan", Value: "free"}},
+			limits:      Limits{NewLimit(PerMinute, 4)},
+		},
+		{
+			desc:        "Second rule, matching any owner and resource",
+			owner:       "search",
+			resource:    "/v1/search",
+			descriptors: []Descriptor{{Key: "plan", Value: "enterprise"}},
+			limits:      Limits{NewLimit(PerMinute, 3)},
+		},
+		{
+			desc:        "Descriptor value matching, but not the key",
+			owner:       "search",
+			resource:    "/v1/search",
+			descriptors: []Descriptor{{Key: "tier", Value: "enterprise"}},
+			limits:      def,
+		},
+		{
+			desc:     "No rule matching",
+			owner:    "checkout",
+			resource: "/v1/order/pay",
+			limits:   def,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			_, err := limiter(def, c.owner, c.resource, c.descriptors...)
+			assert.NoError(t, err)
+			assert.Equal(t, c.limits, applied)
+		})
+	}
+}
+
+func TestRules_PeriodLimits(t *testing.T) {
+	rules, err := CompileRules([]Rule{
+		{Owner: "checkout", Limit: "1/m"},
+		{Owner: "checkout", Period: "1000/month"},
+	})
+	require.NoError(t, err)
+
+	def := PeriodLimits{{Period: Period{Unit: Daily}, Quantity: 10}}
+	assert.Equal(t, PeriodLimits{{Period: Period{Unit: Monthly, Location: time.UTC}, Quantity: 1000}}, rules.PeriodLimits(def, "checkout", "/pay"))
+	assert.Equal(t, Limits{NewLimit(PerMinute, 1)}, rules.Limits(nil, "checkout", "/pay"))
+	assert.Equal(t, def, rules.PeriodLimits(def, "search", "/pay"))
+	assert.True(t, rules.HasPeriodLimits())
+	assert.False(t, rules[:1].HasPeriodLimits())
+}
+
+func TestRules_Alerts(t *testing.T) {
+	rules, err := CompileRules([]Rule{
+		{Owner: "checkout", Limit: "1/m"},
+		{Owner: "checkout", Alerts: []AlertThreshold{{Percent: 80}, {ConsecutiveRejections: 5}}},
+	})
+	require.NoError(t, err)
+
+	def := []AlertThreshold{{Percent: 90}}
+	assert.Equal(t, []AlertThreshold{{Percent: 80}, {ConsecutiveRejections: 5}}, rules.Alerts(def, "checkout", "/pay"))
+	assert.Equal(t, def, rules.Alerts(def, "search", "/pay"))
+	assert.Equal(t, Limits{NewLimit(PerMinute, 1)}, rules.Limits(nil, "checkout", "/pay"))
+	assert.True(t, rules.HasAlerts())
+	assert.False(t, rules[:1].HasAlerts())
+	assert.Equal(t, []AlertThreshold{{Percent: 80}, {ConsecutiveRejections: 5}}, rules.AllAlerts())
+	assert.Empty(t, rules[:1].AllAlerts())
+}
+
+func TestCompileRules_InvalidRules(t *testing.T) {
+	cases := map[string]Rule{
+		"Invalid limit":              {Limit: "1/never"},
+		"Invalid owner pattern":      {Owner: "[", Limit: "1/m"},
+		"Invalid resource pattern":   {Resource: "[", Limit: "1/m"},
+		"Invalid descriptor pattern": {Descriptors: []Descriptor{{Key: "plan", Value: "["}}, Limit: "1/m"},
+		"Missing limit":              {Owner: "checkout"},
+		"Invalid period":             {Period: "1/year"},
+		"Invalid timezone":           {Period: "1/month", Timezone: "Mars/Olympus"},
+		"Empty alert":                {Alerts: []AlertThreshold{{Webhook: "http://localhost"}}},
+		"Alert with both conditions": {Alerts: []AlertThreshold{{Percent: 80, ConsecutiveRejections: 3}}},
+		"Alert percent out of range": {Alerts: []AlertThreshold{{Percent: 120}}},
+		"Negative alert rejections":  {Alerts: []AlertThreshold{{ConsecutiveRejections: -1}}},
+	}
+
+	for desc, r := range cases {
+		t.Run(desc, func(t *testing.T) {
+			_, err := CompileRules([]Rule{r})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	f, err := ioutil.TempFile("", "rules")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(`[{"owner": "checkout", "descriptors": [{"key": "plan", "value": "free"}], "limit": "10/s"}]`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	rules, err := LoadRules(f.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, []Rule{{Owner: "checkout", Descriptors: []Descriptor{{Key: "plan", Value: "free"}}, Limit: "10/s"}}, rules)
+
+	_, err = LoadRules("/nonexistent")
+	assert.Error(t, err)
+}
@@ -2,6 +2,8 @@ package rate
 
 import (
 	"io"
+	"net/url"
+	"sync"
 	"time"
 )
 
@@ -12,20 +14,62 @@ type SlideWindowStorage interface {
 	Drop(key string, until time.Time) (int, error)
 	Count(key string, until time.Time) (int, error)
 	Flush() error
+	// Ping checks the storage is reachable.
+	Ping() error
+}
+
+func init() {
+	RegisterStorage("inmemory", func(_ *url.URL) (SlideWindowStorage, error) {
+		return NewInMemorySlideWindowStorage(make(map[string][]time.Time)), nil
+	})
 }
 
 type inMemorySlideWindowStorage struct {
+	mu    sync.Mutex
 	store map[string][]time.Time
+	// leases maps the keys of the ConcurrencyLimiter to the expiration of each lease.
+	leases map[string]map[string]time.Time
+	// weighted maps the keys to their weighted entries by id.
+	weighted map[string]map[string]weightedHit
+	// counters are the counters of the CounterStorage. Expired ones are swept at most once per minute.
+	counters map[string]counter
+	swept    time.Time
+	// usage are the buckets of the UsageStorage by their start.
+	usage map[int64]*usageBucket
+}
+
+type usageBucket struct {
+	expireAt time.Time
+	usages   map[[2]string]*Usage
+}
+
+type counter struct {
+	n        int
+	expireAt time.Time
+}
+
+type weightedHit struct {
+	time time.Time
+	n    int
 }
 
 // NewInMemorySlideWindowStorage creates a new InMemory SlideWindowStorage.
-// Not recommended for prod. Just testing purpose. In case you want to use an in memory storage, please implement it with
-// the interface SlideWindowStorage. A good implementation could be https://github.com/patrickmn/go-cache.
+// It is safe for concurrent use but it is not distributed, so every ratio instance keeps its own hits. Use it in
+// cluster mode, where each instance owns a portion of the keys, or for testing purposes.
 func NewInMemorySlideWindowStorage(store map[string][]time.Time) SlideWindowStorage {
-	return &inMemorySlideWindowStorage{store: store}
+	return &inMemorySlideWindowStorage{
+		store:    store,
+		leases:   make(map[string]map[string]time.Time),
+		weighted: make(map[string]map[string]weightedHit),
+		counters: make(map[string]counter),
+		usage:    make(map[int64]*usageBucket),
+	}
 }
 
-func (s inMemorySlideWindowStorage) Add(key string, now time.Time, _ time.Duration) error {
+func (s *inMemorySlideWindowStorage) Add(key string, now time.Time, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	if _, ok := s.store[key]; !ok {
 		s.store[key] = make([]time.Time, 0)
 	}
@@ -35,11 +79,24 @@ func (s inMemorySlideWindowStorage) Add(key string, now time.Time, _ time.Durati
 }
 
 func (s *inMemorySlideWindowStorage) Drop(key string, until time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var dropped int
+	for id, w := range s.weighted[key] {
+		if w.time.Before(until) {
+			dropped += w.n
+			delete(s.weighted[key], id)
+		}
+	}
+	if len(s.weighted[key]) == 0 {
+		delete(s.weighted, key)
+	}
+
 	if len(s.store[key]) == 0 {
-		return 0, nil
+		return dropped, nil
 	}
 
-	var dropped int
 	tsInWindow := s.store[key][:0]
 	for _, t := range s.store[key] {
 		if t.After(until) || t.Equal(until) {
@@ -49,12 +106,19 @@ func (s *inMemorySlideWindowStorage) Drop(key string, until time.Time) (int, err
 		}
 	}
 
-	s.store[key] = tsInWindow
+	if len(tsInWindow) == 0 {
+		delete(s.store, key)
+	} else {
+		s.store[key] = tsInWindow
+	}
 
 	return dropped, nil
 }
 
-func (s inMemorySlideWindowStorage) Count(key string, until time.Time) (int, error) {
+func (s *inMemorySlideWindowStorage) Count(key string, until time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	var hits int
 	for _, t := range s.store[key] {
 		if t.Before(until) || t.Equal(until) {
@@ -62,15 +126,224 @@ func (s inMemorySlideWindowStorage) Count(key string, until time.Time) (int, err
 		}
 	}
 
+	for _, w := range s.weighted[key] {
+		if !w.time.After(until) {
+			hits += w.n
+		}
+	}
+
 	return hits, nil
 }
 
+func (s *inMemorySlideWindowStorage) AddWeighted(key, id string, n int, now time.Time, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.weighted[key]; !ok {
+		s.weighted[key] = make(map[string]weightedHit)
+	}
+
+	s.weighted[key][id] = weightedHit{time: now, n: n}
+	return nil
+}
+
+func (s *inMemorySlideWindowStorage) Unweight(key, id string, n int) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.weighted[key][id]
+	if !ok {
+		return 0, time.Time{}, nil
+	}
+
+	if n >= w.n {
+		delete(s.weighted[key], id)
+		return w.n, w.time, nil
+	}
+
+	w.n -= n
+	s.weighted[key][id] = w
+	return n, w.time, nil
+}
+
 func (s *inMemorySlideWindowStorage) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	s.store = make(map[string][]time.Time)
+	s.leases = make(map[string]map[string]time.Time)
+	s.weighted = make(map[string]map[string]weightedHit)
+	s.counters = make(map[string]counter)
+	s.usage = make(map[int64]*usageBucket)
+	return nil
+}
+
+func (s *inMemorySlideWindowStorage) Incr(key string, n int, expireAt time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	c := s.counters[key]
+	if !c.expireAt.After(now) {
+		c.n = 0
+	}
+
+	if now.Sub(s.swept) > time.Minute {
+		for k, other := range s.counters {
+			if !other.expireAt.After(now) {
+				delete(s.counters, k)
+			}
+		}
+		s.swept = now
+	}
+
+	c.n += n
+	c.expireAt = expireAt
+	s.counters[key] = c
+
+	return c.n, nil
+}
+
+func (s *inMemorySlideWindowStorage) Get(key string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.counters[key]
+	if !ok || !c.expireAt.After(time.Now()) {
+		return 0, nil
+	}
+
+	return c.n, nil
+}
+
+// Ban stores the ban as a counter expiring at its end.
+func (s *inMemorySlideWindowStorage) Ban(key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.counters[key] = counter{n: 1, expireAt: until}
+	return nil
+}
+
+func (s *inMemorySlideWindowStorage) BannedUntil(key string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.counters[key]
+	if !ok || !c.expireAt.After(time.Now()) {
+		return time.Time{}, nil
+	}
+
+	return c.expireAt, nil
+}
+
+func (s *inMemorySlideWindowStorage) Unban(key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.counters[key]
+	delete(s.counters, key)
+
+	return ok && c.expireAt.After(time.Now()), nil
+}
+
+func (s *inMemorySlideWindowStorage) Acquire(key, lease string, max int, now, expireAt time.Time) (bool, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	leases, ok := s.leases[key]
+	if !ok {
+		leases = make(map[string]time.Time)
+		s.leases[key] = leases
+	}
+
+	for id, e := range leases {
+		if !e.After(now) {
+			delete(leases, id)
+		}
+	}
+
+	held := len(leases)
+	if held >= max {
+		if held == 0 {
+			delete(s.leases, key)
+		}
+		return false, held, nil
+	}
+
+	leases[lease] = expireAt
+	return true, held, nil
+}
+
+func (s *inMemorySlideWindowStorage) Release(key, lease string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.leases[key][lease]; !ok {
+		return false, nil
+	}
+
+	delete(s.leases[key], lease)
+	if len(s.leases[key]) == 0 {
+		delete(s.leases, key)
+	}
+
+	return true, nil
+}
+
+func (s *inMemorySlideWindowStorage) AddUsage(usages []Usage, expireIn time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for start, b := range s.usage {
+		if !b.expireAt.After(now) {
+			delete(s.usage, start)
+		}
+	}
+
+	for _, u := range usages {
+		b, ok := s.usage[u.Bucket.UnixNano()]
+		if !ok {
+			b = &usageBucket{usages: make(map[[2]string]*Usage)}
+			s.usage[u.Bucket.UnixNano()] = b
+		}
+		b.expireAt = now.Add(expireIn)
+
+		stored, ok := b.usages[[2]string{u.Owner, u.Resource}]
+		if !ok {
+			stored = &Usage{Owner: u.Owner, Resource: u.Resource, Bucket: u.Bucket}
+			b.usages[[2]string{u.Owner, u.Resource}] = stored
+		}
+		stored.Allowed += u.Allowed
+		stored.Rejected += u.Rejected
+	}
+
+	return nil
+}
+
+func (s *inMemorySlideWindowStorage) Usage(bucket time.Time) ([]Usage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.usage[bucket.UnixNano()]
+	if !ok || !b.expireAt.After(time.Now()) {
+		return nil, nil
+	}
+
+	usages := make([]Usage, 0, len(b.usages))
+	for _, u := range b.usages {
+		usages = append(usages, *u)
+	}
+
+	return usages, nil
+}
+
+func (s *inMemorySlideWindowStorage) Ping() error {
 	return nil
 }
 
-func (s inMemorySlideWindowStorage) Close() error {
+func (s *inMemorySlideWindowStorage) Close() error {
 	// no-op
 	return nil
 }
@@ -0,0 +1,232 @@
+package rate
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Usage are the hits of an owner on a resource during a bucket of time.
+type Usage struct {
+	Owner    string
+	Resource string
+	// Bucket is the start of the bucket of time.
+	Bucket   time.Time
+	Allowed  int
+	Rejected int
+}
+
+// UsageStorage stores the usage of every owner and resource by bucket of time. Implemented by the Redis and in
+// memory storages.
+type UsageStorage interface {
+	// AddUsage adds the hits of the usages to the ones stored for the same bucket, owner and resource. Their buckets
+	// expire in expireIn.
+	AddUsage(usages []Usage, expireIn time.Duration) error
+	// Usage returns the usage of every owner and resource during the bucket starting at bucket.
+	Usage(bucket time.Time) ([]Usage, error)
+}
+
+// Default UsageOptions.
+const (
+	DefaultUsageBucket        = time.Hour
+	DefaultUsageRetention     = 90 * 24 * time.Hour
+	DefaultUsageFlushInterval = 10 * time.Second
+	// MaxUsageBuckets is the max number of buckets exported at once.
+	MaxUsageBuckets = 10000
+)
+
+// UsageOptions configures a UsageAccountant.
+type UsageOptions struct {
+	// Bucket is the size of the buckets of time the hits are aggregated into.
+	Bucket time.Duration
+	// Retention is the time the buckets are kept for.
+	Retention time.Duration
+	// FlushInterval is the interval the aggregated hits are written to the storage at.
+	FlushInterval time.Duration
+}
+
+type usageKey struct {
+	owner    string
+	resource string
+	bucket   int64
+}
+
+// UsageAccountant accounts the allowed and rejected hits of every owner and resource into buckets of time, for
+// reporting and billing. Hits are aggregated in memory and written to the storage periodically, so accounting does
+// not make callers wait. It is safe for concurrent use.
+type UsageAccountant struct {
+	s UsageStorage
+	o UsageOptions
+
+	mu      sync.Mutex
+	pending map[usageKey]*Usage
+
+	closeOnce sync.Once
+	done      chan struct{}
+	stopped   chan struct{}
+}
+
+// NewUsageAccountant creates a UsageAccountant writing to s. Close writes the pending hits.
+func NewUsageAccountant(s UsageStorage, o UsageOptions) *UsageAccountant {
+	if o.Bucket <= 0 {
+		o.Bucket = DefaultUsageBucket
+	}
+
+	if o.Retention <= 0 {
+		o.Retention = DefaultUsageRetention
+	}
+
+	if o.FlushInterval <= 0 {
+		o.FlushInterval = DefaultUsageFlushInterval
+	}
+
+	a := &UsageAccountant{
+		s:       s,
+		o:       o,
+		pending: make(map[usageKey]*Usage),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	go a.flushEvery(o.FlushInterval)
+
+	return a
+}
+
+// Bucket returns the size of the buckets of time.
+func (a *UsageAccountant) Bucket() time.Duration {
+	return a.o.Bucket
+}
+
+// Record accounts a hit of the owner on the resource at now.
+func (a *UsageAccountant) Record(owner, resource string, allowed bool, now time.Time) {
+	a.RecordN(owner, resource, allowed, 1, now)
+}
+
+// RecordN accounts n hits of the owner on the resource at now, like the permits of a reservation. A negative n takes
+// back hits accounted before, like the permits returned.
+func (a *UsageAccountant) RecordN(owner, resource string, allowed bool, n int, now time.Time) {
+	bucket := now.Truncate(a.o.Bucket)
+	k := usageKey{owner: owner, resource: resource, bucket: bucket.UnixNano()}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	u, ok := a.pending[k]
+	if !ok {
+		u = &Usage{Owner: owner, Resource: resource, Bucket: bucket}
+		a.pending[k] = u
+	}
+
+	if allowed {
+		u.Allowed += n
+	} else {
+		u.Rejected += n
+	}
+}
+
+// Flush writes the pending hits to the storage. Hits failing to be written are lost.
+func (a *UsageAccountant) Flush() error {
+	a.mu.Lock()
+	pending := a.pending
+	a.pending = make(map[usageKey]*Usage)
+	a.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	usages := make([]Usage, 0, len(pending))
+	for _, u := range pending {
+		usages = append(usages, *u)
+	}
+
+	if err := a.s.AddUsage(usages, a.o.Bucket+a.o.Retention); err != nil {
+		return fmt.Errorf("writing the usage of %d owners and resources: %s", len(usages), err.Error())
+	}
+
+	return nil
+}
+
+// Export returns the usage of the buckets between from and to, sorted by bucket, owner and resource. An empty owner
+// means every owner. The pending hits are written first.
+func (a *UsageAccountant) Export(from, to time.Time, owner string) ([]Usage, error) {
+	if err := a.Flush(); err != nil {
+		log.Printf("error flushing the usage before exporting it: %s\n", err.Error())
+	}
+
+	from = from.Truncate(a.o.Bucket)
+	if n := to.Sub(from) / a.o.Bucket; n > MaxUsageBuckets {
+		return nil, fmt.Errorf("too many buckets to export: %d, max %d", n, MaxUsageBuckets)
+	}
+
+	var usages []Usage
+	for bucket := from; bucket.Before(to); bucket = bucket.Add(a.o.Bucket) {
+		bucketUsages, err := a.s.Usage(bucket)
+		if err != nil {
+			return nil, fmt.Errorf("getting the usage of %s: %s", bucket.UTC().Format(time.RFC3339), err.Error())
+		}
+
+		for _, u := range bucketUsages {
+			if owner == "" || u.Owner == owner {
+				usages = append(usages, u)
+			}
+		}
+	}
+
+	sort.Slice(usages, func(i, j int) bool {
+		if !usages[i].Bucket.Equal(usages[j].Bucket) {
+			return usages[i].Bucket.Before(usages[j].Bucket)
+		}
+		if usages[i].Owner != usages[j].Owner {
+			return usages[i].Owner < usages[j].Owner
+		}
+
+		return usages[i].Resource < usages[j].Resource
+	})
+
+	return usages, nil
+}
+
+// Close stops the periodic flushes and writes the pending hits.
+func (a *UsageAccountant) Close() error {
+	a.closeOnce.Do(func() {
+		close(a.done)
+		<-a.stopped
+	})
+
+	return a.Flush()
+}
+
+func (a *UsageAccountant) flushEvery(interval time.Duration) {
+	defer close(a.stopped)
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-a.done:
+			return
+		case <-t.C:
+			if err := a.Flush(); err != nil {
+				log.Printf("error flushing usage: %s\n", err.Error())
+			}
+		}
+	}
+}
+
+// UsageRateLimiter accounts the decisions of limiter by owner and resource. Descriptors are not taken into account,
+// and failed decisions are not accounted.
+func UsageRateLimiter(limiter Limiter, a *UsageAccountant) Limiter {
+	return func(l Limits, owner, resource string, descriptors ...Descriptor) (Decision, error) {
+		d, err := limiter(l, owner, resource, descriptors...)
+		if err == nil {
+			a.Record(owner, resource, d.Allowed, time.Now())
+		}
+
+		return d, err
+	}
+}
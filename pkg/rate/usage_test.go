@@ -0,0 +1,104 @@
+package rate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsageAccountant(t *testing.T) {
+	r, m := createRedis()
+	defer m.Close()
+
+	storages := map[string]UsageStorage{
+		"In memory": NewInMemorySlideWindowStorage(make(map[string][]time.Time)).(UsageStorage),
+		"Redis":     NewRedisSlideWindowStorage(r, DefaultRedisNamespace).(UsageStorage),
+	}
+
+	for desc, s := range storages {
+		t.Run(desc, func(t *testing.T) {
+			a := NewUsageAccountant(s, UsageOptions{Bucket: time.Hour, FlushInterval: time.Hour})
+
+			bucket := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+			a.Record("checkout", "/pay", true, bucket.Add(time.Minute))
+			a.Record("checkout", "/pay", true, bucket.Add(time.Minute*2))
+			a.Record("checkout", "/pay", false, bucket.Add(time.Minute*3))
+			a.Record("checkout", "/pay:now", true, bucket.Add(time.Minute*3))
+			a.Record("search", "/q", false, bucket.Add(time.Minute*4))
+			a.Record("checkout", "/pay", true, bucket.Add(time.Hour))
+			require.NoError(t, a.Flush())
+
+			// Hits are added to the ones already stored, weighted ones included.
+			a.RecordN("checkout", "/pay", true, 4, bucket.Add(time.Minute*5))
+
+			usages, err := a.Export(bucket.Add(time.Minute), bucket.Add(time.Hour*2), "")
+			require.NoError(t, err)
+			assert.Equal(t, []Usage{
+				{Owner: "checkout", Resource: "/pay", Bucket: bucket, Allowed: 6, Rejected: 1},
+				{Owner: "checkout", Resource: "/pay:now", Bucket: bucket, Allowed: 1},
+				{Owner: "search", Resource: "/q", Bucket: bucket, Rejected: 1},
+				{Owner: "checkout", Resource: "/pay", Bucket: bucket.Add(time.Hour), Allowed: 1},
+			}, utc(usages))
+
+			usages, err = a.Export(bucket, bucket.Add(time.Hour), "search")
+			require.NoError(t, err)
+			assert.Equal(t, []Usage{{Owner: "search", Resource: "/q", Bucket: bucket, Rejected: 1}}, utc(usages))
+
+			_, err = a.Export(bucket.Add(-time.Hour*24*365*2), bucket, "")
+			assert.Error(t, err, "too many buckets")
+
+			a.Record("search", "/q", true, bucket)
+			require.NoError(t, a.Close())
+			usages, err = s.Usage(bucket)
+			require.NoError(t, err)
+			assert.Len(t, usages, 3, "pending hits should be written on close")
+		})
+	}
+}
+
+func TestUsageRateLimiter(t *testing.T) {
+	s := NewInMemorySlideWindowStorage(make(map[string][]time.Time))
+	a := NewUsageAccountant(s.(UsageStorage), UsageOptions{})
+	defer a.Close()
+
+	limiter := UsageRateLimiter(SlideWindowRateLimiter(s), a)
+	for i := 0; i < 3; i++ {
+		_, err := limiter(Limits{NewLimit(PerMinute, 2)}, "checkout", "/pay", Descriptor{Key: "customer", Value: "c1"})
+		require.NoError(t, err)
+	}
+
+	_, err := limiter(Limits{}, "checkout", "/pay")
+	assert.Error(t, err)
+
+	now := time.Now()
+	usages, err := a.Export(now, now.Add(time.Second), "checkout")
+	require.NoError(t, err)
+	require.Len(t, usages, 1)
+	assert.Equal(t, 2, usages[0].Allowed)
+	assert.Equal(t, 1, usages[0].Rejected, "failed decisions should not be accounted")
+}
+
+func TestParseUsageField(t *testing.T) {
+	for _, c := range [][2]string{{"checkout", "/pay"}, {"a:b", ":c:"}, {"", ""}} {
+		owner, resource, kind, ok := parseUsageField(usageField(c[0], c[1], "r"))
+		assert.True(t, ok)
+		assert.Equal(t, c[0], owner)
+		assert.Equal(t, c[1], resource)
+		assert.Equal(t, "r", kind)
+	}
+
+	for _, field := range []string{"", "x:checkout:a", "10:checkout:a", "3:abc"} {
+		_, _, _, ok := parseUsageField(field)
+		assert.False(t, ok, field)
+	}
+}
+
+func utc(usages []Usage) []Usage {
+	for i := range usages {
+		usages[i].Bucket = usages[i].Bucket.UTC()
+	}
+
+	return usages
+}